@@ -0,0 +1,99 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/goulash/matcher/glob"
+)
+
+// join joins pattern to the directory dir, escaping the characters in dir
+// that are special in patterns.
+func join(dir, pattern string) string {
+	return filepath.Join(glob.QuoteMeta(dir), pattern)
+}
+
+// Anchor returns pattern as it is matched when it is written in a rule file
+// in the directory dir, as AddFile does: if the pattern contains a path
+// separator other than a trailing one, it is joined to dir, in which the
+// characters that are special in patterns are escaped. Otherwise, it matches
+// files of that name in any directory and is returned as is. A leading "!"
+// and a trailing slash are kept. Patterns starting with "//" are relative
+// to the workspace root of a Worker instead, and are also returned as is.
+//
+// The pattern must not be preceded by predicates.
+func Anchor(pattern, dir string) string {
+	neg := strings.HasPrefix(pattern, "!")
+	if neg {
+		pattern = pattern[1:]
+	}
+	p, dirOnly := trimSlash(pattern)
+	if !strings.HasPrefix(p, "//") && strings.Contains(p, "/") {
+		pattern = join(dir, p)
+		if dirOnly {
+			pattern += "/"
+		}
+	}
+	if neg {
+		pattern = "!" + pattern
+	}
+	return pattern
+}
+
+// Unanchor is the reverse of Anchor. It splits an anchored pattern into the
+// longest directory that it matches literally, with escapes removed, and
+// the pattern relative to that directory, so that Anchor(rel, dir) returns
+// pattern again. If the relative pattern would not contain a path separator
+// otherwise, it starts with "./" to keep it anchored. A leading "!" and
+// a trailing slash are kept in rel.
+//
+// If pattern is not an absolute path, dir is empty and rel is pattern.
+func Unanchor(pattern string) (dir, rel string) {
+	var neg string
+	if strings.HasPrefix(pattern, "!") {
+		neg, pattern = "!", pattern[1:]
+	}
+	p, dirOnly := trimSlash(pattern)
+	if !filepath.IsAbs(p) {
+		return "", neg + pattern
+	}
+
+	elems := strings.Split(p, "/")
+	lits := make([]string, 0, len(elems))
+	for _, e := range elems[:len(elems)-1] {
+		lit, ok := literal(e)
+		if !ok {
+			break
+		}
+		lits = append(lits, lit)
+	}
+	dir = strings.Join(lits, "/")
+	if dir == "" {
+		dir = "/"
+	}
+	rel = strings.Join(elems[len(lits):], "/")
+	if !strings.Contains(rel, "/") {
+		rel = "./" + rel
+	}
+	if dirOnly {
+		rel += "/"
+	}
+	return dir, neg + rel
+}
+
+// literal returns the name that the path element e matches if it contains
+// no wildcards, and whether it does.
+func literal(e string) (string, bool) {
+	if e == "" {
+		return "", true
+	}
+	g, err := glob.Compile(e)
+	if err != nil {
+		return "", false
+	}
+	return g.Literal()
+}
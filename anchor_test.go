@@ -0,0 +1,88 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnchor(fw *testing.T) {
+	tests := map[string]string{
+		"*.o":          "*.o",
+		"build/":       "build/",
+		"!build/":      "!build/",
+		"out/*.o":      "/src/out/*.o",
+		"./a":          "/src/a",
+		"/a":           "/src/a",
+		"!out/*.o":     "!/src/out/*.o",
+		"a/b/":         "/src/a/b/",
+		"**/keep":      "/src/**/keep",
+		"//build/*":    "//build/*",
+		"a/../../b":    "/b",
+		"!//build/x/":  "!//build/x/",
+		"dir/\\[x\\]/": "/src/dir/\\[x\\]/",
+	}
+	for k, v := range tests {
+		if a := Anchor(k, "/src"); a != v {
+			fw.Errorf("Anchor(%q, \"/src\") = %q, expected %q", k, a, v)
+		}
+	}
+	if a := Anchor("out/*.o", "/x/[y]*"); a != "/x/\\[y]\\*/out/*.o" {
+		fw.Errorf("Anchor() in a directory with special characters = %q", a)
+	}
+}
+
+func TestUnanchor(fw *testing.T) {
+	type result struct {
+		Dir, Rel string
+	}
+	tests := map[string]result{
+		"/src/out/*.o":     {"/src/out", "./*.o"},
+		"/src/out/file":    {"/src/out", "./file"},
+		"!/src/out/*.o":    {"/src/out", "!./*.o"},
+		"/src/out/":        {"/src", "./out/"},
+		"/src/*/x":         {"/src", "*/x"},
+		"/src/**/keep":     {"/src", "**/keep"},
+		"/a":               {"/", "./a"},
+		"/x/\\[y]/out/*.o": {"/x/[y]/out", "./*.o"},
+		"*.o":              {"", "*.o"},
+		"a/b":              {"", "a/b"},
+		"!build/":          {"", "!build/"},
+	}
+	for k, v := range tests {
+		dir, rel := Unanchor(k)
+		if dir != v.Dir || rel != v.Rel {
+			fw.Errorf("Unanchor(%q) = (%q, %q), expected (%q, %q)", k, dir, rel, v.Dir, v.Rel)
+		}
+		if dir != "" {
+			if a := Anchor(rel, dir); a != k {
+				fw.Errorf("Anchor(Unanchor(%q)) = %q", k, a)
+			}
+		}
+	}
+}
+
+func TestAnchorSpecialDirectory(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher[*]")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "rules"), []byte("out/*.o\n"), 0644)
+
+	w, err := New("rules").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if !w.Matches("out/a.o") {
+		fw.Errorf("anchored pattern does not match in %s", dir)
+	}
+	if r := w.Rules()[0]; r.Glob != Anchor("out/*.o", dir) {
+		fw.Errorf("rule %q is not anchored as by Anchor", r.Glob)
+	}
+}
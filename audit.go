@@ -0,0 +1,74 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// AuditRecord is a decision of a Worker as written to its audit log,
+// one JSON object per line, with the field names given in the tags.
+type AuditRecord struct {
+	// Time is when the decision was made.
+	Time time.Time `json:"time"`
+
+	// Path is the absolute path that was decided on.
+	Path string `json:"path"`
+
+	// Matched is whether the path is matched.
+	Matched bool `json:"matched"`
+
+	// Pattern, Source, Line, and Scope are the rule that decided, as in
+	// MatchResult. They are empty if a layer or no rule decided.
+	Pattern string `json:"pattern,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Scope   string `json:"scope,omitempty"`
+
+	// Layer is the type of the layer that decided, as in Decision.
+	Layer string `json:"layer,omitempty"`
+}
+
+// SetAudit makes the Worker write an AuditRecord to out for every decision
+// made by Matches, MatchesInfo, and Match, and thus by the functions that
+// walk directories, so that it can be proven later which files were
+// excluded from a backup or upload, and why. The records are newline
+// delimited JSON, and each is written with a single call to out.Write.
+// Passing nil turns the audit log off again.
+//
+// The result cache of the Worker is not consulted while the audit log is
+// on, so that every record names the rule that decided. Files beneath
+// a matched directory are not decided on by walking, and have no records.
+// Errors writing to out are reported to the Logger of the Matcher.
+func (w *Worker) SetAudit(out io.Writer) {
+	w.audit = out
+}
+
+func (w *Worker) record(path string, res MatchResult, matched bool) {
+	rec := AuditRecord{
+		Time:    now(),
+		Path:    path,
+		Matched: matched,
+		Pattern: res.Pattern,
+		Source:  res.Source,
+		Line:    res.Line,
+	}
+	if res.Rule.Glob != "" {
+		rec.Scope = res.Rule.Scope.String()
+	}
+	if res.Layer != nil {
+		rec.Layer = fmt.Sprintf("%T", res.Layer)
+	}
+	b, err := json.Marshal(rec)
+	if err == nil {
+		_, err = w.audit.Write(append(b, '\n'))
+	}
+	if err != nil {
+		w.logf("error writing audit record for %s: %s", path, err)
+	}
+}
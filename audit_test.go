@@ -0,0 +1,67 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetAudit(fw *testing.T) {
+	defer noUserFiles(fw)()
+
+	t0 := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return t0 }
+	defer func() { now = time.Now }()
+
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	conf := filepath.Join(dir, "match.conf")
+	ioutil.WriteFile(conf, []byte("*.log\n!keep.log\n"), 0644)
+
+	w, err := New("match.conf").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.EnableCache(time.Hour)
+	w.Matches("a.log")
+
+	var buf bytes.Buffer
+	w.SetAudit(&buf)
+	w.Matches("a.log")
+	w.Matches("keep.log")
+	w.Matches("a.txt")
+	w.SetAudit(nil)
+	w.Matches("b.log")
+
+	expected := []AuditRecord{
+		{t0, filepath.Join(dir, "a.log"), true, "*.log", conf, 1, "project", ""},
+		{t0, filepath.Join(dir, "keep.log"), false, "!keep.log", conf, 2, "project", ""},
+		{t0, filepath.Join(dir, "a.txt"), false, "", "", 0, "", ""},
+	}
+	var records []AuditRecord
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var r AuditRecord
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			fw.Fatalf("invalid audit record %q: %s", sc.Text(), err)
+		}
+		r.Time = r.Time.UTC()
+		records = append(records, r)
+	}
+	if !reflect.DeepEqual(records, expected) {
+		fw.Errorf("audit records = %v, expected %v", records, expected)
+	}
+}
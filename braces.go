@@ -0,0 +1,81 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+// maxAlternatives is the number of globs that a single pattern may expand
+// to, so that a line such as "{a,b}{a,b}{a,b}..." cannot exhaust memory.
+const maxAlternatives = 1024
+
+// braces returns the offsets of the first brace expression in s that has
+// alternatives: those of its opening and closing braces, and those of the
+// commas separating the alternatives. Escaped braces and commas are literal,
+// and so is a pair of braces without a comma between them, as in the shell.
+// The returned ok is false if s has no such expression.
+func braces(s string) (open, close int, commas []int, ok bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			close, commas = braceEnd(s, i)
+			if close >= 0 && len(commas) > 0 {
+				return i, close, commas, true
+			}
+		}
+	}
+	return 0, 0, nil, false
+}
+
+// braceEnd returns the offset of the brace closing the one at s[open], and
+// the offsets of the commas that are directly within them. It returns -1 if
+// the brace is not closed.
+func braceEnd(s string, open int) (int, []int) {
+	var commas []int
+	depth := 0
+	for i := open + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i, commas
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				commas = append(commas, i)
+			}
+		}
+	}
+	return -1, nil
+}
+
+// expandBraces returns the globs that the brace expressions in pattern
+// expand to, in order, such as "a.c" and "a.h" for "a.{c,h}". Expressions
+// may be nested. A pattern without brace expressions expands to itself.
+// Escaped braces and commas are kept escaped, so that the globs match them
+// literally.
+func expandBraces(pattern string) ([]string, error) {
+	open, close, commas, ok := braces(pattern)
+	if !ok {
+		return []string{pattern}, nil
+	}
+	prefix, suffix := pattern[:open], pattern[close+1:]
+	var globs []string
+	start := open + 1
+	for _, end := range append(commas, close) {
+		alts, err := expandBraces(prefix + pattern[start:end] + suffix)
+		if err != nil {
+			return nil, err
+		}
+		if globs = append(globs, alts...); len(globs) > maxAlternatives {
+			return nil, ErrBraceExpansion
+		}
+		start = end + 1
+	}
+	return globs, nil
+}
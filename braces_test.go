@@ -0,0 +1,110 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandBraces(fw *testing.T) {
+	tests := map[string][]string{
+		"*.o":                       {"*.o"},
+		"*.{jpg,png,gif}":           {"*.jpg", "*.png", "*.gif"},
+		"{a,b}{c,d}":                {"ac", "ad", "bc", "bd"},
+		"{src,test/{unit,e2e}}/*.o": {"src/*.o", "test/unit/*.o", "test/e2e/*.o"},
+		"a{,.bak}":                  {"a", "a.bak"},
+		"a{b}c":                     {"a{b}c"},
+		"a{b}{c,d}":                 {"a{b}c", "a{b}d"},
+		"\\{a,b}":                   {"\\{a,b}"},
+		"{a\\,b,c}":                 {"a\\,b", "c"},
+		"{a,b\\}":                   {"{a,b\\}"},
+		"{a,b":                      {"{a,b"},
+		"a}{b,c}":                   {"a}b", "a}c"},
+	}
+	for k, v := range tests {
+		globs, err := expandBraces(k)
+		if err != nil || !reflect.DeepEqual(globs, v) {
+			fw.Errorf("expandBraces(%q) = (%q, %v), expected %q", k, globs, err, v)
+		}
+	}
+
+	if _, err := expandBraces(strings.Repeat("{a,b}", 11)); err != ErrBraceExpansion {
+		fw.Errorf("expanding 11 brace expressions = %v, expected %v", err, ErrBraceExpansion)
+	}
+}
+
+func TestBraces(fw *testing.T) {
+	type result struct {
+		Globs []string
+		Err   error
+	}
+	tests := map[string]result{
+		"*.{c,h}":                    {[]string{"*.c", "*.h"}, nil},
+		"!{a,b}":                     {[]string{"a", "b"}, nil},
+		"set x = c, h\n*.{${x},o}":   {[]string{"*.c", "*.o", "*.h", "*.o"}, nil},
+		"define SRC = *.{c,h}\n@SRC": {[]string{"*.c", "*.h"}, nil},
+		"@{A,B}":                     {nil, ErrUndefinedMacro},
+		"a{[,b}":                     {nil, ErrIncompleteClass},
+		"{**,x}/*.o":                 {[]string{"**/*.o", "x/*.o"}, nil},
+	}
+	for k, v := range tests {
+		globs, err := parseAll(strings.Split(k, "\n")...)
+		if err != v.Err || !reflect.DeepEqual(globs, v.Globs) {
+			fw.Errorf("parsing %q = (%q, %v), expected (%q, %v)", k, globs, err, v.Globs, v.Err)
+		}
+	}
+
+	p := newParser(DialectNative)
+	if _, err := p.parseLine("x a{[,b}"); err == nil || err.(*BadPatternError).Column != 3 {
+		fw.Errorf("p.parseLine(%q) = %v, expected error at column 3", "x a{[,b}", err)
+	}
+	if err := Check("*.{a,[}"); err == nil || err.(*BadPatternError).Column != 2 {
+		fw.Errorf("Check(%q) = %v, expected error at column 2", "*.{a,[}", err)
+	}
+	if err := Check("{**,x}/*.o"); err != nil {
+		fw.Errorf("Check(%q) = %v, expected nil", "{**,x}/*.o", err)
+	}
+
+	p = newParser(DialectGitignore)
+	if rules, err := p.parseLine("*.{c,h}"); err != nil || len(rules) != 1 || rules[0].Glob != "*.{c,h}" {
+		fw.Errorf("gitignore: p.parseLine(%q) = (%v, %v), expected a literal glob", "*.{c,h}", rules, err)
+	}
+
+	matches := map[[2]string]bool{
+		{"*.{jpg,png}", "a/b.png"}: true,
+		{"*.{jpg,png}", "a/b.gif"}: false,
+		{"{a,b/c}", "x/a"}:         true,
+		{"{a,b/c}", "b/c"}:         true,
+		{"{a,b/c}", "x/c"}:         false,
+		{"\\{a,b}", "{a,b}"}:       true,
+		{"\\{a,b}", "a"}:           false,
+		{"a{b}", "a{b}"}:           true,
+	}
+	for k, v := range matches {
+		if ok, err := MatchPattern(k[0], k[1]); ok != v || err != nil {
+			fw.Errorf("MatchPattern(%q, %q) = (%v, %v), expected %v", k[0], k[1], ok, err, v)
+		}
+		p, err := CompilePattern(k[0])
+		if err != nil || p.Match(k[1]) != v || p.String() != k[0] {
+			fw.Errorf("CompilePattern(%q).Match(%q) = %v, expected %v", k[0], k[1], !v, v)
+		}
+	}
+
+	m := New("")
+	if err := m.Add("*.{tmp,bak}"); err != nil {
+		fw.Fatal(err)
+	}
+	if !m.Matches("a.tmp") || !m.Matches("a.bak") || m.Matches("a.{tmp,bak}") {
+		fw.Errorf("m.Matches does not match the globs that %q expands to", "*.{tmp,bak}")
+	}
+	if err := m.Add("{a,b/c}"); err != ErrGlobIsPath {
+		fw.Errorf("m.Add(%q) = %v, expected %v", "{a,b/c}", err, ErrGlobIsPath)
+	}
+	if !m.Remove("*.{tmp,bak}") || len(m.global) != 0 {
+		fw.Errorf("m.Remove(%q) left %v", "*.{tmp,bak}", m.global)
+	}
+}
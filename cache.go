@@ -0,0 +1,145 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"os"
+	"time"
+)
+
+// resultCache contains the decisions of a Worker by absolute path.
+type resultCache struct {
+	results   map[string]bool
+	staleness time.Duration
+	checked   time.Time
+}
+
+// EnableCache makes the Worker remember the decision of Matches for each
+// path, which speeds up programs that ask about the same paths repeatedly.
+// Decisions that depend on predicates, i.e. on the file rather than its
+// name, are not cached, since the file may change, and neither are those
+// of rules with an expires predicate.
+//
+// To avoid serving decisions based on outdated rules in long-running
+// processes, the configuration files that the Worker has read are checked
+// for changes to their modification time or size at most once every
+// staleness, when Matches is called. If any has changed, the Worker reads
+// its configuration files again and forgets all decisions. If a staleness
+// of zero is given, the files are checked on every call.
+//
+// The cache is also cleared when rules or layers are added to or removed
+// from the Worker. Changes to the rules of layers themselves are not noticed.
+// Clones of the Worker start with an empty cache.
+func (w *Worker) EnableCache(staleness time.Duration) {
+	w.cache = &resultCache{
+		results:   make(map[string]bool),
+		staleness: staleness,
+		checked:   now(),
+	}
+}
+
+// DisableCache turns the cache off and forgets all decisions.
+func (w *Worker) DisableCache() {
+	w.cache = nil
+}
+
+// invalidate forgets all cached decisions.
+func (w *Worker) invalidate() {
+	if w.cache != nil && len(w.cache.results) > 0 {
+		w.cache.results = make(map[string]bool)
+	}
+}
+
+// cached returns the cached decision for path, if there is one.
+func (w *Worker) cached(path string) (bool, bool) {
+	c := w.cache
+	if c == nil {
+		return false, false
+	}
+	if t := now(); t.Sub(c.checked) >= c.staleness {
+		c.checked = t
+		if w.configsChanged() {
+			if err := w.reload(); err != nil {
+				w.logf("error reloading configuration: %s", err)
+			}
+			w.invalidate()
+		}
+	}
+	m, ok := c.results[path]
+	return m, ok
+}
+
+// Changed reports whether any of the configuration files that the Worker
+// has read, in NewWorker or with AddFile and the like, has been changed or
+// removed since, judging by its modification time and size.
+func (w *Worker) Changed() bool {
+	return w.configsChanged()
+}
+
+// Reload reads the configuration files of the Worker again, so that
+// long-running processes pick up edits to them without creating a new
+// Worker, and forgets all cached decisions. The rules of the files are
+// replaced, and other rules, such as those added with Add, are kept. Files
+// that no longer exist are dropped along with their rules. If any file cannot
+// be read, the error is returned and the Worker is not changed. In
+// ModeCollect, the files are reloaded without their invalid lines, whose
+// errors are returned together as PatternErrors.
+//
+// Files that did not exist when the Worker was created are not read;
+// a new Worker is needed for them. See also SyncWorker.Watch.
+func (w *Worker) Reload() error {
+	err := w.reload()
+	if _, ok := err.(PatternErrors); err != nil && !ok {
+		return err
+	}
+	w.invalidate()
+	return err
+}
+
+// configsChanged returns whether any of the configuration files that
+// the Worker has read has changed since.
+func (w *Worker) configsChanged() bool {
+	for _, c := range w.configs {
+		fi, err := c.stat()
+		if err != nil || !fi.ModTime().Equal(c.modTime) || fi.Size() != c.size {
+			return true
+		}
+	}
+	return false
+}
+
+// reload reads the configuration files of the Worker again, replacing their
+// rules. Other rules are kept. Files that no longer exist are skipped. If any
+// file cannot be read, the Worker is not changed.
+func (w *Worker) reload() error {
+	names := make(map[string]bool, len(w.configs))
+	for _, c := range w.configs {
+		names[c.name] = true
+	}
+
+	t := *w
+	t.cache = nil
+	t.local, t.configs = nil, nil
+	for _, r := range w.local {
+		if !names[r.Source] {
+			t.local = append(t.local, r)
+		}
+	}
+	var errs PatternErrors
+	for _, c := range w.configs {
+		err := t.readConfig(c)
+		if e, ok := err.(PatternErrors); ok {
+			errs = append(errs, e...)
+		} else if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	w.local, w.configs = t.local, t.configs
+	w.logf("reloaded %d configuration files", len(w.configs))
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rules := filepath.Join(dir, "rules")
+	ioutil.WriteFile(rules, []byte("*.a\n"), 0644)
+
+	defer func(f func() time.Time) { now = f }(now)
+	t := time.Now()
+	now = func() time.Time { return t }
+
+	w, err := New("rules").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.c")
+	w.EnableCache(time.Minute)
+	if !w.Matches("x.a") || w.Matches("x.bb") {
+		fw.Fatalf("w.Matches() gives wrong results with cache")
+	}
+	sink := make(countingSink)
+	w.metrics = sink
+	w.SetProfiling(true)
+	w.Matches("x.a")
+	if len(w.Profile()) != 0 || sink[MetricMatches] != 1 {
+		fw.Errorf("w.Matches(x.a) was not served from the cache")
+	}
+
+	ioutil.WriteFile(rules, []byte("*.bb\n"), 0644)
+	if w.Matches("x.bb") {
+		fw.Errorf("w.Matches(x.bb) = true before the staleness window passed")
+	}
+	t = t.Add(time.Minute)
+	if !w.Matches("x.bb") || w.Matches("x.a") || !w.Matches("x.c") {
+		fw.Errorf("w.Matches() did not pick up the changed rules: %v", w.Rules())
+	}
+
+	w.Add("*.a")
+	if !w.Matches("x.a") {
+		fw.Errorf("w.Add did not invalidate the cache")
+	}
+	w.DisableCache()
+	if w.cache != nil {
+		fw.Errorf("w.DisableCache() kept the cache")
+	}
+}
+
+func TestCachePredicates(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	big := filepath.Join(dir, "big")
+	ioutil.WriteFile(big, nil, 0644)
+
+	w, err := New("").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.addReader(strings.NewReader("size:>10\n"), "rules", dir, ScopeSession)
+	w.EnableCache(time.Hour)
+	if w.Matches(big) {
+		fw.Fatalf("w.Matches(big) = true for an empty file")
+	}
+	ioutil.WriteFile(big, make([]byte, 100), 0644)
+	if !w.Matches(big) {
+		fw.Errorf("decision depending on a predicate was cached")
+	}
+}
+
+func TestReload(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rules := filepath.Join(dir, "rules")
+	ioutil.WriteFile(rules, []byte("*.a\n"), 0644)
+
+	w, err := New("rules").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.c")
+	w.EnableCache(time.Hour)
+	if w.Changed() || !w.Matches("x.a") {
+		fw.Fatal("w.Changed() = true or wrong result before changing the file")
+	}
+
+	ioutil.WriteFile(rules, []byte("*.bb\n"), 0644)
+	if !w.Changed() {
+		fw.Error("w.Changed() = false after changing the file")
+	}
+	if err := w.Reload(); err != nil {
+		fw.Fatal(err)
+	}
+	if w.Changed() || w.Matches("x.a") || !w.Matches("x.bb") || !w.Matches("x.c") {
+		fw.Errorf("w.Reload() did not replace the rules of the file: %v", w.Rules())
+	}
+
+	// In ModeCollect, a file with an invalid line is reloaded without it.
+	m := New("rules")
+	m.Mode = ModeCollect
+	c, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	ioutil.WriteFile(rules, []byte("*.cc\na[\n"), 0644)
+	err = c.Reload()
+	if errs, _ := err.(PatternErrors); len(errs) != 1 || c.Matches("x.bb") || !c.Matches("x.cc") {
+		fw.Errorf("c.Reload() with an invalid line = %v, rules %v", err, c.Rules())
+	}
+
+	os.Remove(rules)
+	if err := w.Reload(); err != nil || w.Matches("x.bb") || len(w.ConfigFiles()) != 0 {
+		fw.Errorf("w.Reload() after removing the file = %v, rules %v", err, w.Rules())
+	}
+}
@@ -8,18 +8,32 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goulash/matcher/glob"
 )
 
 // The (above) error variables are returned by Check in BadPatternError.
 var (
-	ErrUnexpectedRune     = errors.New("unexpected rune")
-	ErrNegativeRange      = errors.New("negative range")
-	ErrDualStar           = errors.New("dual stars not supported")
-	ErrEmptyClass         = errors.New("character class empty")
-	ErrEmptyGlob          = errors.New("glob empty")
-	ErrIncompleteClass    = errors.New("character class incomplete")
-	ErrTrailingEscape     = errors.New("trailing escape character")
-	ErrTrailingWhitespace = errors.New("trailing whitespace")
+	ErrUnexpectedRune     = glob.ErrUnexpectedRune
+	ErrNegativeRange      = glob.ErrNegativeRange
+	ErrDualStar           = glob.ErrDualStar
+	ErrEmptyClass         = glob.ErrEmptyClass
+	ErrEmptyGlob          = glob.ErrEmptyGlob
+	ErrIncompleteClass    = glob.ErrIncompleteClass
+	ErrTrailingEscape     = glob.ErrTrailingEscape
+	ErrTrailingWhitespace = glob.ErrTrailingWhitespace
+	ErrBraceExpansion     = errors.New("brace expansion too large")
+	ErrBadPredicate       = errors.New("invalid predicate")
+	ErrBadMacro           = errors.New("invalid macro definition")
+	ErrUndefinedMacro     = errors.New("undefined macro")
+	ErrMacroCycle         = errors.New("macro references itself")
+	ErrMacroExpansion     = errors.New("macro expansion too large")
+	ErrBadVariable        = errors.New("invalid variable")
+	ErrUndefinedVariable  = errors.New("undefined variable")
+	ErrVariableExpansion  = errors.New("variable expansion too large")
+	ErrExtension          = errors.New("extension of the gitignore syntax")
 )
 
 // BadPatternError is what is returned by Check.
@@ -34,12 +48,34 @@ var (
 //     ErrIncompleteClass
 //     ErrTrailingEscape
 //     ErrTrailingWhitespace
+//     ErrBraceExpansion
+//     ErrBadPredicate
+//     ErrBadMacro
+//     ErrUndefinedMacro
+//     ErrMacroCycle
+//     ErrMacroExpansion
+//     ErrBadVariable
+//     ErrUndefinedVariable
+//     ErrVariableExpansion
+//     ErrUnknownSyntax
+//     ErrBadRegexp
+//     ErrExtension
+//
+// Check never returns the last eleven, but they are returned for invalid
+// predicates, macros, and variables in rule files, for invalid syntax
+// lines and regular expressions in .hgignore files, and for extensions
+// of the gitignore syntax in ModeStrict.
 //
+// Column is the index of the rune of the pattern or line at which the problem
+// was detected, as is RuneColumn, which is for people to read. ByteColumn is
+// the index of its first byte, which editors need to place a marker.
 type BadPatternError struct {
-	Err    error
-	Column int
-	Line   int
-	File   string
+	Err        error
+	Column     int
+	RuneColumn int
+	ByteColumn int
+	Line       int
+	File       string
 }
 
 func (pe *BadPatternError) Error() string {
@@ -49,12 +85,39 @@ func (pe *BadPatternError) Error() string {
 	return fmt.Sprintf("%s:%d:%d: %s", pe.File, pe.Line, pe.Column, pe.Err)
 }
 
-// Check returns nil when the glob pattern is okay.
+// Unwrap returns Err, so that errors.Is finds the error variables.
+func (pe *BadPatternError) Unwrap() error {
+	return pe.Err
+}
+
+// locate sets RuneColumn and ByteColumn from Column, which is a column
+// of s.
+func (pe *BadPatternError) locate(s string) {
+	pe.RuneColumn, pe.ByteColumn = pe.Column, len(s)
+	n := 0
+	for i := range s {
+		if n == pe.Column {
+			pe.ByteColumn = i
+			break
+		}
+		n++
+	}
+}
+
+// Check returns nil when the glob pattern is okay. It is the same as
+// glob.Check, but returns a BadPatternError, allows "**" as an element
+// of a path, where it matches any number of directories, and expands brace
+// expressions, so that "*.{jpg,png}" stands for both "*.jpg" and "*.png".
 // The pattern syntax is:
 //
 //  pattern:
 //      { term }
 //  term:
+//      '{' pattern { ',' pattern } '}'
+//                  matches any of the patterns, each of which is combined
+//                  with the rest of the pattern and checked on its own
+//      '**'        as a whole element of a path, matches any number of
+//                  elements (see the package documentation)
 //      '*'         matches any sequence of non-Separator characters
 //      '?'         matches any single non-Separator character
 //      '[' [ '^' ] { character-range } ']'
@@ -67,129 +130,74 @@ func (pe *BadPatternError) Error() string {
 //      '\\' c      matches character c
 //      lo '-' hi   matches character c for lo <= c <= hi
 //
+// Braces without a comma between them, and escaped braces and commas, are
+// matched literally. An error in one of the patterns of a brace expression
+// is reported at the column of its opening brace.
+//
 // The only possible returned error is BadPatternError, when pattern
 // is malformed.
-func Check(glob string) error {
-	type State int
-	const (
-		Initial State = iota
-		Regular
-		ClassBegin
-		ClassMiddle
-		ClassRange
-		ClassRequire
-		Star
-		DualStar
-		Escape
-		Whitespace
-	)
+func Check(pattern string) error {
+	_, err := expandPattern(pattern)
+	return err
+}
 
-	column := -1
-	give := func(e error) error {
-		return &BadPatternError{
-			Err:    e,
-			Column: column,
-			Line:   -1,
-			File:   "",
+// expandPattern returns the globs that the brace expressions in pattern
+// expand to, after checking each of them as Check does.
+func expandPattern(pattern string) ([]string, error) {
+	globs, err := expandBraces(pattern)
+	if err != nil {
+		return nil, &BadPatternError{Err: err, Column: 0, Line: -1}
+	}
+	for _, g := range globs {
+		if err := checkPattern(g); err != nil {
+			pe := err.(*BadPatternError)
+			if len(globs) > 1 {
+				open, _, _, _ := braces(pattern)
+				pe.Column = utf8.RuneCountInString(pattern[:open])
+			}
+			pe.locate(pattern)
+			return nil, pe
 		}
 	}
+	return globs, nil
+}
 
-	var last rune
-	var state State
-	var next State
-	for _, r := range glob {
-		column++
-		switch state {
-		case Initial:
-			state = Regular
-			fallthrough
-		case Regular:
-			switch r {
-			case '[':
-				state = ClassBegin
-			case '*':
-				state = Star
-			case '\\':
-				state = Escape
-				next = Regular
-			case ' ', '\t', '\n': // find out if this is the end
-				state = Whitespace
-			default:
-			}
-		case ClassBegin:
-			switch r {
-			case ']':
-				return give(ErrEmptyClass)
-			case '-':
-				return give(ErrUnexpectedRune)
-			case '\\':
-				state = Escape
-				next = ClassRequire
-			default:
-				last = r
-				state = ClassMiddle
-			}
-		case ClassRequire:
-			if r == '-' {
-				return give(ErrUnexpectedRune)
-			}
-			state = ClassMiddle
-			fallthrough
-		case ClassMiddle:
-			switch r {
-			case '\\':
-				state = Escape
-				next = ClassMiddle
-			case ']':
-				state = Regular
-			case '-':
-				state = ClassRange
-			default:
-				last = r
-			}
-		case ClassRange:
-			switch r {
-			// TODO: following case may be unnecessary
-			case '-', '\\', ']':
-				return give(ErrUnexpectedRune)
-			default:
-				if r-last < 0 {
-					return give(ErrNegativeRange)
-				}
-				state = ClassRequire
-			}
-		case Escape:
-			state = next
-		case Star:
-			switch r {
-			case '*':
-				state = DualStar
-			default:
-				state = Regular
-			}
-		case DualStar:
-			return give(ErrDualStar)
-		case Whitespace:
-			switch r {
-			case ' ', '\t', '\n':
-			default:
-				state = Regular
+// checkPattern is Check for a pattern whose braces are literal.
+func checkPattern(pattern string) error {
+	if !hasDualStar(pattern) {
+		return checkGlob(pattern, 0)
+	}
+	var column int
+	elems := strings.Split(pattern, "/")
+	for i, e := range elems {
+		if e != "" && e != "**" {
+			err := checkGlob(e, column)
+			if err != nil && (i == len(elems)-1 || err.(*BadPatternError).Err != ErrTrailingWhitespace) {
+				return err
 			}
 		}
+		column += utf8.RuneCountInString(e) + 1
 	}
+	return nil
+}
 
-	switch state {
-	case Initial:
-		return give(ErrEmptyGlob)
-	case ClassBegin, ClassMiddle, ClassRange:
-		return give(ErrIncompleteClass)
-	case Escape:
-		return give(ErrTrailingEscape)
-	case Whitespace:
-		return give(ErrTrailingWhitespace)
-	default:
-		return nil
+// checkGlob is glob.Check for a pattern that starts at column.
+func checkGlob(pattern string, column int) error {
+	if err := glob.Check(pattern); err != nil {
+		e := err.(*glob.Error)
+		return &BadPatternError{Err: e.Err, Column: column + e.Column, Line: -1}
 	}
+	return nil
+}
+
+// hasDualStar reports whether "**" is an element of the path pattern.
+func hasDualStar(pattern string) bool {
+	for _, e := range strings.Split(pattern, "/") {
+		if e == "**" {
+			return true
+		}
+	}
+	return false
 }
 
 // Clean discards parts of s that are not needed, as gitignore does.
@@ -236,10 +244,15 @@ func Clean(s string) string {
 			switch r {
 			case ' ', '\t', '\n':
 				sp.WriteRune(r)
+			case '\\':
+				state = Escape
+				buf.Write(sp.Bytes())
+				sp.Reset()
 			default:
 				state = Regular
 				buf.Write(sp.Bytes())
 				sp.Reset()
+				buf.WriteRune(r)
 			}
 		}
 	}
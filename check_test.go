@@ -4,7 +4,10 @@
 
 package matcher
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestCheck(fw *testing.T) {
 	tests := map[string]error{
@@ -47,7 +50,14 @@ func TestCheck(fw *testing.T) {
 		" ":              ErrTrailingWhitespace,
 		"ab ":            ErrTrailingWhitespace,
 		"[\\--]":         ErrUnexpectedRune,
-		"foo/**/bar":     ErrDualStar,
+		"foo/**/bar":     nil,
+		"**/bar":         nil,
+		"foo/**":         nil,
+		"**":             nil,
+		"foo/a**/bar":    ErrDualStar,
+		"foo/**/a**":     ErrDualStar,
+		"foo /**/bar":    nil,
+		"foo/**/bar ":    ErrTrailingWhitespace,
 		"foo[]bar":       ErrEmptyClass,
 		"[z-a]":          ErrNegativeRange,
 		"]":              nil,
@@ -70,3 +80,55 @@ func TestCheck(fw *testing.T) {
 		}
 	}
 }
+
+func TestClean(fw *testing.T) {
+	tests := map[string]string{
+		"":            "",
+		"# comment":   "",
+		"\\# hash":    "\\# hash",
+		"foo":         "foo",
+		"foo  ":       "foo",
+		"foo\\ ":      "foo\\ ",
+		"foo\\":       "foo",
+		"a b":         "a b",
+		"a \t b  ":    "a \t b",
+		"a \\ b":      "a \\ b",
+		"type:dir *":  "type:dir *",
+		"  leading":   "  leading",
+		"foo # bar  ": "foo # bar",
+	}
+
+	for k, v := range tests {
+		if s := Clean(k); s != v {
+			fw.Errorf("Clean(%q) = %q, expected %q", k, s, v)
+		}
+	}
+}
+
+func TestColumns(fw *testing.T) {
+	type columns struct{ Rune, Byte int }
+	tests := map[string]columns{
+		"a[":      {1, 1},
+		"äb[":     {2, 3},
+		"größe/[": {6, 8},
+		"*.{ä,[}": {2, 2},
+		"ä.{b,[}": {2, 3},
+		"日本/**/[": {6, 10},
+	}
+	for k, v := range tests {
+		pe, ok := Check(k).(*BadPatternError)
+		if !ok || pe.Column != v.Rune || pe.RuneColumn != v.Rune || pe.ByteColumn != v.Byte {
+			fw.Errorf("Check(%q) = %+v, expected rune column %d and byte column %d", k, pe, v.Rune, v.Byte)
+		}
+	}
+
+	w := New("").newWorker("/")
+	err := w.AddReader(strings.NewReader("*.o\n  größe/[\n"), "rules")
+	if pe, ok := err.(*BadPatternError); !ok || pe.Line != 2 || pe.RuneColumn != 8 || pe.ByteColumn != 10 {
+		fw.Errorf("w.AddReader() = %+v, expected line 2, rune column 8, byte column 10", err)
+	}
+	diags, _ := Lint(strings.NewReader("größe/[\n"), "rules")
+	if len(diags) != 1 || diags[0].Column != 6 || diags[0].ByteColumn != 8 {
+		fw.Errorf("Lint() = %v, expected column 6 and byte column 8", diags)
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Command matchergen reads rule files at build time and writes a Go source
+// file containing a matcher function for them. The globs are compiled by
+// matchergen and written out as tables, so the generated code depends only
+// on the standard library, and programs with fixed rules need neither this
+// package nor any parsing at run time.
+//
+// It is meant to be used with go generate:
+//
+//	//go:generate matchergen -pkg main -func ignored -o ignored.go .ignore
+//
+// The generated function has the signature
+//
+//	func ignored(path string) bool
+//
+// and reports whether path is matched in the same way as Worker.Matches of
+// a Worker in the current directory to which the rule files were added with
+// AddFile, including negated rules, "**", and the precedence of later rules.
+// Globs containing a path separator are relative to the directory of the
+// rule file they are in, which is made relative to the current directory,
+// so paths passed to the function must be relative to it as well.
+// Rules with a trailing slash only match paths that end in a separator,
+// since the generated function does not look at the file system, so
+// directories must be passed with one.
+//
+// Rules with predicates depend on the file system and cannot be generated.
+// Neither can regular expressions, or globs ending in unescaped whitespace.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goulash/matcher"
+	"github.com/goulash/matcher/glob"
+)
+
+func main() {
+	var (
+		pkg    = flag.String("pkg", "main", "package name of the generated file")
+		fn     = flag.String("func", "matches", "name of the generated function")
+		output = flag.String("o", "", "output file (default standard output)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: matchergen [flags] file...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	rules, err := load(flag.Args())
+	if err != nil {
+		fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := generate(&buf, *pkg, *fn, rules, os.Args[1:]); err != nil {
+		fatal(err)
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = ioutil.WriteFile(*output, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "matchergen: %s\n", err)
+	os.Exit(1)
+}
+
+// load reads the rule files and returns their rules in the order in which
+// a Worker tries them. Globs containing a path separator are made relative
+// to the current directory.
+func load(files []string) ([]matcher.Rule, error) {
+	m := matcher.New("")
+	m.SetScope(matcher.ScopeUser, false)
+	m.SetScope(matcher.ScopeSystem, false)
+	w, err := m.NewWorker(".")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := w.AddFile(f); err != nil {
+			return nil, err
+		}
+	}
+
+	rules := precedence(w.Rules())
+	for i, r := range rules {
+		if r.Cond != "" {
+			return nil, fmt.Errorf("%s:%d: rule %q has predicates, which cannot be generated", r.Source, r.Line, r.String())
+		}
+		if r.Regexp {
+			return nil, fmt.Errorf("%s:%d: rule %q is a regular expression, which cannot be generated", r.Source, r.Line, r.String())
+		}
+		if !strings.Contains(r.Glob, "/") {
+			continue
+		}
+		rel, err := filepath.Rel(glob.QuoteMeta(w.Dir()), r.Glob)
+		if err != nil {
+			return nil, err
+		}
+		rules[i].Glob = filepath.ToSlash(rel)
+	}
+	return rules, nil
+}
+
+// precedence returns the rules in the order in which a Worker tries them:
+// the rules of a file come before those of the files after it, but within
+// a file, later rules come first.
+func precedence(rules []matcher.Rule) []matcher.Rule {
+	out := make([]matcher.Rule, 0, len(rules))
+	for i := 0; i < len(rules); {
+		j := i + 1
+		for j < len(rules) && rules[j].Source == rules[i].Source && rules[j].Scope == rules[i].Scope {
+			j++
+		}
+		for k := j - 1; k >= i; k-- {
+			out = append(out, rules[k])
+		}
+		i = j
+	}
+	return out
+}
+
+// generate writes the source of a file in package pkg that contains the
+// function fn matching the rules, which are tried in order. The args are
+// recorded in the header.
+func generate(w io.Writer, pkg, fn string, rules []matcher.Rule, args []string) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"matchergen %s\"; DO NOT EDIT.\n\n", strings.Join(args, " "))
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "import (\n\t\"os\"\n\t\"path/filepath\"\n\t\"strings\"\n\t\"unicode/utf8\"\n)\n\n")
+
+	fmt.Fprintf(&buf, "// %sRules are the rules in the order in which they are tried.\n", fn)
+	fmt.Fprintf(&buf, "var %sRules = []%sRule{\n", fn, fn)
+	for _, r := range rules {
+		if err := writeRule(&buf, fn, r); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(&buf, "}\n\n")
+	buf.WriteString(strings.Replace(runtime, "FN", fn, -1))
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// writeRule writes the table entry of r. Globs with "**" are split into
+// the elements of the path, as package matcher does.
+func writeRule(buf *bytes.Buffer, fn string, r matcher.Rule) error {
+	elems := []string{r.Glob}
+	split := false
+	for _, e := range strings.Split(r.Glob, "/") {
+		if e == "**" {
+			elems, split = strings.Split(r.Glob, "/"), true
+			break
+		}
+	}
+
+	fmt.Fprintf(buf, "\t// %s", r.String())
+	if r.Source != "" {
+		fmt.Fprintf(buf, " (%s:%d)", filepath.Base(r.Source), r.Line)
+	}
+	fmt.Fprintf(buf, "\n\t{negate: %v, dirOnly: %v, base: %v, split: %v, elems: []%sElem{\n",
+		r.Negate, r.DirOnly, !strings.Contains(r.Glob, "/"), split, fn)
+	for _, e := range elems {
+		if split && e == "**" {
+			fmt.Fprintf(buf, "\t\t{dualStar: true},\n")
+			continue
+		}
+		var chunks []glob.Chunk
+		if e != "" {
+			g, err := glob.Compile(e)
+			if err != nil {
+				return fmt.Errorf("%s:%d: rule %q cannot be generated: %s", r.Source, r.Line, r.String(), err)
+			}
+			chunks = g.Chunks()
+		}
+		fmt.Fprintf(buf, "\t\t{chunks: []%sChunk{", fn)
+		for _, c := range chunks {
+			fmt.Fprintf(buf, "{star: %v, tokens: []%sToken{", c.Star, fn)
+			for _, t := range c.Tokens {
+				writeToken(buf, t)
+			}
+			fmt.Fprintf(buf, "}},")
+		}
+		fmt.Fprintf(buf, "}},\n")
+	}
+	fmt.Fprintf(buf, "\t}},\n")
+	return nil
+}
+
+func writeToken(buf *bytes.Buffer, t glob.Token) {
+	switch t.Kind {
+	case glob.TokenLiteral:
+		fmt.Fprintf(buf, "{lit: %q},", t.Literal)
+	case glob.TokenAny:
+		fmt.Fprintf(buf, "{any: true},")
+	case glob.TokenClass:
+		fmt.Fprintf(buf, "{class: true, negate: %v, ranges: []rune{", t.Negate)
+		for _, r := range t.Ranges {
+			fmt.Fprintf(buf, "%q, %q, ", r.Lo, r.Hi)
+		}
+		fmt.Fprintf(buf, "}},")
+	}
+}
+
+// runtime is the code that matches paths against the tables, with FN
+// standing for the name of the generated function. It matches as package
+// glob and package matcher do.
+const runtime = `// FN reports whether path is matched by the rules it was generated from.
+// Directories must be passed with a trailing separator to be matched by
+// rules that only match directories.
+func FN(path string) bool {
+	if path == "" {
+		return false
+	}
+	dir := os.IsPathSeparator(path[len(path)-1])
+	path = filepath.ToSlash(filepath.Clean(path))
+	name := path[strings.LastIndexByte(path, '/')+1:]
+	for i := range FNRules {
+		r := &FNRules[i]
+		if r.dirOnly && !dir {
+			continue
+		}
+		s := path
+		if r.base {
+			s = name
+		}
+		var ok bool
+		if r.split {
+			ok = FNMatchElems(r.elems, strings.Split(s, "/"))
+		} else {
+			ok = FNMatchChunks(r.elems[0].chunks, s)
+		}
+		if ok {
+			return !r.negate
+		}
+	}
+	return false
+}
+
+type FNRule struct {
+	negate, dirOnly, base, split bool
+	elems                        []FNElem
+}
+
+type FNElem struct {
+	dualStar bool
+	chunks   []FNChunk
+}
+
+type FNChunk struct {
+	star   bool
+	tokens []FNToken
+}
+
+type FNToken struct {
+	lit        string
+	any, class bool
+	negate     bool
+	ranges     []rune
+}
+
+func FNMatchElems(pattern []FNElem, elems []string) bool {
+	for i, p := range pattern {
+		if p.dualStar {
+			rest := pattern[i+1:]
+			if len(rest) == 0 {
+				return len(elems) > i
+			}
+			for j := i; j <= len(elems); j++ {
+				if FNMatchElems(rest, elems[j:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if i >= len(elems) || !FNMatchChunks(p.chunks, elems[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(elems)
+}
+
+func FNMatchChunks(chunks []FNChunk, s string) bool {
+Chunks:
+	for i, c := range chunks {
+		last := i == len(chunks)-1
+		if c.star && len(c.tokens) == 0 {
+			return strings.IndexByte(s, '/') < 0
+		}
+		if t, ok := FNMatchTokens(c.tokens, s); ok && (len(t) == 0 || !last) {
+			s = t
+			continue
+		}
+		if c.star {
+			for j := 0; j < len(s) && s[j] != '/'; j++ {
+				t, ok := FNMatchTokens(c.tokens, s[j+1:])
+				if ok && (len(t) == 0 || !last) {
+					s = t
+					continue Chunks
+				}
+			}
+		}
+		return false
+	}
+	return len(s) == 0
+}
+
+func FNMatchTokens(tokens []FNToken, s string) (string, bool) {
+	for _, t := range tokens {
+		switch {
+		case t.any:
+			if len(s) == 0 || s[0] == '/' {
+				return "", false
+			}
+			_, n := utf8.DecodeRuneInString(s)
+			s = s[n:]
+		case t.class:
+			if len(s) == 0 {
+				return "", false
+			}
+			r, n := utf8.DecodeRuneInString(s)
+			s = s[n:]
+			match := false
+			for i := 0; i < len(t.ranges); i += 2 {
+				if t.ranges[i] <= r && r <= t.ranges[i+1] {
+					match = true
+					break
+				}
+			}
+			if match == t.negate {
+				return "", false
+			}
+		default:
+			if !strings.HasPrefix(s, t.lit) {
+				return "", false
+			}
+			s = s[len(t.lit):]
+		}
+	}
+	return s, true
+}
+`
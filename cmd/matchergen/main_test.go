@@ -0,0 +1,229 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/goulash/matcher"
+)
+
+func TestLoad(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matchergen")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rules := filepath.Join(dir, "rules")
+	ioutil.WriteFile(rules, []byte("*.o\nvendor\nbuild/*.tmp\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "bad"), []byte("size:>1M *.iso\n"), 0644)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	rs, err := load([]string{"rules"})
+	if err != nil {
+		fw.Fatal(err)
+	}
+	var globs []string
+	for _, r := range rs {
+		globs = append(globs, r.Glob)
+	}
+	expected := []string{"build/*.tmp", "vendor", "*.o"}
+	if !reflect.DeepEqual(globs, expected) {
+		fw.Errorf("load() globs = %q, expected %q", globs, expected)
+	}
+
+	if _, err := load([]string{"bad"}); err == nil {
+		fw.Errorf("load() of rules with predicates succeeded")
+	}
+}
+
+func TestGenerate(fw *testing.T) {
+	rules := []matcher.Rule{{Glob: "*.o"}, {Glob: "build/[a-c]?.tmp", Negate: true}}
+	var buf bytes.Buffer
+	if err := generate(&buf, "foo", "ignored", rules, []string{"rules"}); err != nil {
+		fw.Fatal(err)
+	}
+	src := buf.String()
+	for _, s := range []string{
+		"package foo",
+		"func ignored(path string) bool",
+		`{lit: ".o"}`,
+		`{class: true, negate: false, ranges: []rune{'a', 'c'}}`,
+		"negate: true",
+	} {
+		if !strings.Contains(src, s) {
+			fw.Errorf("generated source does not contain %q:\n%s", s, src)
+		}
+	}
+	if strings.Contains(src, "filepath.Match") {
+		fw.Errorf("generated source parses globs at run time:\n%s", src)
+	}
+
+	bad := []matcher.Rule{{Glob: "a\\"}}
+	if err := generate(&buf, "foo", "ignored", bad, nil); err == nil {
+		fw.Error("generate() of a malformed glob succeeded")
+	}
+}
+
+// extraRules are matched along with the rules of the corpus, to cover
+// what it does not use.
+const extraRules = `[a-c]?.txt
+[^x]y.dat
+\#lit
+\*star
+x/**/y
+z/**
+!z/keep
+`
+
+var extraPaths = []string{
+	"a1.txt", "d1.txt", "ab.txt", "zy.dat", "xy.dat", "#lit", "*star", "xstar",
+	"x/y", "x/a/b/y", "x/a/y/", "y", "z", "z/a", "z/keep", "z/a/keep", "./a1.txt",
+	"sub/../b2.txt",
+}
+
+// TestCorpus compares the generated function with Worker.Matches on the
+// rule files and paths of the corpus of package matcher.
+func TestCorpus(fw *testing.T) {
+	if testing.Short() {
+		fw.Skip("runs the go command")
+	}
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		fw.Skip("go command not found")
+	}
+	corpora, _ := filepath.Glob(filepath.Join("..", "..", "testdata", "corpus", "*.txt"))
+	if len(corpora) == 0 {
+		fw.Fatal("no corpora found")
+	}
+	for _, name := range corpora {
+		files, paths, err := readCorpus(name)
+		if err != nil {
+			fw.Fatal(err)
+		}
+		files["extra.ignore"] = extraRules
+		paths = append(paths, extraPaths...)
+		compareCorpus(fw, goCmd, files, paths)
+	}
+}
+
+// readCorpus returns the rule files of a corpus by their paths, and
+// its paths, with a trailing slash for directories.
+func readCorpus(name string) (map[string]string, []string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	files := make(map[string]string)
+	var (
+		paths   []string
+		section string
+	)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		s := sc.Text()
+		switch {
+		case strings.HasPrefix(s, "-- ") && strings.HasSuffix(s, " --"):
+			section = strings.TrimSpace(s[3 : len(s)-3])
+		case section == "paths":
+			if len(s) > 2 {
+				paths = append(paths, s[2:])
+			}
+		case section != "":
+			files[section] += s + "\n"
+		}
+	}
+	return files, paths, sc.Err()
+}
+
+func compareCorpus(fw *testing.T, goCmd string, files map[string]string, paths []string) {
+	dir, err := ioutil.TempDir("", "matchergen")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var names []string
+	for name, rules := range files {
+		p := filepath.Join(dir, "tree", filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		ioutil.WriteFile(p, []byte(rules), 0644)
+		names = append(names, filepath.Join("tree", filepath.FromSlash(name)))
+	}
+	sort.Strings(names)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	rules, err := load(names)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w, err := matcher.New("").NewWorker(".")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	for _, name := range names {
+		w.AddFile(name)
+	}
+
+	prog := filepath.Join(dir, "prog")
+	os.Mkdir(prog, 0755)
+	var buf bytes.Buffer
+	if err := generate(&buf, "main", "ignored", rules, names); err != nil {
+		fw.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(prog, "ignored.go"), buf.Bytes(), 0644)
+	ioutil.WriteFile(filepath.Join(prog, "main.go"), []byte(`package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+func main() {
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		fmt.Println(ignored(sc.Text()))
+	}
+}
+`), 0644)
+
+	var in []string
+	for _, p := range paths {
+		in = append(in, "tree/"+p)
+	}
+	cmd := exec.Command(goCmd, "run", "ignored.go", "main.go")
+	cmd.Dir = prog
+	cmd.Env = append(os.Environ(), "GO111MODULE=off")
+	cmd.Stdin = strings.NewReader(strings.Join(in, "\n") + "\n")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		fw.Fatalf("running the generated code failed: %s\n%s", err, out)
+	}
+	results := strings.Fields(string(out))
+	if len(results) != len(in) {
+		fw.Fatalf("the generated code printed %d results for %d paths:\n%s", len(results), len(in), out)
+	}
+	for i, p := range in {
+		if m := w.Matches(p); results[i] != strconv.FormatBool(m) {
+			fw.Errorf("generated code matched %q: %s, but w.Matches() = %v", p, results[i], m)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// config is a configuration file that has been read by a Worker.
+type config struct {
+	path  string
+	name  string // as passed to addFile
+	scope Scope
+
+	// fsys is the file system that the file was read from with AddFileFS,
+	// or nil for the file system of the OS.
+	fsys fs.FS
+
+	// modTime and size are those of the file when it was read.
+	modTime time.Time
+	size    int64
+
+	// nested is whether the file was found beneath the working directory
+	// because of Matcher.DiscoverDepth; see addNested.
+	nested bool
+}
+
+// DefaultSentinels are the sentinels used if Matcher.Sentinels is nil.
+var DefaultSentinels = []string{".git"}
+
+// configDirs returns the directories in which configuration files
+// are looked for, starting with dir and going up to, but not including,
+// the root directory. It stops at the first directory that contains
+// a sentinel, or that is the Root of the Matcher.
+func (m *Matcher) configDirs(dir string) []string {
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		if m.isRoot(dir) {
+			break
+		}
+		dir = filepath.Clean(filepath.Join(dir, ".."))
+		if dir == "/" {
+			break
+		}
+	}
+	return dirs
+}
+
+// isRoot returns whether dir is the Root of the Matcher
+// or contains one of its sentinels.
+func (m *Matcher) isRoot(dir string) bool {
+	if m.Root != "" {
+		if root, err := filepath.Abs(m.Root); err == nil && root == dir {
+			return true
+		}
+	}
+	for _, s := range m.sentinels() {
+		if _, err := os.Lstat(filepath.Join(dir, s)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// sentinels returns the sentinels of the Matcher, or DefaultSentinels.
+func (m *Matcher) sentinels() []string {
+	if m.Sentinels == nil {
+		return DefaultSentinels
+	}
+	return m.Sentinels
+}
+
+// Root returns the root of the project that the Worker is in, i.e. the
+// nearest directory containing one of the sentinels of the Matcher, or its
+// Root, starting with the working directory. It returns "" if none of the
+// directories is one.
+func (w *Worker) Root() string {
+	return w.root
+}
+
+// loaded returns whether the Worker has read the configuration file path.
+func (w *Worker) loaded(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, c := range w.configs {
+		if c.path == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigsFor returns the existing rule files that can influence whether
+// path is matched, in order of precedence, so that editors can offer to
+// open the relevant one. These are the configuration files and their local
+// overrides in the directories containing path, followed by the files of
+// the user and system scopes that the Worker has read.
+//
+// A relative path is relative to the working directory of the Worker.
+// Configuration files beneath the working directory are included,
+// even though the Worker itself only reads them for Matcher.DiscoverDepth.
+func (w *Worker) ConfigsFor(path string) []string {
+	var files []string
+	if len(w.m.names) > 0 {
+		for _, d := range w.m.configDirs(filepath.Dir(w.abs(path))) {
+			for _, name := range w.m.configNames() {
+				p := filepath.Join(d, name)
+				if _, err := os.Stat(p); err == nil {
+					files = append(files, p)
+				}
+			}
+		}
+	}
+	for _, s := range []Scope{ScopeUser, ScopeSystem} {
+		for _, c := range w.configs {
+			if c.scope == s {
+				files = append(files, c.path)
+			}
+		}
+	}
+	return files
+}
+
+// ConfigFiles returns the absolute paths of the rule files that the Worker
+// has read successfully, both in NewWorker and through AddFile, in the order
+// in which Reload reads them. This is the order they were read in, except
+// that the files found through Matcher.DiscoverDepth come before all the
+// others, the last one found first, since their rules take precedence.
+// A configuration file that NewWorker did not find or could not parse is
+// not included.
+func (w *Worker) ConfigFiles() []string {
+	files := make([]string, len(w.configs))
+	for i, c := range w.configs {
+		files[i] = c.path
+	}
+	return files
+}
+
+// Scope returns a Worker for the subdirectory dir of the working directory,
+// like fs.Sub does for file systems, so that a library handed a subtree
+// still enforces the rules of its parents. Relative paths passed to the new
+// Worker are resolved against dir. A relative dir is relative to the working
+// directory; it is an error if dir is not beneath it.
+//
+// The new Worker has all the rules of w, and in addition those of the
+// configuration files in dir and the directories between it and the working
+// directory, which w does not read. Their rules take precedence over those
+// of w in the project scope, as if NewWorker had been called with dir.
+// Errors reading them are handled as by NewWorker.
+func (w *Worker) Scope(dir string) (*Worker, error) {
+	dir = w.abs(dir)
+	rel, err := filepath.Rel(w.cwd, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, ErrNotSubdir
+	}
+
+	c := w.Clone()
+	c.cwd = dir
+	if len(w.m.names) == 0 || w.m.disabled[ScopeProject] || rel == "." {
+		return c, nil
+	}
+
+	t := *c
+	t.local, t.configs = nil, nil
+	var errs ConfigErrors
+	for d := dir; d != w.cwd; d = filepath.Dir(d) {
+		for _, name := range w.m.configNames() {
+			path := filepath.Join(d, name)
+			if w.loaded(path) {
+				// It was discovered by NewWorker already.
+				continue
+			}
+			if err := t.load(path, ScopeProject, &errs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	i := 0
+	for i < len(c.local) && c.local[i].Scope < ScopeProject {
+		i++
+	}
+	c.own()
+	c.local = append(c.local[:i], append(t.local, c.local[i:]...)...)
+	c.configs = append(c.configs, t.configs...)
+	if errs != nil {
+		return c, errs
+	}
+	return c, nil
+}
+
+// discover reads the configuration files in the subdirectories of the
+// working directory, down to Matcher.DiscoverDepth levels beneath it, for
+// NewWorker. Directories that the Worker matches, including by the rules
+// discovered so far, and those that are the root of another project are
+// not scanned. Directories that cannot be read are skipped. Whether the
+// Worker matches a directory is decided by a quiet copy of it, so that
+// scanning leaves no hits, metrics, or audit records.
+func (w *Worker) discover(errs *ConfigErrors) error {
+	m := w.m
+	if m.DiscoverDepth <= 0 || len(m.names) == 0 || m.disabled[ScopeProject] {
+		return nil
+	}
+	q, n := w.quiet(), len(w.configs)
+	w.scanned = append(w.scanned, w.cwd)
+	return filepath.WalkDir(w.cwd, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil || dir == w.cwd || !d.IsDir() {
+			return nil
+		}
+		if len(w.configs) != n {
+			q, n = w.quiet(), len(w.configs)
+		}
+		fi, err := d.Info()
+		if err != nil || q.MatchesInfo(dir, fi) || m.isRoot(dir) {
+			return filepath.SkipDir
+		}
+		w.scanned = append(w.scanned, dir)
+		for _, name := range m.configNames() {
+			if err := w.loadNested(filepath.Join(dir, name), errs); err != nil {
+				return err
+			}
+		}
+		rel, _ := filepath.Rel(w.cwd, dir)
+		if strings.Count(rel, string(filepath.Separator))+1 >= m.DiscoverDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// loadNested loads the configuration file path found by discover, whose
+// rules take precedence over those of the files read before it in the
+// project scope, as they would for a Worker created in its directory.
+// Errors are handled as by load.
+func (w *Worker) loadNested(path string, errs *ConfigErrors) error {
+	t := *w
+	t.local, t.configs = nil, nil
+	if err := t.handleLoad(path, t.addNested(path), errs); err != nil {
+		return err
+	}
+	if len(t.configs) == 0 {
+		return nil
+	}
+
+	i := 0
+	for i < len(w.local) && w.local[i].Scope < ScopeProject {
+		i++
+	}
+	w.own()
+	w.local = append(w.local[:i], append(t.local, w.local[i:]...)...)
+	w.configs = append(t.configs, w.configs...)
+	w.invalidate()
+	return nil
+}
+
+// addNested reads the configuration file path in a subdirectory of the
+// working directory into the project scope. Globs without a path separator,
+// which match in any directory, are anchored beneath the directory of the
+// file, so that its rules only apply there.
+func (w *Worker) addNested(path string) error {
+	err := w.addFile(path, ScopeProject)
+	if _, ok := err.(PatternErrors); err != nil && !ok {
+		return err
+	}
+	w.own()
+	base := filepath.Dir(w.abs(path))
+	for i, r := range w.local {
+		if r.Source == path && !r.Regexp && !strings.Contains(r.Glob, "/") {
+			w.local[i].Glob = join(base, "**/"+r.Glob)
+			compiled(w.local[i].Glob)
+		}
+	}
+	w.configs[len(w.configs)-1].nested = true
+	return err
+}
+
+// ErrorPolicy determines what NewWorker does when a configuration file
+// exists but cannot be read or parsed.
+type ErrorPolicy int
+
+const (
+	// Skip ignores the file and continues.
+	Skip ErrorPolicy = iota
+
+	// Abort makes NewWorker fail with the error.
+	Abort
+
+	// Collect ignores the file and continues, but NewWorker returns
+	// all collected errors together with the Worker at the end.
+	Collect
+)
+
+// ConfigErrors are the errors collected by NewWorker under the
+// Collect policy.
+type ConfigErrors []error
+
+func (e ConfigErrors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// policy returns the policy of the Matcher for the error err reading path,
+// along with the error that NewWorker should fail with under Abort.
+func (m *Matcher) policy(path string, err error) (ErrorPolicy, error) {
+	switch {
+	case m.ErrHandler != nil:
+		if err = m.ErrHandler(err); err != nil {
+			return Abort, err
+		}
+		return Skip, nil
+	case m.OnError != nil:
+		return m.OnError(path, err), err
+	default:
+		return m.Policy, err
+	}
+}
+
+// DefaultMaxFileSize is the default of Matcher.MaxFileSize.
+const DefaultMaxFileSize = 10 << 20
+
+func (m *Matcher) maxFileSize() int64 {
+	if m.MaxFileSize == 0 {
+		return DefaultMaxFileSize
+	}
+	return m.MaxFileSize
+}
+
+// limitReader reads from r until n bytes have been read, after which
+// it fails with ErrFileTooLarge if there is more to read.
+type limitReader struct {
+	r    io.Reader
+	n    int64
+	name string
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		return int(l.n), &os.PathError{Op: "read", Path: l.name, Err: ErrFileTooLarge}
+	}
+	l.n -= int64(n)
+	return n, err
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns a reader of the decompressed content of r if r is
+// compressed with gzip, which is detected from its content or name.
+func decompress(r io.Reader, name string) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(gzipMagic))
+	if !bytes.Equal(magic, gzipMagic) && !strings.HasSuffix(name, ".gz") {
+		return br, nil
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, &os.PathError{Op: "read", Path: name, Err: err}
+	}
+	return zr, nil
+}
@@ -0,0 +1,472 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfigsFor(fw *testing.T) {
+	defer noUserFiles(fw)()
+
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	files := []string{
+		"match.conf",
+		"a/b/match.conf",
+		"a/b/match.conf.local",
+		"system",
+	}
+	for _, f := range files {
+		ioutil.WriteFile(filepath.Join(dir, f), nil, 0644)
+	}
+
+	m := New("match.conf")
+	m.SystemFile = filepath.Join(dir, "system")
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "a/b/match.conf.local"),
+		filepath.Join(dir, "a/b/match.conf"),
+		filepath.Join(dir, "match.conf"),
+		filepath.Join(dir, "system"),
+	}
+	if c := w.ConfigsFor("a/b/file"); !reflect.DeepEqual(c, expected) {
+		fw.Errorf("w.ConfigsFor(%q) = %q, expected %q", "a/b/file", c, expected)
+	}
+	if c := w.ConfigsFor("a/b"); !reflect.DeepEqual(c, expected[2:]) {
+		fw.Errorf("w.ConfigsFor(%q) = %q, expected %q", "a/b", c, expected[2:])
+	}
+}
+
+func TestConfigFiles(fw *testing.T) {
+	defer noUserFiles(fw)()
+
+	w, err := New("match.conf").NewWorker("tests/dead/good")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	abs, _ := filepath.Abs("tests")
+	expected := []string{
+		filepath.Join(abs, "dead/good/match.conf"),
+		filepath.Join(abs, "dead/match.conf"),
+		filepath.Join(abs, "match.conf"),
+	}
+	if c := w.ConfigFiles(); !reflect.DeepEqual(c, expected) {
+		fw.Errorf("w.ConfigFiles() = %q, expected %q", c, expected)
+	}
+
+	if err := w.AddFile("tests/missing.conf"); err == nil {
+		fw.Fatalf("w.AddFile of missing file succeeded")
+	}
+	w.AddFile("tests/dead/match.conf")
+	if c := w.ConfigFiles(); len(c) != 4 || c[3] != expected[1] {
+		fw.Errorf("w.ConfigFiles() after AddFile = %q", c)
+	}
+}
+
+func TestErrorPolicy(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+	ioutil.WriteFile(filepath.Join(dir, "match.conf"), []byte("ok\n[\n"), 0644)
+	ioutil.WriteFile(filepath.Join(sub, "match.conf"), []byte("a**b\n"), 0644)
+	ioutil.WriteFile(filepath.Join(sub, "match.conf.local"), []byte("*.swp\n"), 0644)
+
+	m := New("match.conf")
+	w, err := m.NewWorker(sub)
+	if err != nil || !w.Matches("x.swp") {
+		fw.Errorf("NewWorker with Skip = (%v, %v), expected worker", w, err)
+	}
+
+	m.Policy = Abort
+	if _, err = m.NewWorker(sub); err == nil {
+		fw.Errorf("NewWorker with Abort succeeded")
+	}
+
+	m.Policy = Collect
+	w, err = m.NewWorker(sub)
+	errs, ok := err.(ConfigErrors)
+	if w == nil || !ok || len(errs) != 2 {
+		fw.Errorf("NewWorker with Collect = (%v, %v), expected worker and 2 errors", w, err)
+	}
+
+	var paths []string
+	m.OnError = func(path string, err error) ErrorPolicy {
+		paths = append(paths, path)
+		if filepath.Dir(path) == sub {
+			return Skip
+		}
+		return Abort
+	}
+	if _, err = m.NewWorker(sub); err == nil || len(paths) != 2 {
+		fw.Errorf("NewWorker with OnError = %v after %q, expected abort on second file", err, paths)
+	}
+
+	m.ErrHandler = func(error) error { return nil }
+	if _, err = m.NewWorker(sub); err != nil {
+		fw.Errorf("NewWorker with ErrHandler = %v, expected it to take precedence", err)
+	}
+}
+
+func TestStrict(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+
+	// A directory in place of the configuration file cannot be read,
+	// even by root, so this does not depend on permissions.
+	os.Mkdir(filepath.Join(dir, "match.conf"), 0755)
+	ioutil.WriteFile(filepath.Join(sub, "match.conf"), []byte("[\n"), 0644)
+
+	m := New("match.conf")
+	if _, err = m.NewWorker(sub); err != nil {
+		fw.Errorf("NewWorker = %v, expected unreadable file to be skipped", err)
+	}
+
+	m.Strict = true
+	_, err = m.NewWorker(sub)
+	if _, ok := err.(*BadPatternError); err == nil || ok {
+		fw.Errorf("strict NewWorker = %v, expected read error", err)
+	}
+
+	// Parse errors are still subject to the policy.
+	os.Remove(filepath.Join(dir, "match.conf"))
+	if _, err = m.NewWorker(sub); err != nil {
+		fw.Errorf("strict NewWorker = %v, expected parse error to be skipped", err)
+	}
+}
+
+func TestMaxFileSize(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	big := filepath.Join(dir, "big.conf")
+	ioutil.WriteFile(big, []byte("*.aaaa\n*.bbbb\n"), 0644)
+
+	m := New("")
+	for _, max := range []int64{0, -1, 14, 100} {
+		m.MaxFileSize = max
+		w, _ := m.NewWorker(dir)
+		if err := w.AddFile(big); err != nil {
+			fw.Errorf("w.AddFile with MaxFileSize = %d failed: %s", max, err)
+		}
+	}
+
+	m.MaxFileSize = 13
+	w, _ := m.NewWorker(dir)
+	err = w.AddFile(big)
+	if pe, ok := err.(*os.PathError); !ok || pe.Err != ErrFileTooLarge || pe.Path != big {
+		fw.Errorf("w.AddFile with MaxFileSize = 13 = %v, expected ErrFileTooLarge", err)
+	}
+}
+
+func TestGzip(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("*.gen\n"))
+	zw.Close()
+	for _, name := range []string{"rules.gz", "rules"} {
+		ioutil.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0644)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "bad.gz"), []byte("*.txt\n"), 0644)
+
+	for _, name := range []string{"rules.gz", "rules"} {
+		w, _ := New("").NewWorker(dir)
+		if err := w.AddFile(filepath.Join(dir, name)); err != nil || !w.Matches("x.gen") {
+			fw.Errorf("w.AddFile(%q) = %v, rules %v", name, err, w.Rules())
+		}
+	}
+	w, _ := New("").NewWorker(dir)
+	if err := w.AddFile(filepath.Join(dir, "bad.gz")); err == nil {
+		fw.Errorf("w.AddFile of uncompressed .gz file succeeded")
+	}
+}
+
+func TestSentinels(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "services", "api")
+	os.MkdirAll(filepath.Join(sub, "cmd"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "rules"), []byte("*.top\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "services", "rules"), []byte("*.services\n"), 0644)
+	ioutil.WriteFile(filepath.Join(sub, "rules"), []byte("*.api\n"), 0644)
+	ioutil.WriteFile(filepath.Join(sub, "go.mod"), []byte("module api\n"), 0644)
+
+	m := New("rules")
+	m.Sentinels = []string{}
+	w, err := m.NewWorker(filepath.Join(sub, "cmd"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if !w.Matches("x.api") || !w.Matches("x.top") || w.Root() != "" {
+		fw.Errorf("without sentinels, w.Root() = %q and rules = %v", w.Root(), w.Rules())
+	}
+
+	os.Mkdir(filepath.Join(dir, "services", ".git"), 0755)
+	m.Sentinels = nil
+	w, err = m.NewWorker(filepath.Join(sub, "cmd"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if !w.Matches("x.services") || w.Matches("x.top") || w.Root() != filepath.Join(dir, "services") {
+		fw.Errorf("with default sentinels, w.Root() = %q and rules = %v", w.Root(), w.Rules())
+	}
+
+	m.Sentinels = []string{}
+	m.Root = sub
+	w, err = m.NewWorker(filepath.Join(sub, "cmd"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if !w.Matches("x.api") || w.Matches("x.services") || w.Root() != sub {
+		fw.Errorf("with Root, w.Root() = %q and rules = %v", w.Root(), w.Rules())
+	}
+	m.Root = ""
+
+	m.Sentinels = []string{"package.json", "go.mod"}
+	w, err = m.NewWorker(filepath.Join(sub, "cmd"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	tests := map[string]bool{
+		"x.api":      true,
+		"x.services": false,
+		"x.top":      false,
+	}
+	for k, v := range tests {
+		if m := w.Matches(k); m != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, m, v)
+		}
+	}
+	if w.Root() != sub {
+		fw.Errorf("w.Root() = %q, expected %q", w.Root(), sub)
+	}
+	if files := w.ConfigsFor("main.go"); len(files) != 1 {
+		fw.Errorf("w.ConfigsFor() = %q, expected only the rules of %s", files, sub)
+	}
+}
+
+func TestWorkerScope(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "lib", "vendor")
+	os.MkdirAll(sub, 0755)
+	ioutil.WriteFile(filepath.Join(dir, "rules"), []byte("*.top\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "lib", "rules"), []byte("*.lib\n"), 0644)
+	ioutil.WriteFile(filepath.Join(sub, "rules"), []byte("*.vendor\nx/*\n"), 0644)
+
+	m := New("rules")
+	m.Add("*.global")
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	s, err := w.Scope(filepath.Join("lib", "vendor"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if s.Dir() != sub {
+		fw.Errorf("s.Dir() = %q, expected %q", s.Dir(), sub)
+	}
+	tests := map[string][2]bool{
+		"a.global": {true, true},
+		"a.top":    {true, true},
+		"a.lib":    {false, true},
+		"a.vendor": {false, true},
+		"x/a":      {false, true},
+	}
+	for k, v := range tests {
+		if m := w.Matches(k); m != v[0] {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, m, v[0])
+		}
+		if m := s.Matches(k); m != v[1] {
+			fw.Errorf("s.Matches(%q) = %v, expected %v", k, m, v[1])
+		}
+	}
+	if n := len(s.ConfigFiles()); n != 3 {
+		fw.Errorf("s.ConfigFiles() = %q, expected 3 files", s.ConfigFiles())
+	}
+	if n := len(w.ConfigFiles()); n != 1 {
+		fw.Errorf("w.ConfigFiles() = %q after Scope, expected 1 file", w.ConfigFiles())
+	}
+
+	for _, d := range []string{"..", dir + "x", filepath.Dir(dir)} {
+		if _, err := w.Scope(d); err != ErrNotSubdir {
+			fw.Errorf("w.Scope(%q) = %v, expected %v", d, err, ErrNotSubdir)
+		}
+	}
+}
+
+func TestDiscoverDepth(fw *testing.T) {
+	defer noUserFiles(fw)()
+
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"match.conf":           "*.o\nignored/\n",
+		"a/match.conf":         "*.tmp\n!keep.o\n",
+		"a/b/match.conf":       "!x.tmp\n",
+		"a/b/c/match.conf":     "*.c\n",
+		"ignored/match.conf":   "*.md\n",
+		"proj/.git/HEAD":       "",
+		"proj/match.conf":      "*.go\n",
+		"other/sub/match.conf": "out/\n",
+	}
+	for k, v := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(k)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0644)
+	}
+
+	m := New("match.conf")
+	m.Root = dir
+	m.DiscoverDepth = 2
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	for _, r := range w.Rules() {
+		if r.Glob == "ignored" && w.Hits(r) != 0 {
+			fw.Errorf("scanning for configuration files recorded %d hits of %v", w.Hits(r), r)
+		}
+	}
+	tests := map[string]bool{
+		"x.tmp":          false,
+		"a/x.tmp":        true,
+		"a/d/y.tmp":      true,
+		"a/b/x.tmp":      false,
+		"a/b/y.tmp":      true,
+		"keep.o":         true,
+		"a/keep.o":       false,
+		"a/b/c/z.c":      false,
+		"proj/main.go":   false,
+		"other/sub/out/": true,
+		"other/out/":     false,
+		"ignored/":       true,
+	}
+	for k, v := range tests {
+		if r := w.Matches(k); r != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+	if c := w.ConfigFiles(); len(c) == 0 || c[len(c)-1] != filepath.Join(dir, "match.conf") {
+		fw.Errorf("w.ConfigFiles() = %q, expected the discovered files first", c)
+	}
+	for _, f := range w.ConfigFiles() {
+		if rel, _ := filepath.Rel(dir, f); rel == filepath.Join("a", "b", "c", "match.conf") || rel == filepath.Join("ignored", "match.conf") || rel == filepath.Join("proj", "match.conf") {
+			fw.Errorf("w read %s, which it should not discover", rel)
+		}
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "a", "match.conf"), []byte("*.bak\n"), 0644)
+	if err := w.Reload(); err != nil {
+		fw.Fatal(err)
+	}
+	if !w.Matches("a/x.bak") || w.Matches("x.bak") || w.Matches("a/x.tmp") {
+		fw.Errorf("w.Reload() did not keep the rules of a/match.conf beneath a")
+	}
+
+	s, err := w.Scope("a")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	n := 0
+	for _, r := range s.Rules() {
+		if filepath.Dir(r.Source) == filepath.Join(dir, "a") {
+			n++
+		}
+	}
+	if n != 1 {
+		fw.Errorf("w.Scope(%q) has %d rules of a/match.conf, expected 1", "a", n)
+	}
+}
+
+func TestConfigNames(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		".gitignore":     "*.log\nsize:big\n",
+		".ignore":        "!keep.log\n",
+		".ignore.local":  "*.tmp\n",
+		"sub/.gitignore": "!a.log\n",
+		"sub/.ignore":    "a.tmp\n",
+		"sub/match.conf": "*.go\n",
+	}
+	for k, v := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(k)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0644)
+	}
+
+	m := New(".ignore", "", ".gitignore")
+	m.Root = dir
+	w, err := m.NewWorker(filepath.Join(dir, "sub"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	tests := map[string]bool{
+		"../b.log":    true,
+		"../keep.log": false,
+		"a.log":       false,
+		"../size:big": true,
+		"../b.tmp":    true,
+		"a.tmp":       true,
+		"main.go":     false,
+	}
+	for k, v := range tests {
+		if r := w.Matches(k); r != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+	var read []string
+	for _, f := range w.ConfigFiles() {
+		rel, _ := filepath.Rel(dir, f)
+		read = append(read, filepath.ToSlash(rel))
+	}
+	expected := []string{"sub/.ignore", "sub/.gitignore", ".ignore.local", ".ignore", ".gitignore"}
+	if !reflect.DeepEqual(read, expected) {
+		fw.Errorf("w.ConfigFiles() = %q, expected %q", read, expected)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SymlinkPolicy determines how CopyDir and CopyFS copy symbolic links.
+type SymlinkPolicy int
+
+const (
+	// SymlinkCopy creates a link with the same target in the copy.
+	SymlinkCopy SymlinkPolicy = iota
+
+	// SymlinkFollow copies the file that a link points to in place of the
+	// link. Links to directories and broken links are copied as links.
+	SymlinkFollow
+
+	// SymlinkSkip leaves links out of the copy.
+	SymlinkSkip
+)
+
+// CopyDir copies the files and directories beneath src that the Worker w
+// does not match to the directory dst, which is what a deployment tool
+// would ship. Directories that w matches are not descended into. As for
+// ListIncluded, only the rules of w apply, and a relative src is relative
+// to the working directory of w, whereas a relative dst is relative to
+// that of the process.
+//
+// The permission bits of files and directories are preserved, and symbolic
+// links are copied as the Symlinks field of the Matcher of w determines.
+// Other irregular files, such as named pipes, are skipped. Directories are
+// created as needed, but existing files are not overwritten: CopyDir fails
+// with an error satisfying errors.Is(err, fs.ErrExist) instead. If dst is
+// beneath src, it is not copied into itself.
+func CopyDir(dst, src string, w *Worker) error {
+	absDst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(w.abs(src))
+	if err != nil {
+		return err
+	}
+	dirs, err := mkdirRoot(dst, fi.Mode())
+	if err != nil {
+		return err
+	}
+
+	err = w.walk(src, false, func(p, abs string, fi os.FileInfo, excluded bool) error {
+		if abs == absDst {
+			return filepath.SkipDir
+		}
+		if excluded {
+			return nil
+		}
+		rel, err := filepath.Rel(w.abs(src), abs)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		mode := fi.Mode()
+		if mode&os.ModeSymlink != 0 {
+			switch w.m.Symlinks {
+			case SymlinkSkip:
+				return nil
+			case SymlinkFollow:
+				if fi, err := os.Stat(abs); err == nil && fi.Mode().IsRegular() {
+					return copyFile(target, fi.Mode(), func() (io.ReadCloser, error) { return os.Open(abs) })
+				}
+			}
+			link, err := os.Readlink(abs)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		switch {
+		case mode.IsDir():
+			dirs = append(dirs, dirMode{target, mode})
+			if err := os.Mkdir(target, mode.Perm()|0700); err != nil && !os.IsExist(err) {
+				return err
+			}
+		case mode.IsRegular():
+			return copyFile(target, mode, func() (io.ReadCloser, error) { return os.Open(abs) })
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return chmodDirs(dirs)
+}
+
+// CopyFS is like CopyDir, but copies the files beneath the directory src
+// in fsys, for a Worker w created by NewWorkerFS with the same fsys.
+// The directory src is a path in fsys as accepted by fs.ValidPath.
+//
+// Since fsys cannot read symbolic links, they are skipped unless the
+// Symlinks field of the Matcher of w is SymlinkFollow, in which case links
+// to regular files are copied as those files.
+func CopyFS(dst string, fsys fs.FS, src string, w *Worker) error {
+	if !fs.ValidPath(src) {
+		return &os.PathError{Op: "open", Path: src, Err: fs.ErrInvalid}
+	}
+	fi, err := fs.Stat(fsys, src)
+	if err != nil {
+		return err
+	}
+	dirs, err := mkdirRoot(dst, fi.Mode())
+	if err != nil {
+		return err
+	}
+
+	err = fs.WalkDir(fsys, src, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || name == src {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		matched := w.MatchesInfo(fsPath(name), fi)
+		if w.visit != nil {
+			if err := w.visit(name, fi, matched); err != nil {
+				return err
+			}
+		}
+		if matched {
+			if d.IsDir() {
+				w.emit(EventPruned, fsPath(name), Rule{})
+				return fs.SkipDir
+			}
+			return nil
+		}
+		rel := name
+		if src != "." {
+			rel = name[len(src)+1:]
+		}
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		open := func() (io.ReadCloser, error) { return fsys.Open(name) }
+		mode := fi.Mode()
+		if mode&fs.ModeSymlink != 0 {
+			if w.m.Symlinks != SymlinkFollow {
+				return nil
+			}
+			if fi, err := fs.Stat(fsys, name); err == nil && fi.Mode().IsRegular() {
+				return copyFile(target, fi.Mode(), open)
+			}
+			return nil
+		}
+		switch {
+		case mode.IsDir():
+			dirs = append(dirs, dirMode{target, mode})
+			if err := os.Mkdir(target, mode.Perm()|0700); err != nil && !os.IsExist(err) {
+				return err
+			}
+		case mode.IsRegular():
+			return copyFile(target, mode, open)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return chmodDirs(dirs)
+}
+
+// dirMode is a directory created by a copy, and the mode it should end up
+// with. Directories are created writable, so that read-only ones can be
+// filled, and get their modes once all files have been copied.
+type dirMode struct {
+	path string
+	mode fs.FileMode
+}
+
+// mkdirRoot creates the directory dst of a copy, along with its parents,
+// and returns it as the first directory of the copy if it did not exist,
+// so that an existing directory keeps its mode.
+func mkdirRoot(dst string, mode fs.FileMode) ([]dirMode, error) {
+	if _, err := os.Stat(dst); err == nil {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dst, mode.Perm()|0700); err != nil {
+		return nil, err
+	}
+	return []dirMode{{dst, mode}}, nil
+}
+
+// chmodDirs sets the modes of the directories, deepest first.
+func chmodDirs(dirs []dirMode) error {
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode.Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the file opened by open to the new file path,
+// with the permission bits of mode.
+func copyFile(path string, mode fs.FileMode, open func() (io.ReadCloser, error)) error {
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode.Perm())
+}
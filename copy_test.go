@@ -0,0 +1,139 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCopyDir(fw *testing.T) {
+	defer noUserFiles(fw)()
+
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	os.MkdirAll(filepath.Join(src, "build"), 0755)
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "a.o"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(src, "build", "out"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(src, "bin", "run"), []byte("#!/bin/sh\n"), 0755)
+	os.Symlink("main.go", filepath.Join(src, "link.go"))
+
+	type test struct {
+		Policy SymlinkPolicy
+		Link   fs.FileMode
+	}
+	tests := map[string]test{
+		"copy":   {SymlinkCopy, fs.ModeSymlink},
+		"follow": {SymlinkFollow, 0},
+		"skip":   {SymlinkSkip, fs.ModeType},
+	}
+	for k, v := range tests {
+		m := New("match.conf")
+		m.Add("*.o")
+		m.Add("build")
+		m.Symlinks = v.Policy
+		w, err := m.NewWorker(src)
+		if err != nil {
+			fw.Fatal(err)
+		}
+		dst := filepath.Join(dir, k)
+		if err := CopyDir(dst, ".", w); err != nil {
+			fw.Errorf("%s: CopyDir = %v", k, err)
+			continue
+		}
+
+		if data, err := ioutil.ReadFile(filepath.Join(dst, "main.go")); string(data) != "package main\n" {
+			fw.Errorf("%s: main.go = (%q, %v)", k, data, err)
+		}
+		if fi, err := os.Stat(filepath.Join(dst, "bin", "run")); err != nil || fi.Mode().Perm() != 0755 {
+			fw.Errorf("%s: bin/run has mode %v, expected executable (%v)", k, fi.Mode(), err)
+		}
+		for _, name := range []string{"a.o", "build"} {
+			if _, err := os.Lstat(filepath.Join(dst, name)); !os.IsNotExist(err) {
+				fw.Errorf("%s: copied ignored %s", k, name)
+			}
+		}
+		fi, err := os.Lstat(filepath.Join(dst, "link.go"))
+		switch {
+		case v.Link == fs.ModeType:
+			if !os.IsNotExist(err) {
+				fw.Errorf("%s: copied link.go", k)
+			}
+		case err != nil || fi.Mode().Type() != v.Link:
+			fw.Errorf("%s: link.go = (%v, %v), expected type %v", k, fi, err, v.Link)
+		}
+	}
+
+	m := New("match.conf")
+	w, err := m.NewWorker(src)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if err := CopyDir(filepath.Join(dir, "copy"), ".", w); !os.IsExist(err) {
+		fw.Errorf("copying over existing files = %v, expected exist", err)
+	}
+	if err := CopyDir(filepath.Join(src, "self"), ".", w); err != nil {
+		fw.Errorf("copying into a subdirectory = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "self", "self")); !os.IsNotExist(err) {
+		fw.Errorf("CopyDir copied the destination into itself")
+	}
+}
+
+func TestCopyFS(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fsys := fstest.MapFS{
+		"app/match.conf":  {Data: []byte("*.tmp\ncache/\n")},
+		"app/main.go":     {Data: []byte("package main\n"), Mode: 0600},
+		"app/a.tmp":       {},
+		"app/cache/x":     {},
+		"app/web/index":   {Data: []byte("<html>\n")},
+		"app/web/run.cgi": {Mode: 0755},
+	}
+	m := New("match.conf")
+	m.Add("match.conf")
+	w, err := m.NewWorkerFS(fsys, "app")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if err := CopyFS(dir, fsys, "app", w); err != nil {
+		fw.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"main.go":     true,
+		"web/index":   true,
+		"web/run.cgi": true,
+		"a.tmp":       false,
+		"cache":       false,
+		"match.conf":  false,
+	}
+	for k, v := range tests {
+		if _, err := os.Stat(filepath.Join(dir, k)); (err == nil) != v {
+			fw.Errorf("copied %s: %v, expected %v", k, err == nil, v)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "main.go")); err != nil || fi.Mode().Perm() != 0600 {
+		fw.Errorf("main.go = (%v, %v), expected mode 0600", fi, err)
+	}
+	if err := CopyFS(dir, fsys, "/app", w); err == nil {
+		fw.Errorf("CopyFS(dir, fsys, %q) succeeded, expected error", "/app")
+	}
+}
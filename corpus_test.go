@@ -0,0 +1,190 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// corpus is a tree of files and rule files read by loadCorpus, in the format
+// described in testdata/corpus/README.
+type corpus struct {
+	config  string
+	fsys    fstest.MapFS
+	paths   []string
+	matched map[string]bool
+}
+
+// loadCorpus reads the corpus in the file name.
+func loadCorpus(name string) (*corpus, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &corpus{fsys: make(fstest.MapFS), matched: make(map[string]bool)}
+	var section string
+	var rules strings.Builder
+	flush := func() {
+		if section != "" && section != "paths" {
+			c.fsys[section] = &fstest.MapFile{Data: []byte(rules.String())}
+		}
+		rules.Reset()
+	}
+
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		s := sc.Text()
+		switch {
+		case strings.HasPrefix(s, "-- ") && strings.HasSuffix(s, " --"):
+			flush()
+			section = strings.TrimSpace(s[3 : len(s)-3])
+			if section != "paths" && !fs.ValidPath(section) {
+				return nil, fmt.Errorf("%s:%d: invalid path %q", name, line, section)
+			}
+		case section == "paths":
+			if s == "" {
+				continue
+			}
+			if len(s) < 3 || (s[0] != '+' && s[0] != '-') || s[1] != ' ' {
+				return nil, fmt.Errorf("%s:%d: expected + or - and a path", name, line)
+			}
+			p := s[2:]
+			if strings.HasSuffix(p, "/") {
+				p = strings.TrimSuffix(p, "/")
+				c.fsys[p] = &fstest.MapFile{Mode: fs.ModeDir | 0755}
+			} else {
+				c.fsys[p] = &fstest.MapFile{}
+			}
+			c.paths = append(c.paths, p)
+			c.matched[p] = s[0] == '+'
+		case section != "":
+			rules.WriteString(s)
+			rules.WriteByte('\n')
+		case strings.HasPrefix(s, "config "):
+			c.config = strings.TrimSpace(s[len("config "):])
+		case s != "" && !strings.HasPrefix(s, "#"):
+			return nil, fmt.Errorf("%s:%d: expected config or a section", name, line)
+		}
+	}
+	flush()
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if c.config == "" {
+		return nil, fmt.Errorf("%s: missing config line", name)
+	}
+	return c, nil
+}
+
+// workers returns a Worker of m for the directory of every path in the
+// corpus, keyed by the directory.
+func (c *corpus) workers(m *Matcher) (map[string]*Worker, error) {
+	ws := make(map[string]*Worker)
+	for _, p := range c.paths {
+		dir := path.Dir(p)
+		if ws[dir] != nil {
+			continue
+		}
+		w, err := m.NewWorkerFS(c.fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		ws[dir] = w
+	}
+	return ws, nil
+}
+
+// replay calls fn with every path of the corpus and whether the Worker
+// for its directory matches it.
+func (c *corpus) replay(ws map[string]*Worker, fn func(p string, matched bool)) error {
+	for _, p := range c.paths {
+		fi, err := fs.Stat(c.fsys, p)
+		if err != nil {
+			return err
+		}
+		fn(p, ws[path.Dir(p)].MatchesInfo("/"+p, fi))
+	}
+	return nil
+}
+
+// corpora returns the names of the corpus files in testdata/corpus.
+func corpora(tb testing.TB) []string {
+	names, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.txt"))
+	if err != nil || len(names) == 0 {
+		tb.Fatalf("no corpora found: %v", err)
+	}
+	return names
+}
+
+// corpusMatcher returns a Matcher for c that does not read the files of
+// the user or system, so that results are reproducible.
+func corpusMatcher(c *corpus) *Matcher {
+	m := New(c.config)
+	m.SetScope(ScopeUser, false)
+	m.SetScope(ScopeSystem, false)
+	m.Sentinels = []string{}
+	return m
+}
+
+func TestCorpus(fw *testing.T) {
+	for _, name := range corpora(fw) {
+		c, err := loadCorpus(name)
+		if err != nil {
+			fw.Fatal(err)
+		}
+		ws, err := c.workers(corpusMatcher(c))
+		if err != nil {
+			fw.Fatalf("%s: %v", name, err)
+		}
+		err = c.replay(ws, func(p string, matched bool) {
+			if matched != c.matched[p] {
+				fw.Errorf("%s: %s matched = %v, expected %v", name, p, matched, c.matched[p])
+			}
+		})
+		if err != nil {
+			fw.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func BenchmarkCorpus(b *testing.B) {
+	for _, name := range corpora(b) {
+		c, err := loadCorpus(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		m := corpusMatcher(c)
+		b.Run(strings.TrimSuffix(filepath.Base(name), ".txt"), func(b *testing.B) {
+			b.Run("load", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := c.workers(m); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			b.Run("match", func(b *testing.B) {
+				ws, err := c.workers(m)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					c.replay(ws, func(string, bool) {})
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(c.paths)), "ns/path")
+			})
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultRule is the Rule of a FileCoverage for a file that no rule
+// decided, so that it is not matched by default.
+const DefaultRule = "(default)"
+
+// Coverage is the decision of a Worker for every file in a tree, as returned
+// by Worker.Coverage.
+type Coverage struct {
+	Files []FileCoverage
+}
+
+// FileCoverage is what decided whether a file is matched.
+type FileCoverage struct {
+	// Path is the name of the file, formed as by Glob.
+	Path string
+
+	// Excluded is whether the file is matched, either itself or because
+	// a directory containing it is.
+	Excluded bool
+
+	// Rule is the rule that decided, as it would be written in a rule file,
+	// as in MatchResult, or DefaultRule. For a file within a matched
+	// directory, it is the rule that matched the directory. If a layer added
+	// with AddMatcher decided, it is the type of the layer in parentheses.
+	Rule string
+
+	// Source and Line are the file and line the rule was read from.
+	Source string
+	Line   int
+}
+
+// Coverage walks root and returns which rule decided whether each file
+// beneath it is matched, including files within matched directories, in
+// lexical order. Comparing the coverage of a tree before and after changing
+// rule files, such as with Coverage.Diff, shows whether the change had any
+// effect on it. Directories are not included. A relative root is relative
+// to the working directory of the Worker.
+//
+// The decisions are not counted or traced, and the cache is not used.
+func (w *Worker) Coverage(root string) (*Coverage, error) {
+	c := w.quiet()
+	c.cache = nil
+
+	cov := &Coverage{}
+	var pruned string
+	var parent FileCoverage
+	sep := string(filepath.Separator)
+	err := c.walk(root, true, func(path, abs string, fi os.FileInfo, excluded bool) error {
+		if pruned != "" && !strings.HasPrefix(abs, pruned+sep) {
+			pruned = ""
+		}
+		f := parent
+		if pruned == "" {
+			f = c.fileCoverage(abs, excluded)
+			if excluded && fi.IsDir() {
+				pruned, parent = abs, f
+			}
+		}
+		if !fi.IsDir() {
+			f.Path = path
+			cov.Files = append(cov.Files, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cov, nil
+}
+
+// fileCoverage returns what decided whether the file abs is matched.
+func (w *Worker) fileCoverage(abs string, excluded bool) FileCoverage {
+	f := FileCoverage{Excluded: excluded, Rule: DefaultRule}
+	res, _ := w.Match(abs)
+	switch {
+	case res.Layer != nil:
+		f.Rule = fmt.Sprintf("(%T)", res.Layer)
+	case res.Pattern != "":
+		f.Rule, f.Source, f.Line = res.Pattern, res.Source, res.Line
+	}
+	return f
+}
+
+// Diff returns the names of the files whose decision differs between c and
+// other, which should be the coverage of the same tree, such as with an old
+// and a new version of a rule file. Files only in one of them are included.
+// It does not matter which rule decided, so rewriting rules is safe if Diff
+// returns nothing.
+func (c *Coverage) Diff(other *Coverage) []string {
+	excluded := make(map[string]bool, len(c.Files))
+	for _, f := range c.Files {
+		excluded[f.Path] = f.Excluded
+	}
+	var diff []string
+	for _, f := range other.Files {
+		x, ok := excluded[f.Path]
+		if !ok || x != f.Excluded {
+			diff = append(diff, f.Path)
+		}
+		delete(excluded, f.Path)
+	}
+	for _, f := range c.Files {
+		if _, ok := excluded[f.Path]; ok {
+			diff = append(diff, f.Path)
+		}
+	}
+	return diff
+}
+
+// WriteTo writes a line for every file to out, with the name of the file,
+// "excluded" or "included", and the rule that decided, separated by tabs.
+// If the rule was read from a file, its source and line follow.
+func (c *Coverage) WriteTo(out io.Writer) (int64, error) {
+	bw := bufio.NewWriter(out)
+	var n int64
+	for _, f := range c.Files {
+		state := "included"
+		if f.Excluded {
+			state = "excluded"
+		}
+		k, _ := fmt.Fprintf(bw, "%s\t%s\t%s", f.Path, state, f.Rule)
+		n += int64(k)
+		if f.Source != "" {
+			k, _ = fmt.Fprintf(bw, "\t%s:%d", f.Source, f.Line)
+			n += int64(k)
+		}
+		k, _ = fmt.Fprintln(bw)
+		n += int64(k)
+	}
+	return n, bw.Flush()
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCoverage(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, k := range []string{"main.go", "main.o", "keep.o", "build/a", "build/b/c"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(k)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, k), nil, 0644)
+	}
+	conf := filepath.Join(dir, "match.conf")
+	ioutil.WriteFile(conf, []byte("*.o\n!keep.o\nbuild/\n"), 0644)
+
+	m := New("match.conf")
+	m.Root = dir
+	coverage := func() *Coverage {
+		w, err := m.NewWorker(dir)
+		if err != nil {
+			fw.Fatal(err)
+		}
+		c, err := w.Coverage(".")
+		if err != nil {
+			fw.Fatal(err)
+		}
+		return c
+	}
+
+	old := coverage()
+	expected := []FileCoverage{
+		{Path: "build/a", Excluded: true, Rule: "build/", Source: conf, Line: 3},
+		{Path: "build/b/c", Excluded: true, Rule: "build/", Source: conf, Line: 3},
+		{Path: "keep.o", Rule: "!keep.o", Source: conf, Line: 2},
+		{Path: "main.go", Rule: DefaultRule},
+		{Path: "main.o", Excluded: true, Rule: "*.o", Source: conf, Line: 1},
+		{Path: "match.conf", Rule: DefaultRule},
+	}
+	if !reflect.DeepEqual(old.Files, expected) {
+		fw.Errorf("w.Coverage() = %+v, expected %+v", old.Files, expected)
+	}
+
+	// Rewriting the rules without changing their effect.
+	ioutil.WriteFile(conf, []byte("main.o\n/build\n"), 0644)
+	if diff := old.Diff(coverage()); len(diff) != 0 {
+		fw.Errorf("equivalent rules differ in %q", diff)
+	}
+	ioutil.WriteFile(conf, []byte("*.o\n"), 0644)
+	if diff := old.Diff(coverage()); !reflect.DeepEqual(diff, []string{"build/a", "build/b/c", "keep.o"}) {
+		fw.Errorf("old.Diff() = %q", diff)
+	}
+
+	var buf bytes.Buffer
+	old.WriteTo(&buf)
+	line := "main.o\texcluded\t*.o\t" + conf + ":1\n"
+	if !bytes.Contains(buf.Bytes(), []byte(line)) || !bytes.Contains(buf.Bytes(), []byte("main.go\tincluded\t(default)\n")) {
+		fw.Errorf("old.WriteTo() wrote:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import "fmt"
+
+// Decision is a rule or layer that matches a path, as returned by
+// Worker.Decisions, or that is considered for it, as returned by
+// Worker.Explain. It is encoded to JSON with the field names given in the
+// tags, so that editors and web interfaces can show why a path is ignored
+// without parsing traces.
+type Decision struct {
+	// Pattern is the rule as it would be written in a rule file, as in
+	// MatchResult. It is empty for a layer added with AddMatcher.
+	Pattern string `json:"pattern"`
+
+	// Source and Line are the file and line the rule was read from.
+	// Source is empty for globs added with Add.
+	Source string `json:"source,omitempty"`
+	Line   int    `json:"line,omitempty"`
+
+	// Layer is the name of the scope of the rule, such as "project",
+	// or the type of the layer added with AddMatcher.
+	Layer string `json:"layer"`
+
+	// Negated is whether the rule is negated, so that it keeps the path
+	// from being matched.
+	Negated bool `json:"negated"`
+
+	// Matched is whether the rule or layer matches the path. It is always
+	// true for Decisions, but Explain also lists those that do not.
+	Matched bool `json:"matched"`
+
+	// Skipped is why a rule whose glob matches the path does not match it:
+	// "disabled" if it is turned off, "expired" if its expires predicate
+	// has passed, "directory" if it only matches directories, and
+	// "condition" if its other predicates do not hold.
+	Skipped string `json:"skipped,omitempty"`
+
+	// Final is whether the rule decided whether the path is matched,
+	// which is only true for the first Decision that matches.
+	Final bool `json:"final"`
+}
+
+// Decisions returns every rule and layer of the Worker that matches path,
+// in the order of precedence in which the Worker consults them. The first,
+// if there is any, is Final and decides whether the path is matched; the
+// others would decide in turn if the ones before them were removed. Rules
+// that are turned off are left out.
+//
+// Unlike Matches, Decisions neither consults the cache, nor counts, traces,
+// or emits events.
+func (w *Worker) Decisions(path string) []Decision {
+	return w.decisions(path, false)
+}
+
+// Explain is like Decisions, but returns every rule and layer that the Worker
+// considers for path, whether it matches or not, like a verbose form of
+// "git check-ignore -v". The rules whose globs match the path, but which are
+// turned off or whose conditions do not hold, say why in Skipped. The path is
+// matched if the Final decision is not Negated; if no decision is Final,
+// nothing matched it, and it is only matched by Matcher.DenyByDefault.
+func (w *Worker) Explain(path string) []Decision {
+	return w.decisions(path, true)
+}
+
+func (w *Worker) decisions(path string, all bool) []Decision {
+	f := w.file(path, nil)
+	if f == nil {
+		return nil
+	}
+	path = f.path
+
+	var ds []Decision
+	final := false
+	add := func(d Decision) {
+		if d.Matched && !final {
+			d.Final, final = true, true
+		}
+		if all || d.Matched {
+			ds = append(ds, d)
+		}
+	}
+	layers := func(ls []Layer) {
+		for _, l := range ls {
+			add(Decision{Layer: fmt.Sprintf("%T", l), Matched: l.Matches(path)})
+		}
+	}
+	rules := func(rs []Rule) {
+		precedence(rs, func(r Rule) bool {
+			d := Decision{
+				Pattern: r.line(),
+				Source:  r.Source,
+				Line:    r.Line,
+				Layer:   r.Scope.String(),
+				Negated: r.Negate,
+			}
+			if w.m.matchRule(r, path) {
+				d.Skipped = w.skipped(r, f)
+				d.Matched = d.Skipped == ""
+			}
+			add(d)
+			return false
+		})
+	}
+
+	layers(w.above)
+	if !w.disabled[ScopeSession] {
+		rules(w.global)
+	}
+	rules(w.local)
+	layers(w.below)
+	return ds
+}
+
+// skipped returns why r does not match f although its glob does,
+// as described for Decision, or "" if it matches.
+func (w *Worker) skipped(r Rule, f *file) string {
+	switch {
+	case !w.enabled(r):
+		return "disabled"
+	case r.expired():
+		return "expired"
+	case r.test(f):
+		return ""
+	case r.DirOnly && !(Rule{DirOnly: true}).test(f):
+		return "directory"
+	default:
+		return "condition"
+	}
+}
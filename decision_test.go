@@ -0,0 +1,92 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecisions(fw *testing.T) {
+	m := New("")
+	m.Add("*.o")
+	w, err := m.NewWorker("tests")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.AddString("*.o\n!keep.o\n")
+	w.AddReader(strings.NewReader("*\n"), "all")
+
+	tests := map[string][]Decision{
+		"a.o": {
+			{Pattern: "*.o", Layer: "session", Matched: true, Final: true},
+			{Pattern: "*.o", Source: "(string)", Line: 1, Layer: "session", Matched: true},
+			{Pattern: "*", Source: "all", Line: 1, Layer: "session", Matched: true},
+		},
+		"keep.o": {
+			{Pattern: "*.o", Layer: "session", Matched: true, Final: true},
+			{Pattern: "!keep.o", Source: "(string)", Line: 2, Layer: "session", Negated: true, Matched: true},
+			{Pattern: "*.o", Source: "(string)", Line: 1, Layer: "session", Matched: true},
+			{Pattern: "*", Source: "all", Line: 1, Layer: "session", Matched: true},
+		},
+	}
+	for k, v := range tests {
+		if ds := w.Decisions(k); !reflect.DeepEqual(ds, v) {
+			fw.Errorf("w.Decisions(%q) = %+v, expected %+v", k, ds, v)
+		}
+	}
+
+	w.SetScopeEnabled(ScopeSession, false)
+	ds := w.Decisions("keep.o")
+	if len(ds) != 3 || !ds[0].Final || !ds[0].Negated || w.Matches("keep.o") {
+		fw.Errorf("w.Decisions(%q) = %+v, expected the negation to decide", "keep.o", ds)
+	}
+
+	b, err := json.Marshal(ds[0])
+	if err != nil {
+		fw.Fatal(err)
+	}
+	expected := `{"pattern":"!keep.o","source":"(string)","line":2,"layer":"session","negated":true,"matched":true,"final":true}`
+	if string(b) != expected {
+		fw.Errorf("json.Marshal() = %s, expected %s", b, expected)
+	}
+	if ds := w.Decisions("x.c"); len(ds) != 1 || ds[0].Pattern != "*" {
+		fw.Errorf("w.Decisions(%q) = %+v", "x.c", ds)
+	}
+}
+
+func TestExplain(fw *testing.T) {
+	w, err := New("").NewWorker("tests")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.AddString("*.o\nbar/\n!keep.o\n*.c\n")
+	w.AddReader(strings.NewReader("*.o\n"), "off")
+	w.SetFileEnabled("off", false)
+
+	expected := []Decision{
+		{Pattern: "*.c", Source: "(string)", Line: 4, Layer: "session"},
+		{Pattern: "!keep.o", Source: "(string)", Line: 3, Layer: "session", Negated: true},
+		{Pattern: "bar/", Source: "(string)", Line: 2, Layer: "session"},
+		{Pattern: "*.o", Source: "(string)", Line: 1, Layer: "session", Matched: true, Final: true},
+		{Pattern: "*.o", Source: "off", Line: 1, Layer: "session", Skipped: "disabled"},
+	}
+	if ds := w.Explain("a.o"); !reflect.DeepEqual(ds, expected) {
+		fw.Errorf("w.Explain(%q) = %+v, expected %+v", "a.o", ds, expected)
+	}
+
+	// tests/bar is a file, so the rule for directories is skipped.
+	ds := w.Explain("bar")
+	if len(ds) != 5 || ds[2].Skipped != "directory" || ds[2].Matched {
+		fw.Errorf("w.Explain(%q) = %+v, expected bar/ to be skipped", "bar", ds)
+	}
+	for _, d := range ds {
+		if d.Final {
+			fw.Errorf("w.Explain(%q) has final decision %+v, expected none", "bar", d)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// The default Worker used by the package-level functions. Unless SetDefault
+// is called, it is created on first use from New("") in the current working
+// directory, so it matches nothing until patterns are added with AddPattern.
+var (
+	defaultMu     sync.Mutex
+	defaultWorker *Worker
+)
+
+// SetDefault makes the package-level functions Matches and AddPattern use
+// a Worker created by m in the current working directory, replacing any
+// patterns added with AddPattern before. This is meant for small programs that
+// do not want to pass a Worker around; larger ones should use Workers.
+//
+// The error is that of os.Getwd or NewWorker. If it is not nil,
+// the default is not changed.
+func SetDefault(m *Matcher) error {
+	w, err := newDefault(m)
+	if err != nil {
+		return err
+	}
+	defaultMu.Lock()
+	defaultWorker = w
+	defaultMu.Unlock()
+	return nil
+}
+
+func newDefault(m *Matcher) (*Worker, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return m.NewWorker(dir)
+}
+
+// withDefault calls fn with the default Worker, creating it if necessary.
+func withDefault(fn func(w *Worker) error) error {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultWorker == nil {
+		w, err := newDefault(New(""))
+		if err != nil {
+			return err
+		}
+		defaultWorker = w
+	}
+	return fn(defaultWorker)
+}
+
+// Matches reports whether path is matched by the default Worker, see
+// SetDefault. A relative path is relative to the working directory at the
+// time the default was set. It is safe to call concurrently.
+func Matches(path string) bool {
+	var ok bool
+	withDefault(func(w *Worker) error {
+		ok = w.Matches(path)
+		return nil
+	})
+	return ok
+}
+
+// AddPattern adds pattern to the default Worker, see SetDefault. The pattern
+// is read like a line of a rule file in the working directory at the time
+// the default was set, so it may contain a path separator and predicates.
+// It is safe to call concurrently.
+//
+// The default Worker is shared by the whole program, so the pattern changes
+// what Matches returns for every package that uses it, until SetDefault is
+// called again. Libraries should not call AddPattern.
+//
+// The only possible error for an invalid pattern is BadPatternError.
+func AddPattern(pattern string) error {
+	return withDefault(func(w *Worker) error {
+		rules, err := w.line(newParser(DialectNative), pattern, w.cwd)
+		if err != nil {
+			return err
+		}
+		w.insert(rules...)
+		return nil
+	})
+}
+
+// The Matchers of IsIgnored by the name of their configuration files.
+var (
+	ignoredMu       sync.Mutex
+	ignoredMatchers = make(map[string]*Matcher)
+)
+
+// IsIgnored reports whether path is matched by the configuration files
+// called config in its directory and the directories above it, as well as
+// the rule files of the user and system scopes, like a Worker for that
+// directory from New(config) would decide. This is meant for one-off checks
+// in scripts and small tools, such as setting an exit status.
+//
+// The Workers are kept in the pool of WorkerFor, so checking many paths in
+// the same directories does not read their configuration files again, as
+// long as they do not change. It is safe to call concurrently.
+func IsIgnored(config, path string) (bool, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	ignoredMu.Lock()
+	m, ok := ignoredMatchers[config]
+	if !ok {
+		m = New(config)
+		ignoredMatchers[config] = m
+	}
+	ignoredMu.Unlock()
+
+	w, err := m.WorkerFor(filepath.Dir(path))
+	if err != nil {
+		return false, err
+	}
+	return w.Matches(path), nil
+}
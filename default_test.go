@@ -0,0 +1,63 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDefault(fw *testing.T) {
+	defer func() { defaultWorker = nil }()
+	defaultWorker = nil
+
+	if Matches("main.o") {
+		fw.Errorf("Matches(main.o) = true before any patterns were added")
+	}
+	if err := AddPattern("*.o"); err != nil {
+		fw.Fatal(err)
+	}
+	if err := AddPattern("build/*"); err != nil {
+		fw.Fatal(err)
+	}
+	for _, p := range []string{"main.o", "build/out"} {
+		if !Matches(p) {
+			fw.Errorf("Matches(%q) = false after AddPattern", p)
+		}
+	}
+	if err := AddPattern("a["); err == nil {
+		fw.Errorf("AddPattern with invalid pattern succeeded")
+	}
+
+	m := New("")
+	m.Add("*.tmp")
+	if err := SetDefault(m); err != nil {
+		fw.Fatal(err)
+	}
+	if !Matches("a.tmp") || Matches("main.o") {
+		fw.Errorf("SetDefault did not replace the default worker")
+	}
+}
+
+func TestIsIgnored(fw *testing.T) {
+	tests := map[string]bool{
+		"tests/foo":            true,
+		"tests/jack":           false,
+		"tests/brain/foo":      true,
+		"tests/dead/ugly/bar":  true,
+		"tests/dead/good/3":    true,
+		"tests/dead/good/hit":  false,
+		"tests/dead/good/yala": false,
+	}
+	for k, v := range tests {
+		ok, err := IsIgnored("match.conf", filepath.FromSlash(k))
+		if err != nil || ok != v {
+			fw.Errorf("IsIgnored(%q) = (%v, %v), expected %v", k, ok, err, v)
+		}
+	}
+	if ok, err := IsIgnored("nothing.conf", "tests/foo"); ok || err != nil {
+		fw.Errorf("IsIgnored(%q) with another config = (%v, %v), expected false", "tests/foo", ok, err)
+	}
+}
@@ -0,0 +1,425 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Dialect is the syntax of a rule file.
+//
+// Dialects may be added in later versions, so a switch on a Dialect should
+// have a default case. A value that is not one of the constants is unknown:
+// its String is "unknown", it has no Capabilities, and Workers fail to read
+// rule files in it with ErrUnknownDialect.
+type Dialect int
+
+const (
+	// DialectAuto chooses the dialect of each file by its name,
+	// as DialectOf does.
+	DialectAuto Dialect = iota
+
+	// DialectNative is the syntax described in the package documentation,
+	// i.e. that of gitignore with predicates, macros, and variables.
+	DialectNative
+
+	// DialectGitignore is the syntax of .gitignore files, which .npmignore
+	// files share. It lacks the predicates, macros, and variables of
+	// DialectNative, so lines that look like them are plain patterns.
+	DialectGitignore
+
+	// DialectDocker is the syntax of .dockerignore files. Every pattern is
+	// relative to the directory of the file, even without a slash, so
+	// "*.md" only matches in that directory. Leading and trailing
+	// whitespace and slashes are ignored.
+	DialectDocker
+
+	// DialectHg is the syntax of .hgignore files of Mercurial. Lines of the
+	// form "syntax: glob" or "syntax: regexp" switch the syntax of the
+	// lines that follow, and a pattern can be prefixed by "glob:", "re:",
+	// or "rootglob:" to choose the syntax of itself. Regular expressions
+	// are the default. They are matched anywhere in the path relative
+	// to the directory of the file, unless they start with "^". Globs
+	// match at any depth, and rootglobs only relative to the directory.
+	// There is no negation.
+	DialectHg
+
+	// DialectRsync is the syntax of the filter files of rsync, such as
+	// .rsync-filter files and those given to --exclude-from. A line is
+	// a pattern to exclude, optionally prefixed by "- " or "exclude ",
+	// or a pattern to include if it is prefixed by "+ " or "include ".
+	// Unlike in other dialects, the first matching line of a file decides.
+	// A pattern starting with a slash is relative to the directory of the
+	// file, and other patterns match at any depth, even if they contain
+	// a slash. A pattern ending in "/***" matches a directory and all of its
+	// contents. Lines starting with ";" or "#" are comments. Other rules
+	// of rsync, such as merge rules, fail with ErrUnknownSyntax.
+	DialectRsync
+)
+
+// ErrUnknownDialect is returned by ParseDialect for a name that is not one
+// of a dialect, and for reading rule files in an unknown dialect.
+var ErrUnknownDialect = errors.New("unknown dialect")
+
+var dialectNames = [...]string{
+	DialectAuto:      "auto",
+	DialectNative:    "native",
+	DialectGitignore: "gitignore",
+	DialectDocker:    "docker",
+	DialectHg:        "hg",
+	DialectRsync:     "rsync",
+}
+
+func (d Dialect) String() string {
+	if !d.valid() {
+		return "unknown"
+	}
+	return dialectNames[d]
+}
+
+// valid returns whether d is one of the constants.
+func (d Dialect) valid() bool {
+	return d >= 0 && int(d) < len(dialectNames)
+}
+
+// ParseDialect returns the dialect whose String is name, ignoring case,
+// so that applications can let users choose a dialect in flags or their
+// own configuration files. For any other name it returns ErrUnknownDialect.
+func ParseDialect(name string) (Dialect, error) {
+	for d, n := range dialectNames {
+		if strings.EqualFold(n, name) {
+			return Dialect(d), nil
+		}
+	}
+	return DialectAuto, ErrUnknownDialect
+}
+
+// MarshalText implements encoding.TextMarshaler. It fails with
+// ErrUnknownDialect if d is unknown.
+func (d Dialect) MarshalText() ([]byte, error) {
+	if !d.valid() {
+		return nil, ErrUnknownDialect
+	}
+	return []byte(dialectNames[d]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler as ParseDialect does.
+func (d *Dialect) UnmarshalText(text []byte) error {
+	v, err := ParseDialect(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Capabilities describes which features of patterns a dialect supports,
+// so that tools that convert, check, or edit rule files can adapt to it.
+type Capabilities struct {
+	// Globstar is whether "**" matches any number of directories.
+	Globstar bool
+
+	// Negation is whether a leading "!" negates a pattern.
+	Negation bool
+
+	// Regexp is whether patterns can be regular expressions.
+	Regexp bool
+
+	// DirOnly is whether a trailing slash restricts a pattern
+	// to directories.
+	DirOnly bool
+
+	// Unanchored is whether a pattern without a slash matches at any depth,
+	// rather than only in the directory of its file.
+	Unanchored bool
+
+	// Predicates, Macros, and Variables are whether the extensions of the
+	// native syntax are supported.
+	Predicates bool
+	Macros     bool
+	Variables  bool
+
+	// Braces is whether brace expressions such as "*.{jpg,png}" expand
+	// to several patterns.
+	Braces bool
+}
+
+var dialectCapabilities = [...]Capabilities{
+	DialectNative: {
+		Globstar:   true,
+		Negation:   true,
+		DirOnly:    true,
+		Unanchored: true,
+		Predicates: true,
+		Macros:     true,
+		Variables:  true,
+		Braces:     true,
+	},
+	DialectGitignore: {
+		Globstar:   true,
+		Negation:   true,
+		DirOnly:    true,
+		Unanchored: true,
+	},
+	DialectDocker: {
+		Globstar: true,
+		Negation: true,
+	},
+	DialectHg: {
+		Globstar:   true,
+		Regexp:     true,
+		DirOnly:    true,
+		Unanchored: true,
+	},
+	DialectRsync: {
+		Globstar:   true,
+		Negation:   true,
+		DirOnly:    true,
+		Unanchored: true,
+	},
+}
+
+// Capabilities returns the features that d supports. DialectAuto and
+// unknown dialects support none; see Matcher.Capabilities for the dialect
+// chosen for a file.
+func (d Dialect) Capabilities() Capabilities {
+	if !d.valid() {
+		return Capabilities{}
+	}
+	return dialectCapabilities[d]
+}
+
+// Capabilities returns the features supported in the rule file name,
+// which is read in the dialect of Matcher.Dialect or DialectOf.
+func (m *Matcher) Capabilities(name string) Capabilities {
+	return m.dialect(name).Capabilities()
+}
+
+// ErrUnknownSyntax is returned for a syntax line in a .hgignore file
+// that names an unknown syntax, and for a rule in an rsync filter file
+// that is not supported.
+var ErrUnknownSyntax = errors.New("unknown syntax")
+
+// ErrBadRegexp is returned for an invalid regular expression
+// in a .hgignore file.
+var ErrBadRegexp = errors.New("invalid regular expression")
+
+// DialectOf returns the dialect of a rule file by its name, ignoring
+// the directory, a ".gz" suffix, and LocalSuffix: DialectGitignore for
+// .gitignore and .npmignore, DialectDocker for .dockerignore and files
+// ending in it, such as Dockerfile.dockerignore, DialectHg for .hgignore,
+// DialectRsync for .rsync-filter, and DialectNative for all other names.
+func DialectOf(name string) Dialect {
+	name = strings.TrimSuffix(filepath.Base(name), ".gz")
+	name = strings.TrimSuffix(name, LocalSuffix)
+	switch {
+	case name == ".gitignore", name == ".npmignore":
+		return DialectGitignore
+	case strings.HasSuffix(name, ".dockerignore"):
+		return DialectDocker
+	case name == ".hgignore":
+		return DialectHg
+	case name == ".rsync-filter":
+		return DialectRsync
+	default:
+		return DialectNative
+	}
+}
+
+// dialect returns the dialect of the rule file name for the Matcher.
+func (m *Matcher) dialect(name string) Dialect {
+	if m.Dialect != DialectAuto {
+		return m.Dialect
+	}
+	return DialectOf(name)
+}
+
+// parseGitignore parses a cleaned line of a .gitignore file.
+func parseGitignore(s string) (Rule, error) {
+	var r Rule
+	var column int
+	if strings.HasPrefix(s, "!") {
+		r.Negate = true
+		s = s[1:]
+		column++
+	}
+	return r, r.setGlob(s, column)
+}
+
+// parseDocker parses a cleaned line of a .dockerignore file. The glob
+// starts with a slash, so that it is anchored. A line that only consists
+// of slashes yields no rule.
+func parseDocker(s string) ([]Rule, error) {
+	var r Rule
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "!") {
+		r.Negate = true
+		s = strings.TrimSpace(s[1:])
+	}
+	if s = path.Clean("/" + s); s == "/" {
+		return nil, nil
+	}
+	if err := checkPattern(s); err != nil {
+		err.(*BadPatternError).Column = 0
+		return nil, err
+	}
+	r.Glob = s
+	return []Rule{r}, nil
+}
+
+// rsyncRules are the names of the rules of rsync filter files that
+// are not supported, both short and long.
+var rsyncRules = map[string]bool{
+	"P": true, "protect": true,
+	"R": true, "risk": true,
+	"H": true, "hide": true,
+	"S": true, "show": true,
+	".": true, "merge": true,
+	":": true, "dir-merge": true,
+	"!": true, "clear": true,
+}
+
+// parseRsync parses a cleaned line of an rsync filter file. Patterns that
+// match at any depth and contain a slash get a leading "**/", and anchored
+// ones keep a single leading slash, so that both are joined to the directory
+// of the file.
+func parseRsync(s string) ([]Rule, error) {
+	if strings.HasPrefix(s, ";") {
+		return nil, nil
+	}
+	var r Rule
+	column := 0
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		switch name := s[:i]; {
+		case name == "-" || name == "exclude":
+			column = i + 1
+		case name == "+" || name == "include":
+			r.Negate = true
+			column = i + 1
+		case rsyncRules[name]:
+			return nil, &BadPatternError{Err: ErrUnknownSyntax, Column: 0, Line: -1}
+		}
+	} else if rsyncRules[s] {
+		return nil, &BadPatternError{Err: ErrUnknownSyntax, Column: 0, Line: -1}
+	}
+	s = s[column:]
+
+	contents := strings.HasSuffix(s, "/***")
+	if contents {
+		s = strings.TrimSuffix(s, "/***")
+	}
+	if err := r.setGlob(s, column); err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasPrefix(r.Glob, "/"):
+		r.Glob = "/" + strings.TrimLeft(r.Glob, "/")
+	case strings.Contains(r.Glob, "/") && !strings.HasPrefix(r.Glob, "**/"):
+		r.Glob = "**/" + r.Glob
+	}
+	if !contents {
+		return []Rule{r}, nil
+	}
+	c := r
+	c.Glob, c.DirOnly = strings.TrimSuffix(r.Glob, "/")+"/**", false
+	return []Rule{r, c}, nil
+}
+
+// hgSyntaxes maps the names of syntaxes in .hgignore files to those
+// that are supported, which are "glob", "rootglob", and "regexp".
+var hgSyntaxes = map[string]string{
+	"glob":     "glob",
+	"relglob":  "glob",
+	"rootglob": "rootglob",
+	"re":       "regexp",
+	"regexp":   "regexp",
+	"relre":    "regexp",
+}
+
+// parseHg parses a cleaned line of a .hgignore file.
+func (p *parser) parseHg(s string) ([]Rule, error) {
+	if strings.HasPrefix(s, "syntax:") {
+		syntax, ok := hgSyntaxes[strings.TrimSpace(s[len("syntax:"):])]
+		if !ok {
+			return nil, &BadPatternError{Err: ErrUnknownSyntax, Column: 0, Line: -1}
+		}
+		p.syntax = syntax
+		return nil, nil
+	}
+
+	syntax := p.syntax
+	if i := strings.IndexByte(s, ':'); i > 0 {
+		if prefixed, ok := hgSyntaxes[s[:i]]; ok {
+			syntax, s = prefixed, s[i+1:]
+		}
+	}
+	var r Rule
+	switch syntax {
+	case "regexp":
+		if !validRegexp(s) {
+			return nil, &BadPatternError{Err: ErrBadRegexp, Column: 0, Line: -1}
+		}
+		r.Glob, r.Regexp = s, true
+		return []Rule{r}, nil
+	case "rootglob":
+		s = "/" + s
+	default:
+		if strings.Contains(strings.TrimRight(s, "/"), "/") {
+			s = "**/" + s
+		}
+	}
+	if strings.HasPrefix(s, "!") {
+		s = "\\" + s
+	}
+	if err := r.setGlob(s, 0); err != nil {
+		err.(*BadPatternError).Column = 0
+		return nil, err
+	}
+	return []Rule{r}, nil
+}
+
+// anchorRegexp returns a regular expression that matches absolute paths
+// where re, which is not rooted unless it starts with "^", matches the path
+// relative to base.
+func anchorRegexp(re, base string) string {
+	prefix := "^" + regexp.QuoteMeta(strings.TrimSuffix(base, "/")+"/")
+	if strings.HasPrefix(re, "^") {
+		return prefix + "(?:" + re[1:] + ")"
+	}
+	return prefix + ".*?(?:" + re + ")"
+}
+
+// validRegexp reports whether re compiles on its own and in the forms in
+// which it is matched: anchored by anchorRegexp, and with case folding.
+// Checking re alone is not enough, since "\Q" quotes the rest of the
+// expression, including the parenthesis that anchorRegexp closes it with.
+func validRegexp(re string) bool {
+	anchored := anchorRegexp(re, "/")
+	for _, s := range []string{re, anchored, "(?i)" + anchored} {
+		if _, err := regexp.Compile(s); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// regexps holds the compiled regular expressions of rules, or nil for
+// those that do not compile.
+var regexps = compileCache{compile: func(s string) interface{} {
+	re, _ := regexp.Compile(s)
+	return re
+}}
+
+// matchRegexp reports whether the regular expression re matches s.
+// An invalid expression matches nothing; it is reported when its rule
+// is parsed.
+func matchRegexp(re, s string) bool {
+	r := regexps.get(re).(*regexp.Regexp)
+	return r != nil && r.MatchString(s)
+}
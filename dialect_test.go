@@ -0,0 +1,221 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDialectOf(fw *testing.T) {
+	tests := map[string]Dialect{
+		".gitignore":                DialectGitignore,
+		"sub/.gitignore":            DialectGitignore,
+		".gitignore.local":          DialectGitignore,
+		".npmignore":                DialectGitignore,
+		".dockerignore":             DialectDocker,
+		"Dockerfile.dockerignore":   DialectDocker,
+		"/src/app/.dockerignore.gz": DialectDocker,
+		".hgignore":                 DialectHg,
+		"sub/.rsync-filter":         DialectRsync,
+		"match.conf":                DialectNative,
+		"gitignore":                 DialectNative,
+	}
+	for k, v := range tests {
+		if d := DialectOf(k); d != v {
+			fw.Errorf("DialectOf(%q) = %d, expected %d", k, d, v)
+		}
+	}
+}
+
+func TestDialects(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "sub", "deep"), 0755)
+
+	type test struct {
+		Name    string
+		Content string
+		Dialect Dialect
+		Matches map[string]bool
+	}
+	tests := []test{
+		{".gitignore", "*.o\n!keep.o\nsize:big\n", DialectAuto, map[string]bool{
+			"sub/a.o": true, "sub/keep.o": false, "sub/size:big": true,
+		}},
+		{".dockerignore", "*.md\n!README.md\n/build/\n\n/\n", DialectAuto, map[string]bool{
+			"sub/notes.md": true, "sub/README.md": false, "sub/deep/notes.md": false,
+			"sub/build": true, "sub/deep/build": false,
+		}},
+		{".hgignore", "\\.orig$\nsyntax: glob\n*.pyc\ndeep/*.tmp\nrootglob:out\nre:^dist/\n", DialectAuto, map[string]bool{
+			"sub/a.orig": true, "sub/deep/b.orig": true, "sub/a.orig.txt": false, "a.orig": false,
+			"sub/deep/c.pyc": true, "sub/deep/c.tmp": true, "sub/x/deep/c.tmp": true, "sub/c.tmp": false,
+			"sub/out": true, "sub/deep/out": false,
+			"sub/dist/x": true, "sub/deep/dist/x": false,
+		}},
+		{".rsync-filter", "; keep logs\n+ keep.log\n- *.log\ninclude /deep/a.tmp\n*.tmp\n- /out/***\nexclude deep/c\n", DialectAuto, map[string]bool{
+			"sub/a.log": true, "sub/keep.log": false, "sub/deep/keep.log": false,
+			"sub/deep/a.tmp": false, "sub/a.tmp": true, "sub/x/deep/a.tmp": true,
+			"sub/out": true, "sub/out/x/y": true, "sub/deep/out": false,
+			"sub/deep/c": true, "sub/x/deep/c": true, "sub/c": false,
+		}},
+		{".gitignore", "size:>0 *.o\n", DialectNative, map[string]bool{
+			"sub/a.o": false,
+		}},
+		{"rules", "*.md\n", DialectDocker, map[string]bool{
+			"sub/a.md": true, "sub/deep/a.md": false,
+		}},
+	}
+
+	for _, t := range tests {
+		path := filepath.Join(dir, "sub", t.Name)
+		ioutil.WriteFile(path, []byte(t.Content), 0644)
+		m := New("match.conf")
+		m.Dialect = t.Dialect
+		w, err := m.NewWorker(dir)
+		if err != nil {
+			fw.Fatal(err)
+		}
+		if err = w.AddFile(path); err != nil {
+			fw.Errorf("w.AddFile(%q) = %v", t.Name, err)
+			continue
+		}
+		for p, e := range t.Matches {
+			if r := w.Matches(p); r != e {
+				fw.Errorf("with %s: w.Matches(%q) = %v, expected %v", t.Name, p, r, e)
+			}
+		}
+		os.Remove(path)
+	}
+}
+
+func TestDialectErrors(fw *testing.T) {
+	tests := map[string]error{
+		"syntax: perl\n": ErrUnknownSyntax,
+		"re:a(b\n":       ErrBadRegexp,
+		"re:\\Qabc\n":    ErrBadRegexp,
+		"re:a)(b\n":      ErrBadRegexp,
+		"re:^a)|(b\n":    ErrBadRegexp,
+		"glob:[\n":       ErrIncompleteClass,
+	}
+	for k, v := range tests {
+		p := newParser(DialectHg)
+		_, err := p.parseLine(Clean(k[:len(k)-1]))
+		if pe, ok := err.(*BadPatternError); !ok || pe.Err != v {
+			fw.Errorf("parsing %q: got %v, expected %v", k, err, v)
+		}
+	}
+
+	// An invalid expression is reported instead of panicking when matched.
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".hgignore")
+	ioutil.WriteFile(path, []byte("syntax: regexp\n\\Qabc\n"), 0644)
+	w, err := New("").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if err := w.AddFile(path); err == nil {
+		fw.Errorf("w.AddFile() of %q succeeded", "\\Qabc")
+	}
+	if w.Matches("abc") {
+		fw.Errorf("w.Matches(%q) = true with an invalid expression", "abc")
+	}
+	if matchRegexp(anchorRegexp("\\Qabc", dir), filepath.Join(dir, "abc")) {
+		fw.Errorf("matchRegexp() of an invalid expression succeeded")
+	}
+
+	rsync := map[string]error{
+		"merge .rules": ErrUnknownSyntax,
+		": .rules":     ErrUnknownSyntax,
+		"P a":          ErrUnknownSyntax,
+		"!":            ErrUnknownSyntax,
+		"- [":          ErrIncompleteClass,
+	}
+	for k, v := range rsync {
+		_, err := newParser(DialectRsync).parseLine(k)
+		if pe, ok := err.(*BadPatternError); !ok || pe.Err != v {
+			fw.Errorf("rsync: parsing %q: got %v, expected %v", k, err, v)
+		}
+	}
+}
+
+func TestParseDialect(fw *testing.T) {
+	for d := DialectAuto; d <= DialectRsync; d++ {
+		if v, err := ParseDialect(d.String()); v != d || err != nil {
+			fw.Errorf("ParseDialect(%q) = (%v, %v), expected %v", d.String(), v, err, d)
+		}
+		text, err := d.MarshalText()
+		var v Dialect
+		if err != nil || v.UnmarshalText(text) != nil || v != d {
+			fw.Errorf("%v does not survive marshaling as text: %q, %v", d, text, err)
+		}
+	}
+	if d, err := ParseDialect("GitIgnore"); d != DialectGitignore || err != nil {
+		fw.Errorf("ParseDialect(%q) = (%v, %v), expected %v", "GitIgnore", d, err, DialectGitignore)
+	}
+	for _, name := range []string{"", "unknown", "svn"} {
+		if _, err := ParseDialect(name); err != ErrUnknownDialect {
+			fw.Errorf("ParseDialect(%q) = %v, expected %v", name, err, ErrUnknownDialect)
+		}
+	}
+	if _, err := Dialect(42).MarshalText(); err != ErrUnknownDialect {
+		fw.Errorf("Dialect(42).MarshalText() = %v, expected %v", err, ErrUnknownDialect)
+	}
+
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "match.conf")
+	ioutil.WriteFile(path, []byte("*.o\n"), 0644)
+	m := New("match.conf")
+	m.Dialect = Dialect(42)
+	w, err := m.NewWorker(dir)
+	if err == nil {
+		err = w.AddFile(path)
+	}
+	if pe, ok := err.(*os.PathError); !ok || pe.Err != ErrUnknownDialect {
+		fw.Errorf("reading a file in Dialect(42) = %v, expected %v", err, ErrUnknownDialect)
+	}
+}
+
+func TestCapabilities(fw *testing.T) {
+	m := New("match.conf")
+	tests := map[string]Capabilities{
+		"match.conf":    DialectNative.Capabilities(),
+		".gitignore":    {Globstar: true, Negation: true, DirOnly: true, Unanchored: true},
+		".dockerignore": {Globstar: true, Negation: true},
+		".hgignore":     {Globstar: true, Regexp: true, DirOnly: true, Unanchored: true},
+		".rsync-filter": {Globstar: true, Negation: true, DirOnly: true, Unanchored: true},
+	}
+	for k, v := range tests {
+		if c := m.Capabilities(k); c != v {
+			fw.Errorf("m.Capabilities(%q) = %+v, expected %+v", k, c, v)
+		}
+	}
+	if c := DialectNative.Capabilities(); !c.Predicates || !c.Macros || !c.Variables || !c.Braces || c.Regexp {
+		fw.Errorf("DialectNative.Capabilities() = %+v", c)
+	}
+	if c := DialectAuto.Capabilities(); c != (Capabilities{}) {
+		fw.Errorf("DialectAuto.Capabilities() = %+v, expected none", c)
+	}
+	m.Dialect = DialectHg
+	if c := m.Capabilities("match.conf"); !c.Regexp {
+		fw.Errorf("with DialectHg: m.Capabilities(\"match.conf\") = %+v", c)
+	}
+	if s := Dialect(42).String(); s != "unknown" {
+		fw.Errorf("Dialect(42).String() = %q", s)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// NodeKind is the kind of a line in a rule file.
+type NodeKind int
+
+const (
+	// NodeBlank is a line without a pattern, i.e. an empty line or one
+	// consisting only of whitespace.
+	NodeBlank NodeKind = iota
+
+	// NodeComment is a line starting with a hash ("#").
+	NodeComment
+
+	// NodeDefinition is a macro definition or a variable assignment.
+	NodeDefinition
+
+	// NodePattern is a line with a pattern, possibly with predicates.
+	NodePattern
+)
+
+// Node is a line of a rule file, as returned by Parse.
+type Node struct {
+	Kind NodeKind
+
+	// Text is the line as it is written, without the line ending.
+	Text string
+
+	// EOL is the line ending, "\n" or "\r\n". It is empty for a last line
+	// that does not end with a newline.
+	EOL string
+}
+
+// NewNode returns a node for the line s, ending with "\n".
+func NewNode(s string) Node {
+	return Node{Kind: kindOf(s), Text: s, EOL: "\n"}
+}
+
+// Pattern returns the pattern of a NodePattern, as it is read by AddFile,
+// i.e. with unescaped trailing whitespace removed. It returns "" for other
+// kinds of nodes.
+func (n Node) Pattern() string {
+	if n.Kind != NodePattern {
+		return ""
+	}
+	return Clean(n.Text)
+}
+
+// Parse reads a rule file into a node for each of its lines, so that tools
+// can insert and remove rules in files that users maintain, and write them
+// back with Render. Unchanged lines are written back byte for byte, with
+// their comments, whitespace, and line endings.
+//
+// Parse does not check the patterns; use Lint for that. The only possible
+// errors are those of reading from r.
+func Parse(r io.Reader) ([]Node, error) {
+	var nodes []Node
+	br := bufio.NewReader(r)
+	for {
+		s, err := br.ReadString('\n')
+		if s != "" {
+			var n Node
+			switch {
+			case strings.HasSuffix(s, "\r\n"):
+				n.Text, n.EOL = s[:len(s)-2], "\r\n"
+			case strings.HasSuffix(s, "\n"):
+				n.Text, n.EOL = s[:len(s)-1], "\n"
+			default:
+				n.Text = s
+			}
+			n.Kind = kindOf(n.Text)
+			nodes = append(nodes, n)
+		}
+		if err == io.EOF {
+			return nodes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Render writes the nodes to w as a rule file. A node without a line ending
+// is followed by "\n", unless it is the last one.
+func Render(w io.Writer, nodes []Node) error {
+	bw := bufio.NewWriter(w)
+	for i, n := range nodes {
+		bw.WriteString(n.Text)
+		switch {
+		case n.EOL != "":
+			bw.WriteString(n.EOL)
+		case i < len(nodes)-1:
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// kindOf returns the kind of the line s of a rule file.
+func kindOf(s string) NodeKind {
+	c := Clean(s)
+	switch {
+	case strings.HasPrefix(s, "#"):
+		return NodeComment
+	case c == "":
+		return NodeBlank
+	case strings.HasPrefix(c, "define "), strings.HasPrefix(c, "set "):
+		return NodeDefinition
+	default:
+		return NodePattern
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseRender(fw *testing.T) {
+	tests := []string{
+		"",
+		"*.o",
+		"*.o\n",
+		"# objects\n*.o\n\n  \t\n\\#hash\nbuild/   \n",
+		"*.o\r\n# windows\r\n\r\nlast",
+		"define SRC = *.c\nset x = a\n@SRC\n",
+		"\n\n\n",
+	}
+	for _, t := range tests {
+		nodes, err := Parse(strings.NewReader(t))
+		if err != nil {
+			fw.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := Render(&buf, nodes); err != nil {
+			fw.Fatal(err)
+		}
+		if buf.String() != t {
+			fw.Errorf("Render(Parse(%q)) = %q", t, buf.String())
+		}
+	}
+}
+
+func TestNodeKinds(fw *testing.T) {
+	in := "# c\n\n  \n*.o  \ndefine A = x\nset v = y\n\\#x\n size:>1M *.iso\n"
+	nodes, err := Parse(strings.NewReader(in))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	expected := []struct {
+		Kind    NodeKind
+		Pattern string
+	}{
+		{NodeComment, ""},
+		{NodeBlank, ""},
+		{NodeBlank, ""},
+		{NodePattern, "*.o"},
+		{NodeDefinition, ""},
+		{NodeDefinition, ""},
+		{NodePattern, "\\#x"},
+		{NodePattern, " size:>1M *.iso"},
+	}
+	if len(nodes) != len(expected) {
+		fw.Fatalf("Parse() = %d nodes, expected %d", len(nodes), len(expected))
+	}
+	for i, e := range expected {
+		if n := nodes[i]; n.Kind != e.Kind || n.Pattern() != e.Pattern {
+			fw.Errorf("node %d = (%v, %q), expected (%v, %q)", i, n.Kind, n.Pattern(), e.Kind, e.Pattern)
+		}
+	}
+}
+
+func TestEditDocument(fw *testing.T) {
+	nodes, err := Parse(strings.NewReader("# build\r\n*.o\r\n*.tmp"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	nodes = append(nodes[:1], nodes[2:]...)
+	nodes = append(nodes, NewNode("*.log"))
+	var buf bytes.Buffer
+	Render(&buf, nodes)
+	if s := buf.String(); s != "# build\r\n*.tmp\n*.log\n" {
+		fw.Errorf("edited document = %q", s)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import "time"
+
+// EventKind identifies what happened in an Event.
+type EventKind int
+
+const (
+	// EventTried is emitted for every rule that is evaluated against
+	// a path, whether it matches or not.
+	EventTried EventKind = iota
+
+	// EventMatched is emitted when a rule or a layer decides that a path
+	// is matched.
+	EventMatched
+
+	// EventNegated is emitted when a negated rule decides that a path
+	// is not matched.
+	EventNegated
+
+	// EventPruned is emitted when walking a directory tree does not
+	// evaluate the rules for anything beneath a directory, because the
+	// directory itself is matched.
+	EventPruned
+)
+
+var eventNames = []string{
+	EventTried:   "tried",
+	EventMatched: "matched",
+	EventNegated: "negated",
+	EventPruned:  "pruned",
+}
+
+// String returns the name of the kind, such as "matched".
+func (k EventKind) String() string {
+	if k < 0 || int(k) >= len(eventNames) {
+		return "unknown"
+	}
+	return eventNames[k]
+}
+
+// Event is a step in deciding whether a path is matched.
+type Event struct {
+	Kind EventKind
+
+	// Path is the absolute path that the event is about.
+	Path string
+
+	// Rule is the rule that was tried or that decided. It is the zero Rule
+	// for EventPruned and for decisions of layers.
+	Rule Rule
+
+	// Time is when the event happened.
+	Time time.Time
+}
+
+// EventSink receives the events of evaluating rules from a Worker, so that
+// external tools can build timelines or audit logs of decisions. A sink
+// that is shared between Workers used concurrently must be safe for
+// concurrent use.
+//
+// Since an event is emitted for every rule that is tried, a sink slows
+// down matching considerably, and should only be set when needed.
+type EventSink interface {
+	Event(e Event)
+}
+
+func (w *Worker) emit(k EventKind, path string, r Rule) {
+	if w.events != nil {
+		w.events.Event(Event{Kind: k, Path: path, Rule: r, Time: now()})
+	}
+}
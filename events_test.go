@@ -0,0 +1,77 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type eventRecorder []Event
+
+func (r *eventRecorder) Event(e Event) {
+	*r = append(*r, e)
+}
+
+// kinds returns the kinds and rule globs of the recorded events.
+func (r eventRecorder) kinds() []string {
+	var s []string
+	for _, e := range r {
+		s = append(s, e.Kind.String()+" "+e.Rule.String())
+	}
+	return s
+}
+
+func TestEvents(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "out"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "out", "x"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "rules"), []byte("*.log\n!keep.log\nout/\n"), 0644)
+
+	var rec eventRecorder
+	m := New("rules")
+	m.Events = &rec
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+
+	tests := map[string][]string{
+		"a.log":    {"tried out/", "tried !keep.log", "tried *.log", "matched *.log"},
+		"keep.log": {"tried out/", "tried !keep.log", "negated !keep.log"},
+		"a.txt":    {"tried out/", "tried !keep.log", "tried *.log"},
+	}
+	for k, v := range tests {
+		rec = nil
+		w.Matches(k)
+		if !reflect.DeepEqual(rec.kinds(), v) {
+			fw.Errorf("events of w.Matches(%q) = %q, expected %q", k, rec.kinds(), v)
+		}
+		for _, e := range rec {
+			if e.Path != filepath.Join(dir, k) || e.Time.IsZero() {
+				fw.Errorf("event %+v does not have the path and time", e)
+			}
+		}
+	}
+
+	rec = nil
+	w.ListIncluded(".")
+	var pruned []string
+	for _, e := range rec {
+		if e.Kind == EventPruned {
+			pruned = append(pruned, e.Path)
+		}
+	}
+	if !reflect.DeepEqual(pruned, []string{filepath.Join(dir, "out")}) {
+		fw.Errorf("pruned paths = %q, expected the directory out", pruned)
+	}
+}
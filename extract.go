@@ -0,0 +1,107 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Extractor pulls rules out of a structured file that is not a rule file
+// itself, such as the "ignore" array of a package.json. Each returned string
+// is treated as a line of a rule file.
+type Extractor func(r io.Reader) ([]string, error)
+
+// RegisterExtractor makes rule files whose base name matches pattern be read
+// with e instead of line by line. The pattern has the syntax of path.Match.
+// The extracted lines go through the normal pipeline, so they may contain
+// predicates, macros, and variables, and are subject to MaxFileSize.
+// Extractors apply to all files read by a Worker, including the configuration
+// files found by NewWorker, so a Matcher may be created with New("package.json").
+//
+// RegisterExtractor is meant to be called from an init function. It panics
+// if pattern is invalid or already registered. If several patterns match
+// a name, the one registered first is used.
+func RegisterExtractor(pattern string, e Extractor) {
+	if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+		panic("matcher: invalid extractor pattern " + strconv.Quote(pattern))
+	}
+	if e == nil {
+		panic("matcher: RegisterExtractor extractor is nil")
+	}
+
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	for _, x := range extractors {
+		if x.pattern == pattern {
+			panic("matcher: RegisterExtractor called twice for " + pattern)
+		}
+	}
+	extractors = append(extractors, extractor{pattern, e})
+}
+
+var errNotString = errors.New("array contains a value that is not a string")
+
+type extractor struct {
+	pattern string
+	extract Extractor
+}
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   []extractor
+)
+
+func lookupExtractor(name string) (Extractor, bool) {
+	name = strings.TrimSuffix(filepath.Base(name), ".gz")
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	for _, x := range extractors {
+		if m, _ := path.Match(x.pattern, name); m {
+			return x.extract, true
+		}
+	}
+	return nil, false
+}
+
+// JSONExtractor returns an Extractor that reads the array of strings found
+// by following keys through nested objects of a JSON document. For example,
+// JSONExtractor("ignore") reads the "ignore" array of a package.json.
+// If any of the keys is missing or does not lead to an array, no rules are
+// extracted; an array containing anything but strings is an error.
+func JSONExtractor(keys ...string) Extractor {
+	return func(r io.Reader) ([]string, error) {
+		var v interface{}
+		if err := json.NewDecoder(r).Decode(&v); err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, nil
+			}
+			v = obj[k]
+		}
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, nil
+		}
+		lines := make([]string, 0, len(list))
+		for _, x := range list {
+			s, ok := x.(string)
+			if !ok {
+				return nil, errNotString
+			}
+			lines = append(lines, s)
+		}
+		return lines, nil
+	}
+}
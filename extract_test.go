@@ -0,0 +1,69 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func init() {
+	RegisterExtractor("package.json", JSONExtractor("ignore"))
+	RegisterExtractor("*.project.json", JSONExtractor("build", "exclude"))
+}
+
+func TestJSONExtractor(fw *testing.T) {
+	tests := map[string][]string{
+		`{"ignore": ["*.o", "size:>1M"]}`:  {"*.o", "size:>1M"},
+		`{"ignore": []}`:                   {},
+		`{"name": "x"}`:                    nil,
+		`{"ignore": {"a": "b"}}`:           nil,
+		`{"build": {"exclude": ["dist"]}}`: nil,
+	}
+	for k, v := range tests {
+		lines, err := JSONExtractor("ignore")(strings.NewReader(k))
+		if err != nil || !reflect.DeepEqual(lines, v) {
+			fw.Errorf("JSONExtractor(ignore)(%s) = (%q, %v), expected %q", k, lines, err, v)
+		}
+	}
+	for _, s := range []string{`{"ignore": [1]}`, `{"ignore": `} {
+		if _, err := JSONExtractor("ignore")(strings.NewReader(s)); err == nil {
+			fw.Errorf("JSONExtractor(ignore)(%s) succeeded", s)
+		}
+	}
+}
+
+func TestExtractor(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"ignore": ["node_modules", "*.log"]}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "app.project.json"), []byte(`{"build": {"exclude": ["dist"]}}`), 0644)
+
+	w, err := New("package.json").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if err := w.AddFile(filepath.Join(dir, "app.project.json")); err != nil {
+		fw.Fatal(err)
+	}
+	tests := map[string]bool{
+		"node_modules": true,
+		"debug.log":    true,
+		"dist":         true,
+		"package.json": false,
+	}
+	for k, v := range tests {
+		if m := w.Matches(k); m != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, m, v)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/goulash/matcher/glob"
+)
+
+// Flatten writes a single rule file to out that is equivalent to all rules
+// of the Worker, i.e. the global rules and those of every configuration file
+// it loaded. Globs containing a path separator are written relative to the
+// working directory of the Worker, so the file is meant to be read from there,
+// or passed to tools such as rsync or tar that accept only one exclude file.
+//
+// Before the rules of each file, a comment names the file they came from.
+// Since later rules take precedence within a file, the files are written in
+// reverse order of precedence, ending with the global rules. Rules anchored
+// outside of the working directory cannot match anything beneath it and are
+// written as comments, and so are regular expressions from .hgignore files,
+// which cannot be expressed as globs. Layers added with AddMatcher are not
+// included.
+func (w *Worker) Flatten(out io.Writer) error {
+	bw := bufio.NewWriter(out)
+	cwd := glob.QuoteMeta(w.cwd)
+	files := groupBySource(w.Rules())
+	for i := len(files) - 1; i >= 0; i-- {
+		name := files[i][0].Source
+		if name == "" {
+			name = "(global)"
+		} else if rel, ok := w.rel(name); ok {
+			name = rel
+		}
+		if i < len(files)-1 {
+			fmt.Fprintln(bw)
+		}
+		fmt.Fprintf(bw, "# %s\n", name)
+
+		for _, r := range files[i] {
+			if r.Regexp {
+				fmt.Fprintf(bw, "# regular expression: %s\n", r.describe())
+				continue
+			}
+			if strings.Contains(r.Glob, "/") {
+				rel, ok := relTo(cwd, r.Glob)
+				if !ok {
+					fmt.Fprintf(bw, "# outside of %s: %s\n", w.cwd, r.describe())
+					continue
+				}
+				r.Glob = rel
+				if !strings.Contains(rel, "/") {
+					r.Glob = "./" + rel
+				}
+			}
+			fmt.Fprintln(bw, r.line())
+		}
+	}
+	return bw.Flush()
+}
+
+// groupBySource splits rules into runs of rules from the same file.
+func groupBySource(rules []Rule) [][]Rule {
+	var files [][]Rule
+	for i := 0; i < len(rules); {
+		j := i + 1
+		for j < len(rules) && rules[j].Source == rules[i].Source && rules[j].Scope == rules[i].Scope {
+			j++
+		}
+		files = append(files, rules[i:j])
+		i = j
+	}
+	return files
+}
+
+// rel returns path relative to the working directory of the Worker, using
+// forward slashes, and reports whether path is beneath it.
+func (w *Worker) rel(path string) (string, bool) {
+	return relTo(w.cwd, path)
+}
+
+// relTo returns path relative to dir, using forward slashes, and reports
+// whether path is beneath dir.
+func relTo(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path, false
+	}
+	return filepath.ToSlash(rel), true
+}
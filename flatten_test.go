@@ -0,0 +1,46 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFlatten(fw *testing.T) {
+	m := New("match.conf")
+	m.Add("match.conf")
+	w, err := m.NewWorker(filepath.Join("tests", "dead"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("size:>1M *.iso")
+
+	var buf bytes.Buffer
+	if err := w.Flatten(&buf); err != nil {
+		fw.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{"# (global)\nmatch.conf\n", "\n# match.conf\n", "\n./ok\n", "size:>1M *.iso\n"} {
+		if !strings.Contains(out, s) {
+			fw.Errorf("w.Flatten() does not contain %q:\n%s", s, out)
+		}
+	}
+
+	f, err := New("").NewWorker(filepath.Join("tests", "dead"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if err := f.addReader(&buf, "flat", f.cwd, ScopeSession); err != nil {
+		fw.Fatal(err)
+	}
+	for _, p := range []string{"ok", "match.conf", "ugly/foo", "bad/somefoo", "never", "good/x", "../foo"} {
+		if a, b := w.Matches(p), f.Matches(p); a != b {
+			fw.Errorf("flattened Matches(%q) = %v, expected %v", p, b, a)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Fold is a mode of case-insensitive matching. Since file systems disagree
+// about which names are equal, it is up to the application to choose the
+// mode that corresponds to the file systems it deals with.
+type Fold int
+
+const (
+	// FoldNone matches case-sensitively. This is the default.
+	FoldNone Fold = iota
+
+	// FoldASCII only treats the letters A to Z as equal to a to z,
+	// which is the fastest mode.
+	FoldASCII
+
+	// FoldSimple treats characters as equal if they are equal under
+	// simple Unicode case folding, which maps each character to exactly
+	// one character. For example, "K" matches the Kelvin sign.
+	FoldSimple
+
+	// FoldFull additionally applies the Unicode case foldings that map
+	// a character to several, such as "ß" to "ss" and "ﬁ" to "fi".
+	// Since these change the number of characters, "?" matches only one
+	// of the characters that a character is folded to.
+	FoldFull
+)
+
+func (f Fold) String() string {
+	switch f {
+	case FoldNone:
+		return "none"
+	case FoldASCII:
+		return "ascii"
+	case FoldSimple:
+		return "simple"
+	case FoldFull:
+		return "full"
+	default:
+		return "Fold(" + strconv.Itoa(int(f)) + ")"
+	}
+}
+
+// fold returns s folded according to f.
+func (f Fold) fold(s string) string {
+	switch f {
+	case FoldASCII:
+		return strings.Map(func(r rune) rune {
+			if 'A' <= r && r <= 'Z' {
+				return r + 'a' - 'A'
+			}
+			return r
+		}, s)
+	case FoldSimple:
+		return strings.Map(simpleFold, s)
+	case FoldFull:
+		var b strings.Builder
+		for _, r := range s {
+			x, ok := fullFolds[r]
+			if !ok {
+				b.WriteRune(simpleFold(r))
+				continue
+			}
+			for _, r := range x {
+				b.WriteRune(simpleFold(r))
+			}
+		}
+		return b.String()
+	default:
+		return s
+	}
+}
+
+// simpleFold returns the smallest rune that is equivalent to r under
+// simple case folding, so that equivalent runes map to the same one.
+func simpleFold(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
+
+// fullFolds contains the full case foldings of the Unicode character
+// database (status F in CaseFolding.txt) that are likely to occur in
+// filenames.
+var fullFolds = map[rune]string{
+	'\u00DF': "ss",                 // ß
+	'\u1E9E': "ss",                 // ẞ
+	'\u0130': "i\u0307",            // İ
+	'\u0149': "\u02BCn",            // ŉ
+	'\u01F0': "j\u030C",            // ǰ
+	'\u0390': "\u03B9\u0308\u0301", // ΐ
+	'\u03B0': "\u03C5\u0308\u0301", // ΰ
+	'\u0587': "\u0565\u0582",       // և
+	'\u1E96': "h\u0331",            // ẖ
+	'\u1E97': "t\u0308",            // ẗ
+	'\u1E98': "w\u030A",            // ẘ
+	'\u1E99': "y\u030A",            // ẙ
+	'\u1E9A': "a\u02BE",            // ẚ
+	'\uFB00': "ff",                 // ﬀ
+	'\uFB01': "fi",                 // ﬁ
+	'\uFB02': "fl",                 // ﬂ
+	'\uFB03': "ffi",                // ﬃ
+	'\uFB04': "ffl",                // ﬄ
+	'\uFB05': "st",                 // ﬅ
+	'\uFB06': "st",                 // ﬆ
+	'\uFB13': "\u0574\u0576",       // ﬓ
+	'\uFB14': "\u0574\u0565",       // ﬔ
+	'\uFB15': "\u0574\u056B",       // ﬕ
+	'\uFB16': "\u057E\u0576",       // ﬖ
+	'\uFB17': "\u0574\u056D",       // ﬗ
+}
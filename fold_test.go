@@ -0,0 +1,55 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import "testing"
+
+func TestFold(fw *testing.T) {
+	type test struct {
+		Pattern, Path string
+	}
+	tests := map[test][4]bool{
+		// none, ascii, simple, full
+		{"*.JPG", "a.jpg"}:          {false, true, true, true},
+		{"README", "readme"}:        {false, true, true, true},
+		{"[A-Z]*", "lower"}:         {false, true, true, true},
+		{"ÄPFEL", "äpfel"}:          {false, false, true, true},
+		{"k", "\u212A"}:             {false, false, true, true},
+		{"STRASSE", "straße"}:       {false, false, false, true},
+		{"office", "o\uFB03ce"}:     {false, false, false, true},
+		{"straße", "straße"}:        {true, true, true, true},
+		{"stra?e", "straße"}:        {true, true, true, false},
+		{"src/*.GO", "SRC/main.go"}: {false, true, true, true},
+		{"\\*.TXT", "*.txt"}:        {false, true, true, true},
+		{"*.txt", "a.text"}:         {false, false, false, false},
+	}
+	for k, v := range tests {
+		for i, f := range []Fold{FoldNone, FoldASCII, FoldSimple, FoldFull} {
+			m := &Matcher{CaseFold: f}
+			if m := m.match(k.Pattern, k.Path); m != v[i] {
+				fw.Errorf("%s: match(%q, %q) = %v, expected %v", f, k.Pattern, k.Path, m, v[i])
+			}
+		}
+	}
+}
+
+func TestCaseFold(fw *testing.T) {
+	m := New("")
+	m.Add("*.JPG")
+	m.CaseFold = FoldASCII
+	w, err := m.NewWorker(".")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("Thumbs.db")
+	for _, p := range []string{"IMG.jpg", "thumbs.DB"} {
+		if !w.Matches(p) {
+			fw.Errorf("w.Matches(%q) = false with %s folding", p, m.CaseFold)
+		}
+	}
+	if !m.Matches("a.Jpg") {
+		fw.Errorf("m.Matches(%q) = false with %s folding", "a.Jpg", m.CaseFold)
+	}
+}
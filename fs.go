@@ -0,0 +1,134 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// NewWorkerFS is like NewWorker, but reads the configuration files of the
+// project from fsys instead of the file system of the OS, such as from an
+// embed.FS or a zip archive. The directory dir is a path in fsys as accepted
+// by fs.ValidPath, so "." is its root, which is also the last directory in
+// which configuration files and sentinels are looked for.
+//
+// The Worker treats fsys as if it were mounted at the root directory: its
+// working directory is dir with a leading slash, and its rules are anchored
+// beneath it. Paths passed to the Worker are relative to dir as usual.
+// The files of the user and system scopes are still read from the OS.
+// Predicates and rules that only match directories stat paths on the OS,
+// so MatchesInfo should be used with the result of fs.Stat for them.
+func (m *Matcher) NewWorkerFS(fsys fs.FS, dir string) (*Worker, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &os.PathError{Op: "open", Path: dir, Err: fs.ErrInvalid}
+	}
+
+	w := m.newWorker(fsPath(dir))
+	dirs := m.configDirsFS(fsys, dir)
+	if last := dirs[len(dirs)-1]; m.isRootFS(fsys, last) {
+		w.root = fsPath(last)
+	}
+	var errs ConfigErrors
+	if len(m.names) > 0 && !m.disabled[ScopeProject] {
+		for _, d := range dirs {
+			for _, name := range m.configNames() {
+				name = path.Join(d, name)
+				err := w.handleLoad(name, w.addFileFS(fsys, name, ScopeProject), &errs)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if err := w.loadUser(&errs); err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return w, errs
+	}
+	return w, nil
+}
+
+// AddFileFS is like AddFile, but reads the file name from fsys.
+// As for NewWorkerFS, globs containing a path separator are anchored
+// as if fsys were mounted at the root directory.
+func (w *Worker) AddFileFS(fsys fs.FS, name string) error {
+	return w.addFileFS(fsys, name, ScopeSession)
+}
+
+func (w *Worker) addFileFS(fsys fs.FS, name string, s Scope) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	abs := fsPath(name)
+	err = w.read(context.Background(), f, name, filepath.Dir(abs), s, nil)
+	if _, ok := err.(PatternErrors); err != nil && !ok {
+		return err
+	}
+	c := config{path: abs, name: name, scope: s, fsys: fsys}
+	if fi, err := f.Stat(); err == nil {
+		c.modTime, c.size = fi.ModTime(), fi.Size()
+	}
+	w.configs = append(w.configs, c)
+	w.count(MetricConfigsLoaded)
+	return err
+}
+
+// fsPath returns the path at which the Worker sees the path name in an fs.FS.
+func fsPath(name string) string {
+	return filepath.FromSlash(path.Join("/", name))
+}
+
+// configDirsFS is like configDirs for the directory dir in fsys.
+func (m *Matcher) configDirsFS(fsys fs.FS, dir string) []string {
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		if dir == "." || m.isRootFS(fsys, dir) {
+			return dirs
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// isRootFS is like isRoot for the directory dir in fsys,
+// which is compared with the Root of the Matcher as seen by the Worker.
+func (m *Matcher) isRootFS(fsys fs.FS, dir string) bool {
+	if m.Root != "" && filepath.Clean(m.Root) == fsPath(dir) {
+		return true
+	}
+	for _, s := range m.sentinels() {
+		if _, err := fs.Stat(fsys, path.Join(dir, s)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// stat returns the current FileInfo of the configuration file.
+func (c config) stat() (fs.FileInfo, error) {
+	if c.fsys != nil {
+		return fs.Stat(c.fsys, c.name)
+	}
+	return os.Stat(c.path)
+}
+
+// readConfig reads the configuration file c again.
+func (w *Worker) readConfig(c config) error {
+	switch {
+	case c.fsys != nil:
+		return w.addFileFS(c.fsys, c.name, c.scope)
+	case c.nested:
+		return w.addNested(c.name)
+	}
+	return w.addFile(c.name, c.scope)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestNewWorkerFS(fw *testing.T) {
+	fsys := fstest.MapFS{
+		"match.conf":           {Data: []byte("*.o\n")},
+		"proj/.root":           {},
+		"proj/match.conf":      {Data: []byte("*.tmp\nbuild/*\n")},
+		"proj/src/match.conf":  {Data: []byte("gen/\n!keep.tmp\n")},
+		"proj/src/extra.rules": {Data: []byte("*.bak\n")},
+	}
+	m := New("match.conf")
+	m.Sentinels = []string{".root"}
+	w, err := m.NewWorkerFS(fsys, "proj/src")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if w.Dir() != "/proj/src" || w.Root() != "/proj" {
+		fw.Errorf("w.Dir(), w.Root() = %q, %q, expected /proj/src, /proj", w.Dir(), w.Root())
+	}
+	if err := w.AddFileFS(fsys, "proj/src/extra.rules"); err != nil {
+		fw.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"a.o":              false,
+		"a.tmp":            true,
+		"keep.tmp":         false,
+		"a.bak":            true,
+		"/proj/build/x":    true,
+		"build/x":          false,
+		"/proj/src/file.c": false,
+	}
+	for k, v := range tests {
+		if r := w.Matches(k); r != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+	expected := []string{"/proj/src/match.conf", "/proj/match.conf", "/proj/src/extra.rules"}
+	if files := w.ConfigFiles(); len(files) != len(expected) || files[0] != expected[0] || files[1] != expected[1] || files[2] != expected[2] {
+		fw.Errorf("w.ConfigFiles() = %q, expected %q", files, expected)
+	}
+
+	if _, err := m.NewWorkerFS(fsys, "/proj"); err == nil {
+		fw.Errorf("m.NewWorkerFS(fsys, %q) succeeded, expected error", "/proj")
+	}
+	if err := w.AddFileFS(fsys, "missing"); !os.IsNotExist(err) {
+		fw.Errorf("w.AddFileFS(fsys, %q) = %v, expected not exist", "missing", err)
+	}
+}
+
+func TestNewWorkerFSReload(fw *testing.T) {
+	fsys := fstest.MapFS{
+		"match.conf": {Data: []byte("*.o\n"), ModTime: time.Unix(1, 0)},
+	}
+	w, err := New("match.conf").NewWorkerFS(fsys, ".")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if w.configsChanged() {
+		fw.Fatal("w.configsChanged() = true before changing the file")
+	}
+	fsys["match.conf"] = &fstest.MapFile{Data: []byte("*.a\n"), ModTime: time.Unix(2, 0)}
+	if !w.configsChanged() {
+		fw.Fatal("w.configsChanged() = false after changing the file")
+	}
+	if err := w.reload(); err != nil {
+		fw.Fatal(err)
+	}
+	if w.Matches("x.o") || !w.Matches("x.a") {
+		fw.Errorf("w.Matches(x.o), w.Matches(x.a) = %v, %v after reload, expected false, true", w.Matches("x.o"), w.Matches("x.a"))
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// gitConfig returns all values of key in the git configuration as seen from
+// dir, or none if key is not set. If path is true, the values are expanded
+// as paths, so that a leading "~/" is replaced by the home directory.
+// It is replaced in tests.
+var gitConfig = func(dir, key string, path bool) ([]string, error) {
+	args := []string{"config", "--null", "--get-all", key}
+	if path {
+		args = append(args[:1], append([]string{"--path"}, args[1:]...)...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if e, ok := err.(*exec.ExitError); ok && e.ExitCode() == 1 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(bytes.TrimSuffix(out, []byte{0})), "\x00"), nil
+}
+
+// GitConfigLayer returns a Worker in dir whose rules are taken from the git
+// configuration, so that git-adjacent tools honor the settings of the user.
+// It is meant to be attached to another Worker with AddMatcher.
+//
+// The rules are read from the excludes file configured in core.excludesFile,
+// or if that is not set, the default file of git, UserRuleFile("git").
+// In addition, every value of each of the given keys, which may be set
+// multiple times, is read as a line of a rule file in dir.
+//
+// The git configuration is read by running git, which must be installed.
+// The patterns are interpreted with the syntax of this package, which does
+// not support all of gitignore.
+func GitConfigLayer(dir string, keys ...string) (*Worker, error) {
+	m := New("")
+	m.SetScope(ScopeUser, false)
+	m.SetScope(ScopeSystem, false)
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := gitConfig(w.cwd, "core.excludesFile", true)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		if path, err := UserRuleFile("git"); err == nil {
+			files = []string{path}
+		}
+	}
+	for _, f := range files {
+		if err := w.addFile(f, ScopeUser); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	for _, key := range keys {
+		values, err := gitConfig(w.cwd, key, false)
+		if err != nil {
+			return nil, err
+		}
+		r := strings.NewReader(strings.Join(values, "\n"))
+		if err := w.addReader(r, "git config "+key, w.cwd, ScopeUser); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
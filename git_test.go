@@ -0,0 +1,59 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitConfigLayer(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	excludes := filepath.Join(dir, "excludes")
+	ioutil.WriteFile(excludes, []byte("*.swp\n.DS_Store\n"), 0644)
+
+	config := map[string][]string{
+		"core.excludesFile": {excludes},
+		"tool.ignore":       {"*.cache", "build/*"},
+	}
+	defer func(f func(string, string, bool) ([]string, error)) { gitConfig = f }(gitConfig)
+	gitConfig = func(_, key string, _ bool) ([]string, error) {
+		return config[key], nil
+	}
+
+	l, err := GitConfigLayer(dir, "tool.ignore", "tool.unset")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w, err := New("").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.AddMatcher(l, Lowest)
+	tests := map[string]bool{
+		"a.swp":     true,
+		".DS_Store": true,
+		"x.cache":   true,
+		"build/out": true,
+		"src/out":   false,
+		"main.go":   false,
+	}
+	for k, v := range tests {
+		if m := w.Matches(k); m != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, m, v)
+		}
+	}
+
+	config["core.excludesFile"] = []string{filepath.Join(dir, "missing")}
+	if _, err := GitConfigLayer(dir); err != nil {
+		fw.Errorf("GitConfigLayer with missing excludes file failed: %s", err)
+	}
+}
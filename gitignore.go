@@ -0,0 +1,139 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"strings"
+
+	"github.com/goulash/matcher/glob"
+)
+
+// posixClasses are the character classes of POSIX bracket expressions,
+// such as "[:digit:]", as ranges of ASCII characters.
+var posixClasses = map[string]string{
+	"alnum":  "0-9A-Za-z",
+	"alpha":  "A-Za-z",
+	"blank":  " \t",
+	"cntrl":  "\x00-\x1f\x7f",
+	"digit":  "0-9",
+	"graph":  "!-~",
+	"lower":  "a-z",
+	"print":  " -~",
+	"punct":  "!-/:-@\\[-`{-~",
+	"space":  "\t-\r ",
+	"upper":  "A-Z",
+	"xdigit": "0-9A-Fa-f",
+}
+
+// gitGlob rewrites a cleaned line of a .gitignore file for
+// Matcher.GitignoreSemantics into the syntax of this package: "**" that is
+// not a whole element of the path is a single "*", character classes are
+// negated by "!" as well as "^", a "]" at the start of a class and a "-" at
+// either end are literal, and POSIX character classes are expanded.
+func gitGlob(s string) string {
+	s = collapseStars(s)
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			b.WriteString(s[i : i+2])
+			i++
+		case s[i] == '[':
+			i = gitClass(&b, s, i)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// gitClass writes the character class starting at s[i] to b, and returns
+// the index of its closing bracket. If it is not closed, only the opening
+// bracket is written, so that Check reports the class as incomplete.
+func gitClass(b *strings.Builder, s string, i int) int {
+	end := classEnd(s, i)
+	if end < 0 {
+		b.WriteByte('[')
+		return i
+	}
+	b.WriteByte('[')
+	j := i + 1
+	if s[j] == '!' || s[j] == '^' {
+		b.WriteByte('^')
+		j++
+	}
+	start := j
+	if s[j] == ']' {
+		b.WriteString("\\]")
+		j++
+	}
+	for j < end {
+		switch {
+		case s[j] == '\\' && j+1 < end:
+			b.WriteString(s[j : j+2])
+			j += 2
+		case s[j] == '-' && (j == start || j+1 == end):
+			b.WriteString("\\-")
+			j++
+		case strings.HasPrefix(s[j:], "[:"):
+			if k := strings.Index(s[j+2:end], ":]"); k >= 0 {
+				if r, ok := posixClasses[s[j+2:j+2+k]]; ok {
+					b.WriteString(r)
+					j += k + 4
+					continue
+				}
+			}
+			b.WriteString("\\[")
+			j++
+		default:
+			b.WriteByte(s[j])
+			j++
+		}
+	}
+	b.WriteByte(']')
+	return end
+}
+
+// classEnd returns the index of the bracket closing the class that starts
+// at s[i], as git finds it, or -1 if there is none.
+func classEnd(s string, i int) int {
+	j := i + 1
+	if j < len(s) && (s[j] == '!' || s[j] == '^') {
+		j++
+	}
+	if j < len(s) && s[j] == ']' {
+		j++
+	}
+	for j < len(s) {
+		switch {
+		case s[j] == '\\':
+			j += 2
+		case strings.HasPrefix(s[j:], "[:"):
+			k := strings.Index(s[j+2:], ":]")
+			if k < 0 {
+				j++
+			} else {
+				j += k + 4
+			}
+		case s[j] == ']':
+			return j
+		default:
+			j++
+		}
+	}
+	return -1
+}
+
+// gitAnchor anchors pattern, a glob read from a .gitignore file in base, as
+// git does for Matcher.GitignoreSemantics. A pattern with a slash is relative
+// to base, and a single leading slash only serves to anchor it. The pattern is
+// not cleaned, so that elements like "." and ".." and repeated slashes are
+// kept, which never match, as in git.
+func gitAnchor(pattern, base string) string {
+	if !strings.Contains(pattern, "/") {
+		return pattern
+	}
+	return strings.TrimSuffix(glob.QuoteMeta(base), "/") + "/" + strings.TrimPrefix(pattern, "/")
+}
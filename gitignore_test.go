@@ -0,0 +1,264 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// gitVectors are patterns of a .gitignore file in the root of a repository
+// and paths relative to it, along with whether git check-ignore reports them
+// as ignored, which they are if they or one of their parent directories are
+// matched. Paths ending in a slash are directories. Most are taken from the
+// wildmatch tests of git, t3070-wildmatch.sh.
+var gitVectors = []struct {
+	pattern, path string
+	ignored       bool
+}{
+	{"foo", "foo", true},
+	{"foo", "bar", false},
+	{"???", "foo", true},
+	{"??", "foo", false},
+	{"*", "foo", true},
+	{"f*", "foo", true},
+	{"*f", "foo", false},
+	{"*foo*", "foo", true},
+	{"*ob*a*r*", "foobar", true},
+	{"*ab", "aaaaaaabababab", true},
+	{"foo\\*", "foo*", true},
+	{"foo\\*bar", "foobar", false},
+	{"*[al]?", "ball", true},
+	{"[ten]", "ten", false},
+	{"**[!te]", "ten", true},
+	{"**[!ten]", "ten", false},
+	{"t[a-g]n", "ten", true},
+	{"t[!a-g]n", "ten", false},
+	{"t[!a-g]n", "ton", true},
+	{"t[^a-g]n", "ton", true},
+	{"a[]]b", "a]b", true},
+	{"a[]-]b", "a-b", true},
+	{"a[]-]b", "a]b", true},
+	{"a[]-]b", "aab", false},
+	{"a[]a-]b", "aab", true},
+	{"]", "]", true},
+	{"foo*bar", "foo/baz/bar", false},
+	{"foo**bar", "foo/baz/bar", false},
+	{"foo**bar", "foobazbar", true},
+	{"foo/**/bar", "foo/baz/bar", true},
+	{"foo/**/**/bar", "foo/baz/bar", true},
+	{"foo/**/bar", "foo/b/a/z/bar", true},
+	{"foo/**/bar", "foo/bar", true},
+	{"foo/*/bar", "foo/bar", false},
+	{"foo?bar", "foo/bar", false},
+	{"**/foo", "XXX/foo", true},
+	{"**/foo", "bar/baz/foo", true},
+	{"*/foo", "bar/baz/foo", false},
+	{"**/bar*", "foo/bar/baz", true},
+	{"**/bar/*", "deep/foo/bar/baz", true},
+	{"**/bar/*", "deep/foo/bar/baz/", true},
+	{"**/bar/**", "deep/foo/bar/baz/", true},
+	{"**/bar/*", "deep/foo/bar", false},
+	{"*/bar/**", "foo/bar/baz/x", true},
+	{"**/bar**", "foo/bar/baz", true},
+	{"**/**/**", "foo/bb/aa/rr", true},
+	{"*/*/*", "foo/bba/arr", true},
+	{"*/*/*", "foo/bb/aa/rr", true},
+	{"**/*a*b*g*n*t", "abcd/abcdefg/abcdefghijk/abcdefghijklmnop.txt", true},
+	{"*X*i", "abcXdefXghi", true},
+	{"*/*X*/*/*i", "ab/cXd/efXg/hi", true},
+	{"**/*X*/**/*i", "ab/cXd/efXg/hi", true},
+	{"-*-*-*-*-*-*-12-*-*-*-m-*-*-*", "-adobe-courier-bold-o-normal--12-120-75-75-m-70-iso8859-1", true},
+	{"XXX/*/*/*/*/*/*/12/*/*/*/m/*/*/*", "XXX/adobe/courier/bold/o/normal/a/12/120/75/75/m/70/iso8859/1", true},
+	{"[[:alpha:]][[:digit:]][[:upper:]]", "a1B", true},
+	{"[[:digit:][:upper:][:space:]]", "A", true},
+	{"[[:digit:][:upper:][:space:]]", "a", false},
+	{"[[:digit:][:upper:][:space:]]", "1", true},
+	{"[[:xdigit:]]", "5", true},
+	{"[[:xdigit:]]", "f", true},
+	{"[[:xdigit:]]", "D", true},
+	{"[[:xdigit:]]", "g", false},
+	{"[[:punct:]]", "!", true},
+	{"[[:punct:]]", "_", true},
+	{"[[:punct:]]", "a", false},
+	{"[a-c[:digit:]x-z]", "5", true},
+	{"[a-c[:digit:]x-z]", "b", true},
+	{"[a-c[:digit:]x-z]", "y", true},
+	{"[a-c[:digit:]x-z]", "q", false},
+	{"[[:lower:]]x", "ax", true},
+	{"[[:lower:]]x", "Ax", false},
+	{"[![:alnum:]]", "-", true},
+	{"[![:alnum:]]", "z", false},
+	{"[a[:b]", "b", true},
+	{"[a[:b]", "[", true},
+	{"[a[:b]", "c", false},
+	{"/foo", "foo", true},
+	{"/foo", "a/foo", false},
+	{"foo/", "foo/", true},
+	{"foo/", "a/foo/", true},
+	{"foo/", "foo", false},
+	{"a/b", "a/b", true},
+	{"a/b", "x/a/b", false},
+	{"/a/b", "a/b", true},
+	{"a/b/", "a/b/", true},
+	{"//a", "a", false},
+	{"./a", "a", false},
+	{"a/./b", "a/b", false},
+	{"a/../b", "b", false},
+	{"/", "a", false},
+	{"/*", "a", true},
+	{"/*", "a/b", true},
+	{"*/", "a/", true},
+	{"*/", "a/b/", true},
+	{"abc/**", "abc", false},
+	{"abc/**", "abc/x", true},
+	{"abc/**", "abc/x/y", true},
+	{"abc/**/", "abc/x/", true},
+	{"abc/**/", "abc/x", false},
+	{"**", "a/b", true},
+	{"**/", "a/", true},
+	{"**/", "a", false},
+	{"a/**/b", "a/b", true},
+	{"a/**/b", "a/x/y/b", true},
+	{"a\\ ", "a ", true},
+	{"\\#a", "#a", true},
+	{"\\!a", "!a", true},
+}
+
+// gitInfo returns the info of an empty file, or a directory if path ends
+// in a slash, as if it were in a fstest.MapFS.
+func gitInfo(path string) fs.FileInfo {
+	f := &fstest.MapFile{}
+	if strings.HasSuffix(path, "/") {
+		f.Mode = fs.ModeDir
+	}
+	fi, _ := fstest.MapFS{"x": f}.Stat("x")
+	return fi
+}
+
+// gitIgnored reports whether path, which is relative to the working
+// directory of w, is ignored as git decides it: if one of its parent
+// directories is matched, or otherwise, if it is.
+func gitIgnored(w *Worker, path string) bool {
+	elems := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	for i := 1; i < len(elems); i++ {
+		dir := strings.Join(elems[:i], "/") + "/"
+		if w.MatchesInfo(dir, gitInfo(dir)) {
+			return true
+		}
+	}
+	return w.MatchesInfo(path, gitInfo(path))
+}
+
+func TestGitignoreSemantics(fw *testing.T) {
+	m := New("")
+	m.GitignoreSemantics = true
+	for _, v := range gitVectors {
+		w, err := m.NewWorker("/repo")
+		if err != nil {
+			fw.Fatal(err)
+		}
+		if err := w.AddReader(strings.NewReader(v.pattern), ".gitignore"); err != nil {
+			fw.Errorf("%q: %v", v.pattern, err)
+			continue
+		}
+		if r := gitIgnored(w, v.path); r != v.ignored {
+			fw.Errorf("%q: w.Matches(%q) = %v, expected %v", v.pattern, v.path, r, v.ignored)
+		}
+	}
+}
+
+func TestGitignoreAnchoring(fw *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":     {Data: []byte("/top\ndoc/frotz\nx*/y\n//dbl\n")},
+		"sub/.gitignore": {Data: []byte("/anch\nrel/x\n*.tmp\n")},
+	}
+	m := New(".gitignore")
+	m.GitignoreSemantics = true
+	m.Sentinels = []string{}
+	tests := map[string]bool{
+		"top":             true,
+		"sub/top":         false,
+		"doc/frotz":       true,
+		"sub/doc/frotz":   false,
+		"xa/y":            true,
+		"sub/xa/y":        false,
+		"dbl":             false,
+		"sub/anch":        true,
+		"sub/q/anch":      false,
+		"sub/rel/x":       true,
+		"sub/q/rel/x":     false,
+		"sub/a.tmp":       true,
+		"sub/q/a.tmp":     true,
+		"a.tmp":           false,
+		"sub/q/r/doc/top": false,
+	}
+	for k, v := range tests {
+		w, err := m.NewWorkerFS(fsys, filepath.Dir(k))
+		if err != nil {
+			fw.Fatal(err)
+		}
+		if r := w.MatchesInfo("/"+k, gitInfo(k)); r != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+
+	// Without GitignoreSemantics, "//" is relative to the workspace root,
+	// which is the root of the file system here.
+	m.GitignoreSemantics = false
+	w, err := m.NewWorkerFS(fsys, ".")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if !w.MatchesInfo("/dbl", gitInfo("dbl")) {
+		fw.Error("w.Matches(\"/dbl\") = false without GitignoreSemantics, expected true")
+	}
+}
+
+// TestGitignoreGit checks gitVectors against the installed git, if any.
+func TestGitignoreGit(fw *testing.T) {
+	if testing.Short() {
+		fw.Skip("skipping running git in short mode")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		fw.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Keep the configuration of the user out of it.
+	env := append(os.Environ(), "HOME="+dir, "XDG_CONFIG_HOME="+dir, "GIT_CONFIG_NOSYSTEM=1")
+	git := func(args ...string) error {
+		cmd := exec.Command("git", args...)
+		cmd.Dir, cmd.Env = dir, env
+		return cmd.Run()
+	}
+	if err := git("init", "-q"); err != nil {
+		fw.Fatal(err)
+	}
+
+	for _, v := range gitVectors {
+		err := ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte(v.pattern+"\n"), 0644)
+		if err != nil {
+			fw.Fatal(err)
+		}
+		err = git("check-ignore", "-q", "--no-index", "--", v.path)
+		if e, ok := err.(*exec.ExitError); err != nil && (!ok || e.ExitCode() != 1) {
+			fw.Fatal(err)
+		}
+		if r := err == nil; r != v.ignored {
+			fw.Errorf("%q: git check-ignore %q = %v, expected %v", v.pattern, v.path, r, v.ignored)
+		}
+	}
+}
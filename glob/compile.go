@@ -0,0 +1,200 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package glob
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// escapes is whether a backslash escapes the next character, as it does
+// in filepath.Match everywhere but on Windows.
+const escapes = filepath.Separator != '\\'
+
+type tokenKind int
+
+const (
+	tokLiteral tokenKind = iota // a literal string
+	tokAny                      // '?'
+	tokClass                    // a character class
+)
+
+// token is a part of a chunk that matches at a fixed position.
+type token struct {
+	kind   tokenKind
+	lit    string
+	negate bool
+	ranges []runeRange
+}
+
+type runeRange struct {
+	lo, hi rune
+}
+
+// chunk is a run of tokens in a pattern, which may be preceded by a star.
+// A pattern is split into chunks the same way as by filepath.Match, and
+// they are matched in the same way, so that the results are the same.
+type chunk struct {
+	star   bool
+	tokens []token
+}
+
+// compile splits the well-formed pattern into chunks.
+func compile(pattern string) []chunk {
+	var chunks []chunk
+	for len(pattern) > 0 {
+		var c chunk
+		for len(pattern) > 0 && pattern[0] == '*' {
+			pattern = pattern[1:]
+			c.star = true
+		}
+		var lit strings.Builder
+		flush := func() {
+			if lit.Len() > 0 {
+				c.tokens = append(c.tokens, token{kind: tokLiteral, lit: lit.String()})
+				lit.Reset()
+			}
+		}
+	Tokens:
+		for len(pattern) > 0 {
+			switch pattern[0] {
+			case '*':
+				break Tokens
+			case '?':
+				flush()
+				c.tokens = append(c.tokens, token{kind: tokAny})
+				pattern = pattern[1:]
+			case '[':
+				flush()
+				var t token
+				t, pattern = compileClass(pattern[1:])
+				c.tokens = append(c.tokens, t)
+			case '\\':
+				if escapes {
+					pattern = pattern[1:]
+				}
+				fallthrough
+			default:
+				lit.WriteByte(pattern[0])
+				pattern = pattern[1:]
+			}
+		}
+		flush()
+		chunks = append(chunks, c)
+	}
+	return chunks
+}
+
+// compileClass compiles the character class at the start of pattern, which
+// follows its opening bracket, and returns the rest of the pattern.
+func compileClass(pattern string) (token, string) {
+	t := token{kind: tokClass}
+	if len(pattern) > 0 && pattern[0] == '^' {
+		t.negate = true
+		pattern = pattern[1:]
+	}
+	for n := 0; ; n++ {
+		if pattern[0] == ']' && n > 0 {
+			return t, pattern[1:]
+		}
+		var r runeRange
+		r.lo, pattern = classRune(pattern)
+		r.hi = r.lo
+		if pattern[0] == '-' {
+			r.hi, pattern = classRune(pattern[1:])
+		}
+		t.ranges = append(t.ranges, r)
+	}
+}
+
+// classRune returns the possibly escaped rune at the start of pattern
+// in a character class and the rest of the pattern.
+func classRune(pattern string) (rune, string) {
+	if pattern[0] == '\\' && escapes {
+		pattern = pattern[1:]
+	}
+	r, n := utf8.DecodeRuneInString(pattern)
+	return r, pattern[n:]
+}
+
+// matchChunks reports whether s is matched by the chunks of a pattern,
+// as filepath.Match does.
+func matchChunks(chunks []chunk, s string) bool {
+Chunks:
+	for i, c := range chunks {
+		last := i == len(chunks)-1
+		if c.star && len(c.tokens) == 0 {
+			// A trailing star matches the rest unless it has a separator.
+			return strings.IndexByte(s, filepath.Separator) < 0
+		}
+		if t, ok := matchTokens(c.tokens, s); ok && (len(t) == 0 || !last) {
+			s = t
+			continue
+		}
+		if c.star {
+			// A star cannot match a separator.
+			for j := 0; j < len(s) && s[j] != filepath.Separator; j++ {
+				t, ok := matchTokens(c.tokens, s[j+1:])
+				if ok && (len(t) == 0 || !last) {
+					s = t
+					continue Chunks
+				}
+			}
+		}
+		return false
+	}
+	return len(s) == 0
+}
+
+// matchTokens matches the tokens of a chunk against the start of s, and
+// returns the rest of s.
+func matchTokens(tokens []token, s string) (string, bool) {
+	for _, t := range tokens {
+		switch t.kind {
+		case tokLiteral:
+			if !strings.HasPrefix(s, t.lit) {
+				return "", false
+			}
+			s = s[len(t.lit):]
+		case tokAny:
+			if len(s) == 0 || s[0] == filepath.Separator {
+				return "", false
+			}
+			_, n := utf8.DecodeRuneInString(s)
+			s = s[n:]
+		case tokClass:
+			if len(s) == 0 {
+				return "", false
+			}
+			r, n := utf8.DecodeRuneInString(s)
+			s = s[n:]
+			match := false
+			for _, rg := range t.ranges {
+				if rg.lo <= r && r <= rg.hi {
+					match = true
+					break
+				}
+			}
+			if match == t.negate {
+				return "", false
+			}
+		}
+	}
+	return s, true
+}
+
+// suffix returns the literal that every string matched by the chunks must
+// end with, or "" if there is none.
+func suffix(chunks []chunk) string {
+	if len(chunks) == 0 {
+		return ""
+	}
+	tokens := chunks[len(chunks)-1].tokens
+	if len(tokens) == 0 || tokens[len(tokens)-1].kind != tokLiteral {
+		return ""
+	}
+	return tokens[len(tokens)-1].lit
+}
@@ -0,0 +1,365 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package glob provides the shell patterns used by package matcher, without
+// any of the semantics of rule files. A pattern is matched against a whole
+// string, so it can be used to match names in routing, filtering, or tests.
+//
+// The pattern syntax is as defined in filepath.Match:
+//
+//  pattern:
+//      { term }
+//  term:
+//      '*'         matches any sequence of non-Separator characters
+//      '?'         matches any single non-Separator character
+//      '[' [ '^' ] { character-range } ']'
+//                  character class (must be non-empty)
+//      c           matches character c (c != '*', '?', '\\', '[')
+//      '\\' c      matches character c
+//
+//  character-range:
+//      c           matches character c (c != '\\', '-', ']')
+//      '\\' c      matches character c
+//      lo '-' hi   matches character c for lo <= c <= hi
+//
+// Unlike filepath.Match, which may or may not report a malformed pattern
+// depending on the string it is matched against, patterns are validated
+// completely when they are compiled.
+package glob
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The error variables are returned by Check and Compile in an Error.
+var (
+	ErrUnexpectedRune     = errors.New("unexpected rune")
+	ErrNegativeRange      = errors.New("negative range")
+	ErrDualStar           = errors.New("dual stars not supported")
+	ErrEmptyClass         = errors.New("character class empty")
+	ErrEmptyGlob          = errors.New("glob empty")
+	ErrIncompleteClass    = errors.New("character class incomplete")
+	ErrTrailingEscape     = errors.New("trailing escape character")
+	ErrTrailingWhitespace = errors.New("trailing whitespace")
+)
+
+// Error describes a malformed pattern. Err is one of the error variables
+// of this package, and Column is the index of the rune in the pattern
+// at which the problem was detected.
+type Error struct {
+	Err    error
+	Column int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Err)
+}
+
+// Glob is a compiled pattern. It is safe for concurrent use.
+type Glob struct {
+	pattern string
+
+	// literal is the string matched by the pattern if it contains
+	// no wildcards, in which case it is matched by comparison.
+	literal string
+	isLit   bool
+
+	// chunks are the compiled pattern, and every matched string must
+	// start with prefix and end with suffix.
+	chunks []chunk
+	prefix string
+	suffix string
+}
+
+// Compile checks pattern and returns a Glob for it. The only possible
+// returned error is *Error.
+func Compile(pattern string) (*Glob, error) {
+	if err := Check(pattern); err != nil {
+		return nil, err
+	}
+	g := &Glob{pattern: pattern}
+	g.literal, g.isLit = literal(pattern)
+	if !g.isLit {
+		g.chunks = compile(pattern)
+		if c := g.chunks[0]; !c.star && c.tokens[0].kind == tokLiteral {
+			g.prefix = c.tokens[0].lit
+		}
+		g.suffix = suffix(g.chunks)
+	}
+	return g, nil
+}
+
+// MustCompile is like Compile but panics if the pattern is malformed.
+func MustCompile(pattern string) *Glob {
+	g, err := Compile(pattern)
+	if err != nil {
+		panic("glob: Compile(" + pattern + "): " + err.Error())
+	}
+	return g
+}
+
+// String returns the source pattern of g.
+func (g *Glob) String() string {
+	return g.pattern
+}
+
+// Literal returns the string that g matches if its pattern contains no
+// wildcards, i.e. the pattern with escapes removed, and whether it does.
+func (g *Glob) Literal() (string, bool) {
+	return g.literal, g.isLit
+}
+
+// Chunk is a part of a compiled pattern: a run of tokens that match at fixed
+// positions, which may be preceded by a star. The pattern "*.tar.gz" is one
+// chunk with a star and a literal token, and "a?c*" is a chunk without a star
+// followed by an empty chunk with one.
+type Chunk struct {
+	Star   bool
+	Tokens []Token
+}
+
+// TokenKind is the kind of a Token.
+type TokenKind int
+
+const (
+	// TokenLiteral matches Literal.
+	TokenLiteral TokenKind = iota
+
+	// TokenAny matches any single character but the separator, as '?' does.
+	TokenAny
+
+	// TokenClass matches any single character in one of Ranges, or if
+	// Negate is set, in none of them.
+	TokenClass
+)
+
+// Token is a part of a Chunk that matches at a fixed position.
+type Token struct {
+	Kind    TokenKind
+	Literal string
+	Negate  bool
+	Ranges  []Range
+}
+
+// Range is a range of characters in a class, including Lo and Hi.
+type Range struct {
+	Lo, Hi rune
+}
+
+// Chunks returns the compiled form of g, with escapes resolved, for tools
+// that generate code matching the pattern, such as cmd/matchergen of package
+// matcher. A string is matched by the chunks as by filepath.Match: a star
+// matches any run of characters but the separator, the tokens of the last
+// chunk must match at the end of the string, and those of every other chunk
+// match at the first position where they do, without backtracking.
+func (g *Glob) Chunks() []Chunk {
+	chunks := g.chunks
+	if g.isLit {
+		chunks = compile(g.pattern)
+	}
+	out := make([]Chunk, len(chunks))
+	for i, c := range chunks {
+		out[i].Star = c.star
+		for _, t := range c.tokens {
+			u := Token{Kind: TokenKind(t.kind), Literal: t.lit, Negate: t.negate}
+			for _, r := range t.ranges {
+				u.Ranges = append(u.Ranges, Range{r.lo, r.hi})
+			}
+			out[i].Tokens = append(out[i].Tokens, u)
+		}
+	}
+	return out
+}
+
+// Match reports whether b is matched by g.
+func (g *Glob) Match(b []byte) bool {
+	return g.MatchString(string(b))
+}
+
+// MatchString reports whether s is matched by g. The result is the same
+// as that of filepath.Match, but the pattern is not parsed again, and
+// strings that do not start or end with the literal parts of the pattern
+// are rejected right away.
+func (g *Glob) MatchString(s string) bool {
+	if g.isLit {
+		return s == g.literal
+	}
+	if !strings.HasPrefix(s, g.prefix) || !strings.HasSuffix(s, g.suffix) {
+		return false
+	}
+	return matchChunks(g.chunks, s)
+}
+
+// QuoteMeta returns a pattern that matches the literal string s, by escaping
+// all characters that have a special meaning in patterns.
+func QuoteMeta(s string) string {
+	if !strings.ContainsAny(s, "*?[\\") {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// literal returns the string that pattern matches if it has no wildcards.
+func literal(pattern string) (string, bool) {
+	if !strings.ContainsAny(pattern, "*?[\\") {
+		return pattern, true
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+			continue
+		case r == '*' || r == '?' || r == '[':
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), true
+}
+
+// Check returns nil when the pattern is well-formed, and otherwise an *Error
+// describing the first problem. Whitespace at the end of a pattern must be
+// escaped, and "**" is not supported.
+func Check(pattern string) error {
+	type State int
+	const (
+		Initial State = iota
+		Regular
+		ClassBegin
+		ClassMiddle
+		ClassRange
+		ClassRequire
+		Star
+		DualStar
+		Escape
+		Whitespace
+	)
+
+	column := -1
+	give := func(e error) error {
+		return &Error{
+			Err:    e,
+			Column: column,
+		}
+	}
+
+	var last rune
+	var state State
+	var next State
+	var caret bool
+	// regular returns the state after r outside of a character class.
+	regular := func(r rune) State {
+		switch r {
+		case '[':
+			caret = false
+			return ClassBegin
+		case '*':
+			return Star
+		case '\\':
+			next = Regular
+			return Escape
+		case ' ', '\t', '\n': // find out if this is the end
+			return Whitespace
+		default:
+			return Regular
+		}
+	}
+	for _, r := range pattern {
+		column++
+		switch state {
+		case Initial, Regular:
+			state = regular(r)
+		case ClassBegin:
+			switch r {
+			case '^':
+				if !caret {
+					caret = true
+					break
+				}
+				last = r
+				state = ClassMiddle
+			case ']':
+				return give(ErrEmptyClass)
+			case '-':
+				return give(ErrUnexpectedRune)
+			case '\\':
+				state = Escape
+				next = ClassRequire
+			default:
+				last = r
+				state = ClassMiddle
+			}
+		case ClassRequire:
+			if r == '-' {
+				return give(ErrUnexpectedRune)
+			}
+			state = ClassMiddle
+			fallthrough
+		case ClassMiddle:
+			switch r {
+			case '\\':
+				state = Escape
+				next = ClassMiddle
+			case ']':
+				state = Regular
+			case '-':
+				state = ClassRange
+			default:
+				last = r
+			}
+		case ClassRange:
+			switch r {
+			// TODO: following case may be unnecessary
+			case '-', '\\', ']':
+				return give(ErrUnexpectedRune)
+			default:
+				if r-last < 0 {
+					return give(ErrNegativeRange)
+				}
+				state = ClassRequire
+			}
+		case Escape:
+			state = next
+		case Star:
+			if r == '*' {
+				state = DualStar
+			} else {
+				state = regular(r)
+			}
+		case DualStar:
+			return give(ErrDualStar)
+		case Whitespace:
+			state = regular(r)
+		}
+	}
+
+	switch state {
+	case Initial:
+		return give(ErrEmptyGlob)
+	case ClassBegin, ClassMiddle, ClassRange, ClassRequire:
+		return give(ErrIncompleteClass)
+	case Escape:
+		return give(ErrTrailingEscape)
+	case DualStar:
+		return give(ErrDualStar)
+	case Whitespace:
+		return give(ErrTrailingWhitespace)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package glob
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGlob(fw *testing.T) {
+	tests := map[[2]string]bool{
+		{"foo", "foo"}:         true,
+		{"foo", "bar/foo"}:     false,
+		{"*.go", "main.go"}:    true,
+		{"*.go", "cmd/x.go"}:   false,
+		{"*/*.go", "cmd/x.go"}: true,
+		{"[a-c]?", "bx"}:       true,
+		{"[a-c]?", "dx"}:       false,
+		{"\\*", "*"}:           true,
+		{"\\*", "x"}:           false,
+		{"a\\ ", "a "}:         true,
+	}
+	for k, v := range tests {
+		g, err := Compile(k[0])
+		if err != nil {
+			fw.Errorf("Compile(%q) failed: %s", k[0], err)
+			continue
+		}
+		if m := g.MatchString(k[1]); m != v {
+			fw.Errorf("Compile(%q).MatchString(%q) = %v, expected %v", k[0], k[1], m, v)
+		}
+		if m := g.Match([]byte(k[1])); m != v {
+			fw.Errorf("Compile(%q).Match(%q) = %v, expected %v", k[0], k[1], m, v)
+		}
+		if g.String() != k[0] {
+			fw.Errorf("Compile(%q).String() = %q", k[0], g.String())
+		}
+	}
+}
+
+func TestCompileError(fw *testing.T) {
+	tests := map[string]Error{
+		"":     {ErrEmptyGlob, -1},
+		"a[":   {ErrIncompleteClass, 1},
+		"a**b": {ErrDualStar, 3},
+		"a**":  {ErrDualStar, 2},
+		"[z-a": {ErrNegativeRange, 3},
+		"a ":   {ErrTrailingWhitespace, 1},
+		"a\\":  {ErrTrailingEscape, 1},
+		"*[":   {ErrIncompleteClass, 1},
+		"a [":  {ErrIncompleteClass, 2},
+		"[^]":  {ErrEmptyClass, 2},
+		"[\\^": {ErrIncompleteClass, 2},
+	}
+	for k, v := range tests {
+		_, err := Compile(k)
+		e, ok := err.(*Error)
+		if !ok || *e != v {
+			fw.Errorf("Compile(%q) = %v, expected %v", k, err, &v)
+		}
+	}
+}
+
+func TestMustCompile(fw *testing.T) {
+	defer func() {
+		if recover() == nil {
+			fw.Errorf("MustCompile of malformed pattern did not panic")
+		}
+	}()
+	MustCompile("[")
+}
+
+func TestQuoteMeta(fw *testing.T) {
+	for _, s := range []string{"plain", "a*b", "[x]", "what?", "back\\slash", "**"} {
+		g := MustCompile(QuoteMeta(s))
+		if !g.MatchString(s) {
+			fw.Errorf("QuoteMeta(%q) = %q does not match itself", s, g)
+		}
+		if lit, ok := g.Literal(); !ok || lit != s {
+			fw.Errorf("Literal() of QuoteMeta(%q) = (%q, %v), expected (%q, true)", s, lit, ok, s)
+		}
+	}
+}
+
+func TestChunks(fw *testing.T) {
+	tests := map[string][]Chunk{
+		"*.tar.gz": {{Star: true, Tokens: []Token{{Kind: TokenLiteral, Literal: ".tar.gz"}}}},
+		"a?c*": {
+			{Tokens: []Token{{Kind: TokenLiteral, Literal: "a"}, {Kind: TokenAny}, {Kind: TokenLiteral, Literal: "c"}}},
+			{Star: true},
+		},
+		"[^a-c\\]]x": {{Tokens: []Token{
+			{Kind: TokenClass, Negate: true, Ranges: []Range{{'a', 'c'}, {']', ']'}}},
+			{Kind: TokenLiteral, Literal: "x"},
+		}}},
+		"a\\*b": {{Tokens: []Token{{Kind: TokenLiteral, Literal: "a*b"}}}},
+	}
+	for k, v := range tests {
+		if c := MustCompile(k).Chunks(); !reflect.DeepEqual(c, v) {
+			fw.Errorf("Chunks() of %q = %+v, expected %+v", k, c, v)
+		}
+	}
+}
+
+// TestFilepathMatch checks that compiled globs match exactly what
+// filepath.Match matches.
+func TestFilepathMatch(fw *testing.T) {
+	patterns := []string{
+		"*", "*.go", "a*", "a*b", "*a*", "a*b*c", "*/*", "a/*", "?", "??",
+		"a?c", "[abc]", "[^abc]", "[a-c]*", "*[0-9]", "[\\]]", "[^/]", "?/?",
+		"\\*a", "a\\?*", "*.tar.gz", "é?", "[é-ü]x", "x*y*", "*x*x*x",
+	}
+	strs := []string{
+		"", "a", "b", "ab", "abc", "aXbYc", "a/b", "a/bc", "main.go", "go",
+		"x.go/y", "*a", "a?z", "]", "/", "é1", "éé", "üx", "ax", "xyxy",
+		"xxx", "xaxbx", "foo.tar.gz", "a.tar.gz.bak", "9", "a9",
+	}
+	for _, p := range patterns {
+		g := MustCompile(p)
+		for _, s := range strs {
+			e, _ := filepath.Match(p, s)
+			if m := g.MatchString(s); m != e {
+				fw.Errorf("Compile(%q).MatchString(%q) = %v, filepath.Match = %v", p, s, m, e)
+			}
+		}
+	}
+}
+
+var benchNames = []string{"main.go", "README.md", "glob_test.go", "Makefile", "a.out", "x.tar.gz"}
+
+func BenchmarkMatchString(b *testing.B) {
+	patterns := []string{"*.go", "*_test.go", "[A-Z]*", "*.tar.?z", "a.*"}
+	b.Run("filepath", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, p := range patterns {
+				filepath.Match(p, benchNames[i%len(benchNames)])
+			}
+		}
+	})
+	b.Run("compiled", func(b *testing.B) {
+		gs := make([]*Glob, len(patterns))
+		for i, p := range patterns {
+			gs[i] = MustCompile(p)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, g := range gs {
+				g.MatchString(benchNames[i%len(benchNames)])
+			}
+		}
+	})
+}
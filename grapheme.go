@@ -0,0 +1,104 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	zwj = '\u200D'
+
+	// firstPlaceholder is the first rune used by clusterMap to stand for
+	// a grapheme cluster. It is in a private use area, so it does not occur
+	// in real filenames.
+	firstPlaceholder = '\U000F0000'
+)
+
+// clusterMap replaces grapheme clusters consisting of several runes by
+// single placeholder runes, so that patterns treat them as one
+// character. The same cluster is always replaced by the same placeholder.
+type clusterMap struct {
+	m    map[string]rune
+	next rune
+}
+
+// graphemes returns pattern and s with their grapheme clusters replaced
+// consistently by placeholders.
+func graphemes(pattern, s string) (string, string) {
+	c := clusterMap{next: firstPlaceholder}
+	return c.replace(pattern, true), c.replace(s, false)
+}
+
+// replace replaces the clusters in s. If s is a pattern, clusters that start
+// with a special character of patterns or a separator are left alone.
+func (c *clusterMap) replace(s string, pattern bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		n := clusterLen(s[i:])
+		if n == size || r == '/' || (pattern && strings.ContainsRune(`*?[]\-^`, r)) {
+			b.WriteString(s[i : i+size])
+			i += size
+			continue
+		}
+
+		cl := s[i : i+n]
+		p, ok := c.m[cl]
+		if !ok {
+			if c.m == nil {
+				c.m = make(map[string]rune)
+			}
+			p = c.next
+			c.m[cl] = p
+			c.next++
+		}
+		b.WriteRune(p)
+		i += n
+	}
+	return b.String()
+}
+
+// clusterLen returns the length in bytes of the grapheme cluster at the
+// start of s. It implements the rules of Unicode Standard Annex #29 that
+// matter for filenames: combining marks and other extending characters,
+// emoji modifiers and zero-width-joiner sequences, and flags made of
+// regional indicators. Hangul syllables made of conjoining jamo are not
+// joined, since filenames normally use precomposed syllables.
+func clusterLen(s string) int {
+	r, n := utf8.DecodeRuneInString(s)
+	if r == '/' {
+		return n
+	}
+	regional := isRegional(r)
+	prev := r
+	for n < len(s) {
+		next, size := utf8.DecodeRuneInString(s[n:])
+		switch {
+		case isExtend(next):
+		case prev == zwj && next >= utf8.RuneSelf && next != '/':
+		case regional && isRegional(next):
+		default:
+			return n
+		}
+		regional = false
+		prev = next
+		n += size
+	}
+	return n
+}
+
+func isExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == zwj ||
+		(0x1F3FB <= r && r <= 0x1F3FF) || // emoji modifiers
+		(0xE0020 <= r && r <= 0xE007F) // tags
+}
+
+func isRegional(r rune) bool {
+	return 0x1F1E6 <= r && r <= 0x1F1FF
+}
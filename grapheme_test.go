@@ -0,0 +1,49 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import "testing"
+
+func TestClusterLen(fw *testing.T) {
+	tests := map[string]int{
+		"abc":                   1,
+		"e\u0301x":              3,
+		"\U0001F44D\U0001F3FDx": 8,
+		"\U0001F1E9\U0001F1EA\U0001F1EB\U0001F1F7":   8,
+		"\U0001F468\u200D\U0001F469\u200D\U0001F467": 18,
+		"/\u0301": 1,
+		"":        0,
+	}
+	for k, v := range tests {
+		if n := clusterLen(k); n != v {
+			fw.Errorf("clusterLen(%q) = %d, expected %d", k, n, v)
+		}
+	}
+}
+
+func TestGraphemes(fw *testing.T) {
+	tests := map[[2]string][2]bool{
+		// runes, graphemes
+		{"caf?", "cafe\u0301"}:                              {false, true},
+		{"caf?", "caf\u00E9"}:                               {true, true},
+		{"cafe\u0301", "cafe\u0301"}:                        {true, true},
+		{"caf\u00E9", "cafe\u0301"}:                         {false, false},
+		{"?.txt", "\U0001F44D\U0001F3FD.txt"}:               {false, true},
+		{"??", "\U0001F1E9\U0001F1EA\U0001F1EB\U0001F1F7"}:  {false, true},
+		{"?", "\U0001F468\u200D\U0001F469\u200D\U0001F467"}: {false, true},
+		{"[e]\u0301", "e\u0301"}:                            {true, false},
+		{"*\u0301", "e\u0301"}:                              {true, false},
+		{"dir/?", "dir/a\u0308"}:                            {false, true},
+		{"???", "abc"}:                                      {true, true},
+	}
+	for k, v := range tests {
+		for i, g := range []bool{false, true} {
+			m := &Matcher{Graphemes: g}
+			if ok := m.match(k[0], k[1]); ok != v[i] {
+				fw.Errorf("Graphemes=%v: match(%q, %q) = %v, expected %v", g, k[0], k[1], ok, v[i])
+			}
+		}
+	}
+}
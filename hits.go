@@ -0,0 +1,138 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// numStripes is the number of stripes of a hitCounter.
+const numStripes = 16
+
+// hitCounter counts how often a rule decided a match. The count is spread
+// over stripes that are padded to separate cache lines, so that goroutines
+// deciding different paths rarely write to the same memory.
+type hitCounter [numStripes]struct {
+	n int64
+	_ [56]byte
+}
+
+func (c *hitCounter) sum() int {
+	var n int64
+	for i := range c {
+		n += atomic.LoadInt64(&c[i].n)
+	}
+	return int(n)
+}
+
+// hitCounters holds the hit counters of the rules of a Worker. It is safe
+// for concurrent use. Counters are looked up in a read-only map without
+// locking; new ones are added to a copy of it, which replaces it once it
+// has been missed about as often as it has entries, as in sync.Map, but
+// without boxing every rule to look it up.
+type hitCounters struct {
+	read atomic.Value // map[Rule]*hitCounter
+
+	mu     sync.Mutex
+	dirty  map[Rule]*hitCounter
+	misses int
+}
+
+// add counts a hit of r while deciding path, which chooses the stripe.
+func (h *hitCounters) add(r Rule, path string) {
+	c := h.lookup(r)
+	if c == nil {
+		c = h.create(r)
+	}
+	atomic.AddInt64(&c[stripe(path)].n, 1)
+}
+
+// lookup returns the counter of r, or nil if it has not been hit yet.
+func (h *hitCounters) lookup(r Rule) *hitCounter {
+	read, _ := h.read.Load().(map[Rule]*hitCounter)
+	if c, ok := read[r]; ok {
+		return c
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := h.dirty[r]
+	if c == nil {
+		read, _ = h.read.Load().(map[Rule]*hitCounter)
+		return read[r]
+	}
+	h.missLocked()
+	return c
+}
+
+// create returns the counter of r, adding it if necessary.
+func (h *hitCounters) create(r Rule) *hitCounter {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	read, _ := h.read.Load().(map[Rule]*hitCounter)
+	if c, ok := read[r]; ok {
+		return c
+	}
+	if h.dirty == nil {
+		h.dirty = make(map[Rule]*hitCounter, len(read)+1)
+		for k, v := range read {
+			h.dirty[k] = v
+		}
+	}
+	c, ok := h.dirty[r]
+	if !ok {
+		c = new(hitCounter)
+		h.dirty[r] = c
+	}
+	h.missLocked()
+	return c
+}
+
+// missLocked counts a lookup that had to lock, and replaces the read-only
+// map with the dirty one once there have been as many as it has entries.
+// h.mu must be held, and h.dirty must not be nil.
+func (h *hitCounters) missLocked() {
+	if h.misses++; h.misses >= len(h.dirty) {
+		h.read.Store(h.dirty)
+		h.dirty, h.misses = nil, 0
+	}
+}
+
+// get returns the number of hits of r.
+func (h *hitCounters) get(r Rule) int {
+	if c := h.lookup(r); c != nil {
+		return c.sum()
+	}
+	return 0
+}
+
+// clone returns a copy of the counters.
+func (h *hitCounters) clone() *hitCounters {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	m := h.dirty
+	if m == nil {
+		m, _ = h.read.Load().(map[Rule]*hitCounter)
+	}
+	n := make(map[Rule]*hitCounter, len(m))
+	for r, c := range m {
+		d := new(hitCounter)
+		d[0].n = int64(c.sum())
+		n[r] = d
+	}
+	c := new(hitCounters)
+	c.read.Store(n)
+	return c
+}
+
+// stripe returns the stripe of a hitCounter for path, using FNV-1a.
+func stripe(path string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(path); i++ {
+		h ^= uint32(path[i])
+		h *= 16777619
+	}
+	return int(h % numStripes)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHitsConcurrent(fw *testing.T) {
+	w := testWorker(fw)
+	w.Add("*.o")
+	var r Rule
+	for _, x := range w.Rules() {
+		if x.Glob == "*.o" {
+			r = x
+		}
+	}
+
+	const goroutines, n = 8, 1000
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				w.Matches(fmt.Sprintf("dir%d/file%d.o", i, j))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if h := w.Hits(r); h != goroutines*n {
+		fw.Errorf("w.Hits(%v) = %d, expected %d", r, h, goroutines*n)
+	}
+	if h := w.Clone().Hits(r); h != goroutines*n {
+		fw.Errorf("w.Clone().Hits(%v) = %d, expected %d", r, h, goroutines*n)
+	}
+}
+
+func TestHitsPromoted(fw *testing.T) {
+	h := new(hitCounters)
+	rules := []Rule{{Glob: "*.o"}, {Glob: "*.a"}, {Glob: "*.so"}, {Glob: "*.tmp"}}
+	for i := 0; i < 1000; i++ {
+		for _, r := range rules {
+			h.add(r, "file")
+		}
+	}
+	read, _ := h.read.Load().(map[Rule]*hitCounter)
+	if len(read) != len(rules) {
+		fw.Errorf("read-only map has %d of %d rules", len(read), len(rules))
+	}
+	for _, r := range rules {
+		if n := h.get(r); n != 1000 {
+			fw.Errorf("h.get(%v) = %d, expected 1000", r, n)
+		}
+	}
+}
+
+var benchPaths = func() []string {
+	paths := make([]string, 64)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("src/pkg%d/file%d.%c", i%8, i, "co"[i%2])
+	}
+	return paths
+}()
+
+// BenchmarkMatchesParallel compares matching on a Worker shared by many
+// goroutines with and without counting hits, which should add less than
+// five percent. Half of the paths are matched by a rule, and each goroutine
+// starts at a different path, as it would when walking another directory.
+func BenchmarkMatchesParallel(b *testing.B) {
+	for _, counting := range []bool{false, true} {
+		name := "baseline"
+		if counting {
+			name = "counting"
+		}
+		b.Run(name, func(b *testing.B) {
+			m := New("match.conf")
+			m.Add("match.conf", "*.o")
+			w, err := m.NewWorker("tests")
+			if err != nil {
+				b.Fatal(err)
+			}
+			if !counting {
+				w.hits = nil
+			}
+			var start int64
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(atomic.AddInt64(&start, 7))
+				for pb.Next() {
+					w.Matches(benchPaths[i%len(benchPaths)])
+					i++
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkHitParallel counts hits of several rules, which must all end up
+// in the read-only map so that counting does not lock.
+func BenchmarkHitParallel(b *testing.B) {
+	h := new(hitCounters)
+	rules := []Rule{{Glob: "*.o"}, {Glob: "*.a"}, {Glob: "*.so"}, {Glob: "*.tmp"}}
+	var start int64
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(atomic.AddInt64(&start, 7))
+		for pb.Next() {
+			h.add(rules[i%len(rules)], benchPaths[i%len(benchPaths)])
+			i++
+		}
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Includer reads the rules of a Worker as an allowlist, as returned by
+// Including, for tools that only process the files matching a set of
+// patterns rather than skipping them. The rules are read, discovered, and
+// decided on as by the Worker; an Includer only differs in that a path is
+// also matched if a directory containing it is, so that "src/" includes
+// everything beneath src, and "!src/gen/" or "!*.tmp" carve out exceptions.
+type Includer struct {
+	w *Worker
+}
+
+// Including returns an Includer for the rules of w. The Worker can still
+// be used, and changes to its rules apply to the Includer.
+func Including(w *Worker) *Includer {
+	return &Includer{w: w}
+}
+
+// Worker returns the Worker whose rules the Includer reads.
+func (in *Includer) Worker() *Worker {
+	return in.w
+}
+
+// Matches reports whether path is included. The rule or layer that decides
+// on path itself, as for Worker.Matches, takes precedence; if there is none,
+// the decision on its parent directory counts, and so on up to the root of
+// the Worker, not including the root itself. If nothing decides, path is not
+// included, regardless of Matcher.DenyByDefault.
+func (in *Includer) Matches(path string) bool {
+	return in.MatchesInfo(path, nil)
+}
+
+// MatchesInfo is like Matches, but rules with predicates are evaluated
+// against fi instead of the result of os.Lstat on the path. Its parent
+// directories are always passed to os.Lstat if a predicate needs it.
+// The audit log of the Worker only records the verdict on path.
+func (in *Includer) MatchesInfo(path string, fi os.FileInfo) bool {
+	f := in.w.file(path, fi)
+	if f == nil {
+		return false
+	}
+	path = f.path
+	prefix := dirPrefix(in.w.workspace())
+	for {
+		res, ok := in.w.decideRules(f)
+		if res.Layer != nil || res.Rule.Glob != "" {
+			if in.w.audit != nil {
+				in.w.record(path, res, ok)
+			}
+			return ok
+		}
+		dir := filepath.Dir(f.path)
+		if len(dir) <= len(prefix) || !strings.HasPrefix(dir, prefix) {
+			if in.w.audit != nil {
+				in.w.record(path, MatchResult{}, false)
+			}
+			return false
+		}
+		f = &file{path: dir, dir: true}
+	}
+}
+
+// List returns the names of all files beneath root that are included,
+// formed as by Worker.Glob. Since a rule may include files beneath any
+// directory, every directory is descended into.
+func (in *Includer) List(root string) ([]string, error) {
+	absRoot := in.w.abs(root)
+	var names []string
+	err := filepath.Walk(absRoot, func(abs string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || !in.MatchesInfo(abs, fi) {
+			return nil
+		}
+		rel, err := filepath.Rel(absRoot, abs)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.Join(root, rel))
+		return nil
+	})
+	return names, err
+}
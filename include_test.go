@@ -0,0 +1,100 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIncluder(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"src/gen", "src/sub", "docs"} {
+		os.MkdirAll(filepath.Join(dir, d), 0755)
+	}
+	for _, f := range []string{"src/a.go", "src/a.tmp", "src/gen/x.go", "src/gen/keep.go", "src/sub/b.go", "docs/README", "LICENSE"} {
+		ioutil.WriteFile(filepath.Join(dir, f), nil, 0644)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "match.conf"), []byte("src/\n!src/gen/\nsrc/gen/keep.go\n!*.tmp\nLICENSE\n"), 0644)
+
+	w, err := New("match.conf").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	in := Including(w)
+
+	tests := map[string]bool{
+		"src":             true,
+		"src/a.go":        true,
+		"src/sub/b.go":    true,
+		"src/new/c.go":    true,
+		"src/a.tmp":       false,
+		"src/gen":         false,
+		"src/gen/x.go":    false,
+		"src/gen/keep.go": true,
+		"docs/README":     false,
+		"LICENSE":         true,
+		"":                false,
+	}
+	for k, v := range tests {
+		if r := in.Matches(k); r != v {
+			fw.Errorf("in.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+
+	var buf bytes.Buffer
+	w.SetAudit(&buf)
+	in.Matches("src/sub/b.go")
+	w.SetAudit(nil)
+	var rec AuditRecord
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		fw.Errorf("in.Matches() wrote %q to the audit log, expected a single record: %s", buf.String(), err)
+	} else if rec.Path != filepath.Join(dir, "src/sub/b.go") || !rec.Matched || rec.Pattern != "src/" {
+		fw.Errorf("in.Matches() wrote audit record %v, expected the verdict on src/sub/b.go", rec)
+	}
+
+	names, err := in.List(".")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	expected := []string{"LICENSE", "src/a.go", "src/gen/keep.go", "src/sub/b.go"}
+	if !reflect.DeepEqual(names, expected) {
+		fw.Errorf("in.List() = %q, expected %q", names, expected)
+	}
+
+	// Directories above the root of the Worker are not consulted.
+	sub, err := New("match.conf").NewWorker(filepath.Join(dir, "src"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	sub.Reset()
+	sub.Add("src")
+	if Including(sub).Matches("a.go") {
+		fw.Error("the Includer consulted the decision on the root of the Worker")
+	}
+
+	// Directories are consulted if the root of the Worker is /.
+	root, err := New("").NewWorker("/")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	root.Reset()
+	root.Add("src")
+	if !Including(root).Matches(filepath.Join(dir, "src/a.go")) {
+		fw.Error("the Includer did not consult the directories of a path beneath /")
+	}
+	if Including(root).Matches(filepath.Join(dir, "docs/README")) {
+		fw.Error("the Includer matched a path beneath / that no rule includes")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+// Layer is a complete rule set that can be attached to a Worker with
+// AddMatcher. Both *Matcher and *Worker are layers, which makes it possible
+// to compose, for example, built-in rules, organization rules, and project
+// rules into a single Worker.
+//
+// The path passed to Matches is always absolute.
+type Layer interface {
+	Matches(path string) bool
+}
+
+// Precedence determines where AddMatcher places a layer with respect to
+// the globs of the Worker itself and previously added layers.
+type Precedence int
+
+const (
+	// Lowest places the layer below everything else in the Worker,
+	// so it is consulted last.
+	Lowest Precedence = iota
+
+	// Highest places the layer above everything else in the Worker,
+	// so it is consulted first.
+	Highest
+)
+
+// AddMatcher attaches the layer l to the Worker with the precedence p.
+// The layer is consulted by Matches along with the globs of the Worker.
+//
+// Layers are not copied, so globs later added to l take effect in w as well.
+// A Worker must not be added to itself, directly or indirectly.
+func (w *Worker) AddMatcher(l Layer, p Precedence) {
+	switch p {
+	case Highest:
+		w.above = append([]Layer{l}, w.above...)
+	default:
+		w.below = append(w.below, l)
+	}
+	w.invalidate()
+}
+
+// Overlay is a layer of patterns that sits above everything else in
+// a Worker, as returned by Worker.Overlay.
+type Overlay struct {
+	w     *Worker
+	rules []Rule
+}
+
+// Overlay adds a layer with the highest precedence to the Worker that
+// contains the given patterns, such as those passed with --exclude flags on
+// the command line. The patterns are read like lines of a rule file in the
+// working directory of the Worker. The overlay can later be removed again
+// without touching the rules loaded from configuration files.
+//
+// The only possible error is a BadPatternError for an invalid pattern,
+// in which case the Worker is not changed.
+func (w *Worker) Overlay(patterns ...string) (*Overlay, error) {
+	o := &Overlay{w: w}
+	p := newParser(DialectNative)
+	for _, s := range patterns {
+		rules, err := w.line(p, s, w.cwd)
+		if err != nil {
+			return nil, err
+		}
+		o.rules = append(o.rules, rules...)
+	}
+	w.AddMatcher(o, Highest)
+	return o, nil
+}
+
+// Matches returns true if any of the patterns of the overlay matches path,
+// and the last of them that does is not negated. Since the overlay is
+// a layer, a negated pattern cannot exclude path from rules beneath it.
+func (o *Overlay) Matches(path string) bool {
+	r, ok := matchFirst(o.rules, &file{path: path}, o.w.m)
+	return ok && !r.Negate
+}
+
+// Rules returns the rules of the overlay.
+func (o *Overlay) Rules() []Rule {
+	return append([]Rule(nil), o.rules...)
+}
+
+// Remove removes the overlay from the Worker that it was created for.
+// Clones of the Worker made in the meantime keep it.
+func (o *Overlay) Remove() {
+	w := o.w
+	for i, l := range w.above {
+		if l == Layer(o) {
+			w.above = append(w.above[:i:i], w.above[i+1:]...)
+			w.invalidate()
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import "testing"
+
+func TestAddMatcher(fw *testing.T) {
+	builtin := New("")
+	builtin.Add("*.o")
+
+	org, err := New("").NewWorker("/org")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	org.Add("*.bak")
+
+	w, err := New("").NewWorker("tests")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.tmp")
+	w.AddMatcher(builtin, Lowest)
+	w.AddMatcher(org, Highest)
+
+	tests := map[string]bool{
+		"main.o":    true,
+		"main.bak":  true,
+		"main.tmp":  true,
+		"main.go":   false,
+		"sub/x.bak": true,
+	}
+	for k, v := range tests {
+		if m := w.Matches(k); m != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, m, v)
+		}
+	}
+
+	// Layers are shared, not copied.
+	builtin.Add("*.a")
+	if !w.Matches("lib.a") {
+		fw.Errorf("w.Matches(%q) = false after adding glob to layer", "lib.a")
+	}
+}
+
+func TestOverlay(fw *testing.T) {
+	w := testWorker(fw)
+	n := len(w.Rules())
+	if w.Matches("main.go") {
+		fw.Fatal("w.Matches(main.go) before overlay")
+	}
+
+	o, err := w.Overlay("*.go", "", "# comment", "size:>1G")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	c := w.Clone()
+	if !w.Matches("main.go") || !c.Matches("main.go") {
+		fw.Errorf("w.Matches(main.go) = false with overlay")
+	}
+	if len(w.Rules()) != n || len(o.Rules()) != 2 {
+		fw.Errorf("overlay changed the rules of the worker")
+	}
+
+	o.Remove()
+	if w.Matches("main.go") {
+		fw.Errorf("w.Matches(main.go) = true after removing the overlay")
+	}
+	if !c.Matches("main.go") {
+		fw.Errorf("removing the overlay changed a clone")
+	}
+	o.Remove()
+
+	if _, err := w.Overlay("*.c", "a["); err == nil {
+		fw.Errorf("w.Overlay with invalid pattern succeeded")
+	}
+	if w.Matches("main.c") {
+		fw.Errorf("failed w.Overlay changed the worker")
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Severity is the severity of a Diagnostic.
+type Severity string
+
+const (
+	// SeverityError is for lines that make loading the rule file fail.
+	SeverityError Severity = "error"
+
+	// SeverityWarning is for lines that are valid but probably mistaken.
+	SeverityWarning Severity = "warning"
+)
+
+// Check identifiers of diagnostics reported by Lint.
+const (
+	LintBadPattern = "bad-pattern"
+	LintDuplicate  = "duplicate"
+	LintExpired    = "expired"
+)
+
+var lintDescriptions = map[string]string{
+	LintBadPattern: "The line cannot be parsed, so loading the rule file fails.",
+	LintDuplicate:  "The rule is the same as an earlier rule in the file and has no effect.",
+	LintExpired:    "The date of the expires predicate of the rule has passed, so it is ignored.",
+}
+
+// Diagnostic is a problem in a rule file found by Lint. It is encoded to JSON
+// with the field names given in the tags. Column counts runes, as
+// BadPatternError.RuneColumn does, and ByteColumn counts bytes.
+type Diagnostic struct {
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	Column     int      `json:"column"`
+	ByteColumn int      `json:"byteColumn"`
+	Severity   Severity `json:"severity"`
+	Check      string   `json:"check"`
+	Message    string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// Lint reads a rule file from r and returns the problems in it, in the order
+// of their lines. Unlike AddFile, it does not stop at the first invalid line.
+// The name is used as the File of the diagnostics. The returned error is only
+// for failing to read r.
+//
+// Lines are counted from 1, and columns from 0, as in BadPatternError.
+func Lint(r io.Reader, name string) ([]Diagnostic, error) {
+	var (
+		diags []Diagnostic
+		seen  = make(map[string]int)
+		line  int
+	)
+	p := newParser(DialectOf(name))
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line++
+
+		s := Clean(sc.Text())
+		if s == "" {
+			continue
+		}
+		rules, err := p.parseLine(s)
+		if err != nil {
+			pe := err.(*BadPatternError)
+			diags = append(diags, Diagnostic{
+				File:       name,
+				Line:       line,
+				Column:     pe.RuneColumn,
+				ByteColumn: pe.ByteColumn,
+				Severity:   SeverityError,
+				Check:      LintBadPattern,
+				Message:    pe.Err.Error(),
+			})
+			continue
+		}
+		for _, r := range rules {
+			if r.expired() {
+				diags = append(diags, Diagnostic{
+					File:     name,
+					Line:     line,
+					Severity: SeverityWarning,
+					Check:    LintExpired,
+					Message:  fmt.Sprintf("rule %s expired on %s", r.describe(), r.Expires().Format("2006-01-02")),
+				})
+			}
+			key := r.line()
+			if first, ok := seen[key]; ok {
+				diags = append(diags, Diagnostic{
+					File:     name,
+					Line:     line,
+					Severity: SeverityWarning,
+					Check:    LintDuplicate,
+					Message:  fmt.Sprintf("rule %s duplicates line %d", r.describe(), first),
+				})
+				continue
+			}
+			seen[key] = line
+		}
+	}
+	return diags, sc.Err()
+}
+
+// LintFile is like Lint, but reads the rule file at path.
+func LintFile(path string) ([]Diagnostic, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Lint(f, path)
+}
+
+// sarifVersion is the version of the SARIF format written by WriteSARIF.
+const sarifVersion = "2.1.0"
+
+// WriteSARIF writes diags to w as a SARIF log, the Static Analysis Results
+// Interchange Format, so that they can be ingested by code review and IDE
+// tools alongside the results of other analyzers. Relative file names are
+// written as relative URIs; SARIF columns are counted from 1.
+func WriteSARIF(w io.Writer, diags []Diagnostic) error {
+	type text struct {
+		Text string `json:"text"`
+	}
+	type rule struct {
+		ID               string `json:"id"`
+		ShortDescription text   `json:"shortDescription"`
+	}
+	type region struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+	type location struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region region `json:"region"`
+		} `json:"physicalLocation"`
+	}
+	type result struct {
+		RuleID    string     `json:"ruleId"`
+		Level     Severity   `json:"level"`
+		Message   text       `json:"message"`
+		Locations []location `json:"locations"`
+	}
+	type run struct {
+		Tool struct {
+			Driver struct {
+				Name  string `json:"name"`
+				Rules []rule `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []result `json:"results"`
+	}
+
+	var r run
+	r.Tool.Driver.Name = "matcher"
+	ids := make([]string, 0, len(lintDescriptions))
+	for id := range lintDescriptions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		r.Tool.Driver.Rules = append(r.Tool.Driver.Rules, rule{id, text{lintDescriptions[id]}})
+	}
+	r.Results = make([]result, 0, len(diags))
+	for _, d := range diags {
+		var loc location
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(d.File)
+		loc.PhysicalLocation.Region = region{StartLine: d.Line, StartColumn: d.Column + 1}
+		r.Results = append(r.Results, result{
+			RuleID:    d.Check,
+			Level:     d.Severity,
+			Message:   text{d.Message},
+			Locations: []location{loc},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Version string `json:"version"`
+		Schema  string `json:"$schema"`
+		Runs    []run  `json:"runs"`
+	}{sarifVersion, "https://json.schemastore.org/sarif-2.1.0.json", []run{r}})
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const lintInput = `# comment
+*.o
+a[
+*.o
+size:>1M *.iso
+size:>1M *.iso
+@UNDEFINED
+`
+
+func TestLint(fw *testing.T) {
+	diags, err := Lint(strings.NewReader(lintInput), "rules")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	expected := []Diagnostic{
+		{"rules", 3, 1, 1, SeverityError, LintBadPattern, ErrIncompleteClass.Error()},
+		{"rules", 4, 0, 0, SeverityWarning, LintDuplicate, `rule "*.o" duplicates line 2`},
+		{"rules", 6, 0, 0, SeverityWarning, LintDuplicate, `rule size:>1M "*.iso" duplicates line 5`},
+		{"rules", 7, 0, 0, SeverityError, LintBadPattern, ErrUndefinedMacro.Error()},
+	}
+	if !reflect.DeepEqual(diags, expected) {
+		fw.Errorf("Lint() = %v, expected %v", diags, expected)
+	}
+}
+
+func TestLintExpired(fw *testing.T) {
+	now = func() time.Time { return time.Date(2020, 6, 15, 0, 0, 0, 0, time.Local) }
+	defer func() { now = time.Now }()
+
+	input := "expires:2020-06-01 *.tmp\nexpires:2020-07-01 *.log\n"
+	diags, err := Lint(strings.NewReader(input), "rules")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	expected := []Diagnostic{
+		{"rules", 1, 0, 0, SeverityWarning, LintExpired, `rule expires:2020-06-01 "*.tmp" expired on 2020-06-01`},
+	}
+	if !reflect.DeepEqual(diags, expected) {
+		fw.Errorf("Lint() = %v, expected %v", diags, expected)
+	}
+}
+
+func TestWriteSARIF(fw *testing.T) {
+	diags, _ := Lint(strings.NewReader(lintInput), "rules")
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, diags); err != nil {
+		fw.Fatal(err)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						Region struct {
+							StartLine, StartColumn int
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		fw.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != len(diags) {
+		fw.Fatalf("WriteSARIF() = %s", buf.String())
+	}
+	res := log.Runs[0].Results[0]
+	region := res.Locations[0].PhysicalLocation.Region
+	if res.RuleID != LintBadPattern || res.Level != "error" || region.StartLine != 3 || region.StartColumn != 2 {
+		fw.Errorf("WriteSARIF() first result = %+v", res)
+	}
+}
@@ -0,0 +1,365 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// abs returns path resolved against the working directory of the Worker.
+func (w *Worker) abs(path string) string {
+	abs, _ := w.Resolve(path)
+	return abs
+}
+
+// VisitFunc is called for every file and directory that a walking function
+// of a Worker decides on; see SetVisit. The path is formed as by the walking
+// function, fi is the result of os.Lstat on it, and excluded is whether the
+// Worker matches it.
+//
+// If it returns an error, the walking function stops and returns it, except
+// for filepath.SkipDir, which skips the directory as in filepath.Walk.
+type VisitFunc func(path string, fi os.FileInfo, excluded bool) error
+
+// SetVisit makes Glob, ListIncluded, ListExcluded, Summarize, Walk, CopyDir,
+// and CopyFS call fn with the decision for every file and directory they
+// come across, so that backup tools can, for example, total the sizes of the
+// included files and enforce a quota in the same pass as listing or copying
+// them. Files beneath a matched directory are only visited by ListExcluded
+// and Summarize, which descend into it. Passing nil turns this off again.
+func (w *Worker) SetVisit(fn VisitFunc) {
+	w.visit = fn
+}
+
+// walkFunc is called by walk for each file, with path being root joined
+// with the path of the file relative to root, and abs its absolute path.
+type walkFunc func(path, abs string, fi os.FileInfo, excluded bool) error
+
+// walk calls fn for every file and directory beneath root, not including
+// root itself, in lexical order. Relative roots are resolved against the
+// working directory of the Worker.
+//
+// Excluded directories are skipped entirely, unless descend is true,
+// in which case everything beneath them is reported as excluded as well.
+func (w *Worker) walk(root string, descend bool, fn walkFunc) error {
+	absRoot := w.abs(root)
+	var pruned string
+	return filepath.Walk(absRoot, func(abs string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if abs == absRoot {
+			return nil
+		}
+		rel, err := filepath.Rel(absRoot, abs)
+		if err != nil {
+			return err
+		}
+
+		if pruned != "" && !strings.HasPrefix(abs, dirPrefix(pruned)) {
+			pruned = ""
+		}
+		excluded := pruned != "" || w.MatchesInfo(abs, fi)
+		path := filepath.Join(root, rel)
+		if w.visit != nil {
+			if err := w.visit(path, fi, excluded); err != nil {
+				return err
+			}
+		}
+		if err := fn(path, abs, fi, excluded); err != nil {
+			return err
+		}
+		if excluded && fi.IsDir() && pruned == "" {
+			w.emit(EventPruned, abs, Rule{})
+			if !descend {
+				return filepath.SkipDir
+			}
+			pruned = abs
+		}
+		return nil
+	})
+}
+
+// Walk walks the tree beneath root like filepath.WalkDir, but calls fn only
+// for the files and directories that the Worker does not match, starting
+// with root itself. Directories that are matched are not descended into.
+//
+// Unlike the other walking functions, Walk reads the configuration files in
+// each directory as it descends, as if a Worker were created for it with
+// Scope; root must therefore be beneath the working directory. The rules of
+// a directory's configuration files apply to its contents. If they cannot
+// be read, fn is called for the directory a second time with the error,
+// as filepath.WalkDir does for directories it cannot read; if fn returns
+// nil, the walk continues without them.
+//
+// The path passed to fn is root joined with the path relative to root,
+// and a relative root is relative to the working directory of the Worker.
+func (w *Worker) Walk(root string, fn fs.WalkDirFunc) error {
+	absRoot := w.abs(root)
+	type level struct {
+		dir string
+		w   *Worker
+	}
+	var stack []level
+
+	return filepath.WalkDir(absRoot, func(abs string, d fs.DirEntry, err error) error {
+		rel, rerr := filepath.Rel(absRoot, abs)
+		if rerr != nil {
+			return rerr
+		}
+		path := filepath.Join(root, rel)
+		if err != nil {
+			return fn(path, d, err)
+		}
+
+		var cur *Worker
+		if abs != absRoot {
+			for len(stack) > 1 && !strings.HasPrefix(abs, dirPrefix(stack[len(stack)-1].dir)) {
+				stack = stack[:len(stack)-1]
+			}
+			cur = stack[len(stack)-1].w
+			matched := cur.Matches(abs)
+			if err := w.visitEntry(path, d, matched); err != nil {
+				return err
+			}
+			if matched {
+				if d.IsDir() {
+					w.emit(EventPruned, abs, Rule{})
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if err := fn(path, d, nil); err != nil || !d.IsDir() {
+			return err
+		}
+
+		sub, err := w.walkScope(cur, abs)
+		if err != nil {
+			if err := fn(path, d, err); err != nil {
+				return err
+			}
+		}
+		switch {
+		case sub != nil:
+		case cur != nil:
+			sub = cur
+		default:
+			sub = w
+		}
+		stack = append(stack, level{abs, sub})
+		return nil
+	})
+}
+
+// dirPrefix returns dir with a trailing separator, which the paths beneath
+// it start with. The root directory already ends in one.
+func dirPrefix(dir string) string {
+	if os.IsPathSeparator(dir[len(dir)-1]) {
+		return dir
+	}
+	return dir + string(filepath.Separator)
+}
+
+// visitEntry calls the VisitFunc of the Worker, if it is set, for the
+// entry d at path.
+func (w *Worker) visitEntry(path string, d fs.DirEntry, excluded bool) error {
+	if w.visit == nil {
+		return nil
+	}
+	fi, err := d.Info()
+	if err != nil {
+		return err
+	}
+	return w.visit(path, fi, excluded)
+}
+
+// walkScope returns the Worker for the contents of the directory dir
+// for Walk, given the Worker parent of its parent directory, or nil for
+// the root of the walk. It returns parent itself if dir contains no
+// configuration files.
+func (w *Worker) walkScope(parent *Worker, dir string) (*Worker, error) {
+	if parent == nil {
+		return w.Scope(dir)
+	}
+	m := w.m
+	if len(m.names) == 0 || m.disabled[ScopeProject] {
+		return parent, nil
+	}
+	for _, name := range m.configNames() {
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			return parent.Scope(dir)
+		}
+	}
+	return parent, nil
+}
+
+// Glob returns the names of all files beneath root that match pattern and
+// are not matched by the Worker, i.e. "find the sources, minus the ignored".
+// Directories that the Worker matches are not descended into.
+//
+// The pattern has the same syntax as the globs in a rule file; if it contains
+// a path separator, it is relative to root. The returned names are root
+// joined with the path of the file relative to root, and a relative root
+// is relative to the working directory of the Worker. Only the rules of the
+// Worker apply; configuration files in subdirectories of root are not read.
+//
+// The only possible error for an invalid pattern is BadPatternError.
+func (w *Worker) Glob(root, pattern string) ([]string, error) {
+	globs, err := expandPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	for i, g := range globs {
+		if strings.Contains(g, "/") {
+			globs[i] = join(w.abs(root), g)
+		}
+	}
+
+	var matches []string
+	err = w.walk(root, false, func(path, abs string, fi os.FileInfo, excluded bool) error {
+		if excluded || fi.IsDir() {
+			return nil
+		}
+		for _, g := range globs {
+			if w.m.match(g, abs) {
+				matches = append(matches, path)
+				break
+			}
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// ListIncluded returns the names of all files beneath root that the Worker
+// does not match, which is what a packaging tool would ship. Directories that
+// the Worker matches are not descended into. Names are formed as by Glob.
+func (w *Worker) ListIncluded(root string) ([]string, error) {
+	return w.list(root, false)
+}
+
+// ListExcluded returns the names of all files beneath root that the Worker
+// matches, including all files within matched directories. Names are formed
+// as by Glob.
+func (w *Worker) ListExcluded(root string) ([]string, error) {
+	return w.list(root, true)
+}
+
+func (w *Worker) list(root string, excluded bool) ([]string, error) {
+	var names []string
+	err := w.walk(root, excluded, func(path, _ string, fi os.FileInfo, x bool) error {
+		if x == excluded && !fi.IsDir() {
+			names = append(names, path)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Summary contains counts of the files beneath a directory, as returned
+// by Summarize. Directories themselves are not counted.
+type Summary struct {
+	Included      int
+	Excluded      int
+	IncludedBytes int64
+	ExcludedBytes int64
+}
+
+// Summarize counts the files beneath root that the Worker does and does not
+// match, along with their sizes, without building lists of their names.
+// Files within matched directories are counted as excluded.
+func (w *Worker) Summarize(root string) (Summary, error) {
+	var s Summary
+	err := w.walk(root, true, func(_, _ string, fi os.FileInfo, excluded bool) error {
+		switch {
+		case fi.IsDir():
+		case excluded:
+			s.Excluded++
+			s.ExcludedBytes += fi.Size()
+		default:
+			s.Included++
+			s.IncludedBytes += fi.Size()
+		}
+		return nil
+	})
+	return s, err
+}
+
+// Effect describes how adding a pattern changes which files are matched,
+// as returned by Preview.
+type Effect struct {
+	// Excluded are the files that are currently not matched,
+	// but would be after adding the pattern.
+	Excluded []string
+
+	// Included are the files that are currently matched,
+	// but would not be after adding the pattern.
+	Included []string
+}
+
+// Preview returns the effect that adding pattern to the Worker would have on
+// the files beneath root, without changing the Worker. This lets interactive
+// tools show the impact of a rule before committing it to a file.
+//
+// The pattern is interpreted as a line of a rule file located in root, and
+// the names in the result are formed as by Glob. The only possible error for
+// an invalid pattern is BadPatternError.
+func (w *Worker) Preview(pattern, root string) (Effect, error) {
+	var e Effect
+	rules, err := w.line(newParser(DialectNative), pattern, w.abs(root))
+	if err != nil {
+		return e, err
+	}
+
+	before, err := w.quiet().excluded(root)
+	if err != nil {
+		return e, err
+	}
+	c := w.quiet()
+	c.insert(rules...)
+	after, err := c.excluded(root)
+	if err != nil {
+		return e, err
+	}
+
+	for _, p := range after.names {
+		if !before.set[p] {
+			e.Excluded = append(e.Excluded, p)
+		}
+	}
+	for _, p := range before.names {
+		if !after.set[p] {
+			e.Included = append(e.Included, p)
+		}
+	}
+	return e, nil
+}
+
+// quiet returns a clone of the Worker that does not report to the metrics,
+// events, logger, trace, audit log, profile, or VisitFunc of the original.
+func (w *Worker) quiet() *Worker {
+	c := w.Clone()
+	c.metrics, c.events, c.logger, c.tracer, c.profile = nil, nil, nil, nil, nil
+	c.audit, c.visit = nil, nil
+	return c
+}
+
+type fileSet struct {
+	names []string
+	set   map[string]bool
+}
+
+func (w *Worker) excluded(root string) (fileSet, error) {
+	names, err := w.ListExcluded(root)
+	s := fileSet{names: names, set: make(map[string]bool, len(names))}
+	for _, n := range names {
+		s.set[n] = true
+	}
+	return s, err
+}
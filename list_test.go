@@ -0,0 +1,343 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testWorker returns a Worker in the tests directory.
+func testWorker(fw *testing.T) *Worker {
+	m := New("match.conf")
+	m.Add("match.conf")
+	w, err := m.NewWorker("tests")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	return w
+}
+
+func TestGlob(fw *testing.T) {
+	w := testWorker(fw)
+	tests := map[string][]string{
+		"[jl]*":      {"jack", "lucy"},
+		"brain/*":    {"brain/bar", "brain/yahoo"},
+		"foo":        {"dead/ugly/foo"},
+		"*.conf":     nil,
+		"dead/*/?ar": {"dead/ugly/bar"},
+	}
+	for k, v := range tests {
+		matches, err := w.Glob(".", k)
+		if err != nil {
+			fw.Errorf("w.Glob(%q) failed: %s", k, err)
+			continue
+		}
+		if !reflect.DeepEqual(matches, v) {
+			fw.Errorf("w.Glob(%q) = %q, expected %q", k, matches, v)
+		}
+	}
+
+	matches, err := w.Glob("brain", "*")
+	expected := []string{filepath.Join("brain", "bar"), filepath.Join("brain", "yahoo")}
+	if err != nil || !reflect.DeepEqual(matches, expected) {
+		fw.Errorf("w.Glob(%q, %q) = (%q, %v), expected %q", "brain", "*", matches, err, expected)
+	}
+
+	if _, err := w.Glob(".", "a["); err == nil {
+		fw.Errorf("w.Glob with invalid pattern succeeded")
+	}
+
+	// Metacharacters in the root are not special.
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "a[1]")
+	os.MkdirAll(filepath.Join(root, "src"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "src", "main.go"), nil, 0644)
+	matches, err = w.Glob(root, "src/*.go")
+	expected = []string{filepath.Join(root, "src", "main.go")}
+	if err != nil || !reflect.DeepEqual(matches, expected) {
+		fw.Errorf("w.Glob(%q, %q) = (%q, %v), expected %q", root, "src/*.go", matches, err, expected)
+	}
+}
+
+func TestList(fw *testing.T) {
+	w := testWorker(fw)
+	w.Add("ugly")
+
+	included, err := w.ListIncluded("dead")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	excluded, err := w.ListExcluded("dead")
+	if err != nil {
+		fw.Fatal(err)
+	}
+
+	expectedExcluded := []string{
+		"dead/good/match.conf",
+		"dead/match.conf",
+		"dead/ok",
+		"dead/ugly/bar",
+		"dead/ugly/foo",
+		"dead/ugly/foobar",
+	}
+	if !reflect.DeepEqual(excluded, expectedExcluded) {
+		fw.Errorf("w.ListExcluded(%q) = %q, expected %q", "dead", excluded, expectedExcluded)
+	}
+	if n := len(included) + len(excluded); n != 22 {
+		fw.Errorf("included and excluded have %d files, expected 22", n)
+	}
+	for _, p := range included {
+		if filepath.Base(p) == "ok" || filepath.Dir(p) == "dead/ugly" {
+			fw.Errorf("w.ListIncluded(%q) contains excluded %q", "dead", p)
+		}
+	}
+}
+
+func TestWalk(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"match.conf":     "*.o\nbuild/\n",
+		"main.go":        "",
+		"main.o":         "",
+		"build/main":     "",
+		"src/match.conf": "*.tmp\n!keep.o\n",
+		"src/a.o":        "",
+		"src/keep.o":     "",
+		"src/a.tmp":      "",
+		"src/lib/b.o":    "",
+		"src/lib/b.tmp":  "",
+		"doc/a.tmp":      "",
+	}
+	for k, v := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(k)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0644)
+	}
+
+	m := New("match.conf")
+	m.Root = dir
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	var walked []string
+	err = w.Walk(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, filepath.ToSlash(path))
+		return nil
+	})
+	expected := []string{
+		".", "doc", "doc/a.tmp", "main.go", "match.conf",
+		"src", "src/keep.o", "src/lib", "src/match.conf",
+	}
+	if err != nil || !reflect.DeepEqual(walked, expected) {
+		fw.Errorf("w.Walk(\".\") walked %q, %v; expected %q", walked, err, expected)
+	}
+
+	walked = nil
+	err = w.Walk("src", func(path string, d fs.DirEntry, err error) error {
+		if d.Name() == "lib" {
+			return filepath.SkipDir
+		}
+		walked = append(walked, filepath.ToSlash(path))
+		return err
+	})
+	expected = []string{"src", "src/keep.o", "src/match.conf"}
+	if err != nil || !reflect.DeepEqual(walked, expected) {
+		fw.Errorf("w.Walk(\"src\") walked %q, %v; expected %q", walked, err, expected)
+	}
+
+	err = w.Walk("..", func(_ string, _ fs.DirEntry, err error) error { return err })
+	if err != ErrNotSubdir {
+		fw.Errorf("w.Walk(\"..\") = %v, expected %v", err, ErrNotSubdir)
+	}
+
+	// The root directory already ends in a separator.
+	root := string(filepath.Separator)
+	w, err = New("").NewWorker(root)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	var n int
+	err = w.Walk(root, func(path string, d fs.DirEntry, err error) error {
+		if path == root {
+			return err
+		}
+		n++
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil || n == 0 {
+		fw.Errorf("w.Walk(%q) walked %d entries, %v", root, n, err)
+	}
+}
+
+func TestSummarize(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "cache"), 0755)
+	files := map[string]int{
+		"main.go":      100,
+		"main.o":       1000,
+		"README":       10,
+		"cache/a":      300,
+		"cache/b.tmp":  30,
+		"cache/c.conf": 3,
+	}
+	for k, v := range files {
+		ioutil.WriteFile(filepath.Join(dir, k), make([]byte, v), 0644)
+	}
+
+	w, err := New("").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.o", "cache")
+	s, err := w.Summarize(".")
+	expected := Summary{
+		Included:      2,
+		Excluded:      4,
+		IncludedBytes: 110,
+		ExcludedBytes: 1333,
+	}
+	if err != nil || s != expected {
+		fw.Errorf("w.Summarize() = (%+v, %v), expected %+v", s, err, expected)
+	}
+}
+
+func TestSetVisit(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "cache"), 0755)
+	files := map[string]int{
+		"a":       100,
+		"b":       200,
+		"c.o":     1000,
+		"cache/d": 300,
+	}
+	for k, v := range files {
+		ioutil.WriteFile(filepath.Join(dir, k), make([]byte, v), 0644)
+	}
+	w, err := New("").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.o", "cache")
+
+	var included int64
+	visited := make(map[string]bool)
+	w.SetVisit(func(path string, fi os.FileInfo, excluded bool) error {
+		visited[filepath.ToSlash(path)] = excluded
+		if !excluded && !fi.IsDir() {
+			included += fi.Size()
+		}
+		return nil
+	})
+	names, err := w.ListIncluded(".")
+	expected := map[string]bool{"a": false, "b": false, "c.o": true, "cache": true}
+	if err != nil || len(names) != 2 || included != 300 || !reflect.DeepEqual(visited, expected) {
+		fw.Errorf("visited %v with %d included bytes, expected %v with 300", visited, included, expected)
+	}
+
+	visited = make(map[string]bool)
+	w.Walk(".", func(string, fs.DirEntry, error) error { return nil })
+	if !reflect.DeepEqual(visited, expected) {
+		fw.Errorf("w.Walk visited %v, expected %v", visited, expected)
+	}
+
+	errQuota := errors.New("quota exceeded")
+	included = 0
+	w.SetVisit(func(path string, fi os.FileInfo, excluded bool) error {
+		if !excluded {
+			included += fi.Size()
+		}
+		if included > 150 {
+			return errQuota
+		}
+		return nil
+	})
+	if _, err := w.ListIncluded("."); err != errQuota {
+		fw.Errorf("w.ListIncluded with a quota = %v, expected %v", err, errQuota)
+	}
+
+	w.SetVisit(nil)
+	if _, err := w.ListIncluded("."); err != nil {
+		fw.Errorf("w.ListIncluded after SetVisit(nil) = %v", err)
+	}
+}
+
+func TestPreview(fw *testing.T) {
+	w := testWorker(fw)
+	var trace bytes.Buffer
+	w.SetTrace(&trace)
+	n := len(w.Rules())
+
+	e, err := w.Preview("*foo*", "dead")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	expected := []string{"dead/bad/somefoo", "dead/ugly/foo", "dead/ugly/foobar"}
+	if !reflect.DeepEqual(e.Excluded, expected) || e.Included != nil {
+		fw.Errorf("w.Preview(*foo*) = %+v, expected excluded %q", e, expected)
+	}
+
+	e, err = w.Preview("ugly/*", "dead")
+	expected = []string{"dead/ugly/bar", "dead/ugly/foo", "dead/ugly/foobar"}
+	if err != nil || !reflect.DeepEqual(e.Excluded, expected) {
+		fw.Errorf("w.Preview(ugly/*) = (%+v, %v), expected excluded %q", e, err, expected)
+	}
+
+	if len(w.Rules()) != n || trace.Len() != 0 {
+		fw.Errorf("w.Preview changed the worker or wrote a trace")
+	}
+	if _, err = w.Preview("[", "dead"); err == nil {
+		fw.Errorf("w.Preview with invalid pattern succeeded")
+	}
+}
+
+func TestPartition(fw *testing.T) {
+	w := testWorker(fw)
+	w.Add("cache")
+	in := "jack\x00foo\x00brain/foo\x00brain/yahoo\x00dead/good/cache/x\x00dead/ok\x00lucy\x00"
+	matched, unmatched, err := w.Partition(strings.NewReader(in))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	em := []string{"foo", "brain/foo", "dead/good/cache/x", "dead/ok"}
+	eu := []string{"jack", "brain/yahoo", "lucy"}
+	if !reflect.DeepEqual(matched, em) || !reflect.DeepEqual(unmatched, eu) {
+		fw.Errorf("w.Partition() = (%q, %q), expected (%q, %q)", matched, unmatched, em, eu)
+	}
+
+	matched, unmatched, _ = w.Partition(strings.NewReader("foo\r\njack\n\nbar"))
+	if !reflect.DeepEqual(matched, []string{"foo", "bar"}) || !reflect.DeepEqual(unmatched, []string{"jack"}) {
+		fw.Errorf("w.Partition() of lines = (%q, %q)", matched, unmatched)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+)
+
+// snapshotVersion is the version of the encoding of Workers written by
+// MarshalBinary. UnmarshalBinary rejects other versions.
+const snapshotVersion = 1
+
+// ErrBadSnapshot is returned by UnmarshalBinary for data that was not
+// written by MarshalBinary of a compatible version of this package.
+var ErrBadSnapshot = errors.New("invalid worker snapshot")
+
+// snapshot is the encoded state of a Worker.
+type snapshot struct {
+	Version       int            `json:"version"`
+	Dir           string         `json:"dir"`
+	Root          string         `json:"root,omitempty"`
+	Global        []snapshotRule `json:"global,omitempty"`
+	Local         []snapshotRule `json:"local,omitempty"`
+	Disabled      []string       `json:"disabled,omitempty"`
+	DisabledFiles []string       `json:"disabledFiles,omitempty"`
+}
+
+// snapshotRule is the encoded form of a Rule.
+type snapshotRule struct {
+	Glob    string `json:"glob"`
+	Source  string `json:"source,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Scope   string `json:"scope"`
+	Cond    string `json:"cond,omitempty"`
+	Negate  bool   `json:"negate,omitempty"`
+	DirOnly bool   `json:"dirOnly,omitempty"`
+	Regexp  bool   `json:"regexp,omitempty"`
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. It encodes the rules of
+// the Worker in order, with their anchored globs and provenance, along with
+// its working directory, its root, and the scopes and files that are turned
+// off, so that the rules in force can be computed once, such as at build time,
+// and loaded at runtime without reading any configuration files.
+//
+// Layers added with AddMatcher, hit counts, profiles, and the configuration
+// files to reload are not encoded. The encoding is JSON, so that it can be
+// inspected, but its layout is not part of the API.
+func (w *Worker) MarshalBinary() ([]byte, error) {
+	s := snapshot{
+		Version: snapshotVersion,
+		Dir:     w.cwd,
+		Root:    w.root,
+		Global:  snapshotRules(w.global),
+		Local:   snapshotRules(w.local),
+	}
+	for i, off := range w.disabled {
+		if off {
+			s.Disabled = append(s.Disabled, Scope(i).String())
+		}
+	}
+	for f := range w.disabledFiles {
+		s.DisabledFiles = append(s.DisabledFiles, f)
+	}
+	sort.Strings(s.DisabledFiles)
+	return json.Marshal(s)
+}
+
+func snapshotRules(rules []Rule) []snapshotRule {
+	var out []snapshotRule
+	for _, r := range rules {
+		out = append(out, snapshotRule{
+			Glob:    r.Glob,
+			Source:  r.Source,
+			Line:    r.Line,
+			Scope:   r.Scope.String(),
+			Cond:    r.Cond,
+			Negate:  r.Negate,
+			DirOnly: r.DirOnly,
+			Regexp:  r.Regexp,
+		})
+	}
+	return out
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It replaces the rules,
+// working directory, and root of the Worker with those encoded by MarshalBinary,
+// without touching the file system. The Worker keeps its Matcher, whose options,
+// such as CaseFold, apply to the loaded rules; a zero Worker gets a Matcher
+// without a configuration file, as if created by New(""), and
+// Matcher.UnmarshalWorker creates a Worker of a given Matcher. Layers added
+// with AddMatcher are kept.
+//
+// Predicates in the rules must be registered with RegisterPredicate as they
+// were when the data was encoded. Invalid data fails with ErrBadSnapshot,
+// or with a BadPatternError for an invalid pattern.
+func (w *Worker) UnmarshalBinary(data []byte) error {
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return ErrBadSnapshot
+	}
+	if s.Version != snapshotVersion || s.Dir == "" {
+		return ErrBadSnapshot
+	}
+	global, err := unsnapshotRules(s.Global)
+	if err != nil {
+		return err
+	}
+	local, err := unsnapshotRules(s.Local)
+	if err != nil {
+		return err
+	}
+	var disabled [numScopes]bool
+	for _, name := range s.Disabled {
+		sc, ok := parseScope(name)
+		if !ok {
+			return ErrBadSnapshot
+		}
+		disabled[sc] = true
+	}
+
+	if w.m == nil {
+		*w = *New("").newWorker(s.Dir)
+	}
+	w.cwd, w.root = s.Dir, s.Root
+	w.global, w.local, w.shared = global, local, new(uint32)
+	w.configs = nil
+	w.disabled = disabled
+	w.disabledFiles = nil
+	for _, f := range s.DisabledFiles {
+		if w.disabledFiles == nil {
+			w.disabledFiles = make(map[string]bool)
+		}
+		w.disabledFiles[f] = true
+	}
+	w.hits = new(hitCounters)
+	if w.profile != nil {
+		w.profile = make(map[Rule]*PatternProfile)
+	}
+	w.invalidate()
+	return nil
+}
+
+// UnmarshalWorker creates a Worker from data encoded by Worker.MarshalBinary,
+// without reading any configuration files. See Worker.UnmarshalBinary.
+func (m *Matcher) UnmarshalWorker(data []byte) (*Worker, error) {
+	w := m.newWorker("")
+	if err := w.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func unsnapshotRules(rules []snapshotRule) ([]Rule, error) {
+	out := make([]Rule, 0, len(rules))
+	for _, s := range rules {
+		sc, ok := parseScope(s.Scope)
+		if !ok || s.Glob == "" {
+			return nil, ErrBadSnapshot
+		}
+		r := Rule{
+			Glob:    s.Glob,
+			Source:  s.Source,
+			Line:    s.Line,
+			Scope:   sc,
+			Negate:  s.Negate,
+			DirOnly: s.DirOnly,
+			Regexp:  s.Regexp,
+		}
+		if r.Regexp {
+			if _, err := regexp.Compile(r.Glob); err != nil {
+				return nil, &BadPatternError{Err: ErrBadRegexp, Column: 0, Line: s.Line}
+			}
+		} else {
+			if err := checkPattern(r.Glob); err != nil {
+				return nil, err
+			}
+			compiled(r.Glob)
+		}
+		if s.Cond != "" {
+			c, err := parseRule(s.Cond + " *")
+			if err != nil || c.Cond != s.Cond {
+				return nil, ErrBadSnapshot
+			}
+			r.Cond, r.cond = c.Cond, c.cond
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// parseScope returns the scope whose String is name.
+func parseScope(name string) (Scope, bool) {
+	for i, n := range scopeNames {
+		if n == name {
+			return Scope(i), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMarshalBinary(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "match.conf"), []byte("*.o\n!keep.o\nbuild/\nsrc/*.gen\ntype:dir cache\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, ".hgignore"), []byte("\\.orig$\n"), 0644)
+	os.Mkdir(filepath.Join(dir, "cache"), 0755)
+
+	m := New("match.conf")
+	m.Root = dir
+	m.Add("*.tmp")
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if err := w.AddFile(filepath.Join(dir, ".hgignore")); err != nil {
+		fw.Fatal(err)
+	}
+	w.SetScopeEnabled(ScopeUser, false)
+	data, err := w.MarshalBinary()
+	if err != nil {
+		fw.Fatal(err)
+	}
+
+	var u Worker
+	if err := u.UnmarshalBinary(data); err != nil {
+		fw.Fatal(err)
+	}
+	rules, loaded := w.Rules(), u.Rules()
+	if len(rules) != len(loaded) {
+		fw.Fatalf("loaded %d rules, expected %d", len(loaded), len(rules))
+	}
+	for i, r := range rules {
+		l := loaded[i]
+		if l.line() != r.line() || l.Source != r.Source || l.Line != r.Line || l.Scope != r.Scope || l.Regexp != r.Regexp {
+			fw.Errorf("loaded rule %d = %s, expected %s", i, l.describe(), r.describe())
+		}
+	}
+	if u.Dir() != w.Dir() || u.Root() != w.Root() || u.ScopeEnabled(ScopeUser) {
+		fw.Errorf("loaded Worker in %q with root %q", u.Dir(), u.Root())
+	}
+
+	tests := map[string]bool{
+		"a.o":         true,
+		"keep.o":      false,
+		"build/":      true,
+		"src/a.gen":   true,
+		"x/src/a.gen": false,
+		"cache":       true,
+		"a.tmp":       true,
+		"a.orig":      true,
+		"main.go":     false,
+	}
+	for k, v := range tests {
+		if r := u.Matches(k); r != v {
+			fw.Errorf("loaded w.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+
+	m2 := New("")
+	m2.CaseFold = FoldASCII
+	v, err := m2.UnmarshalWorker(data)
+	if err != nil || !v.Matches("A.O") {
+		fw.Errorf("m.UnmarshalWorker does not apply CaseFold: %v", err)
+	}
+
+	bad := []string{
+		"",
+		"{}",
+		`{"version":2,"dir":"/"}`,
+		`{"version":1,"dir":"/","local":[{"glob":"a","scope":"galaxy"}]}`,
+		`{"version":1,"dir":"/","local":[{"glob":"a","scope":"project","cond":"nope:1"}]}`,
+		`{"version":1,"dir":"/","disabled":["galaxy"]}`,
+	}
+	for _, b := range bad {
+		if err := new(Worker).UnmarshalBinary([]byte(b)); err != ErrBadSnapshot {
+			fw.Errorf("UnmarshalBinary(%q) = %v, expected %v", b, err, ErrBadSnapshot)
+		}
+	}
+	if err := new(Worker).UnmarshalBinary([]byte(`{"version":1,"dir":"/","local":[{"glob":"a[","scope":"project"}]}`)); !isPatternError(err) {
+		fw.Errorf("UnmarshalBinary with an invalid glob = %v, expected a BadPatternError", err)
+	}
+}
@@ -7,7 +7,8 @@
 // If there are any bugs, please report them!
 //
 // This package does not define what the matching means. Whether it is to ignore
-// files or not is up to the user.
+// files or not is up to the user. For rules that list the files to process,
+// Matcher.DenyByDefault and Includer read them as an allowlist.
 //
 // Pattern Format
 //
@@ -20,10 +21,51 @@
 // Trailing and leading spaces are ignored unless they are quoted with backslash ("\").
 // Any character that is quoted with a backslash is interpreted as is.
 //
+// An optional prefix "!" negates the pattern: a file it matches is not
+// matched, even if another pattern of lower precedence matches it. Within
+// a file, later patterns take precedence over earlier ones, so that "*.log"
+// followed by "!keep.log" matches all logs but one. Files in the working
+// directory take precedence over those in its parents, and the scopes are
+// ordered as described for Scope. Put a backslash in front of the first "!"
+// for patterns that begin with one.
+//
+// If the pattern ends with a slash, it only matches directories, but is
+// otherwise treated as if the slash was not there. So "build/" matches
+// directories named build anywhere, but no files of that name.
+//
 // If the pattern does not contain a slash /, it is treated as a shell glob
 // applicable to only the basename of files. Otherwise, it is matched against
 // the full filename.
 //
+// Two consecutive asterisks "**" that form a whole element of the pattern
+// match any number of directories. A leading "**/", as in "**/foo", matches
+// in every directory beneath the one of the rule file; a "/**/" in the
+// middle, as in "a/**/b", matches zero or more directories, so a/b, a/x/b,
+// and a/x/y/b; and a trailing "/**", as in "abc/**", matches everything
+// inside abc, but not abc itself. Any other "**" is invalid.
+//
+// The patterns "dir/", "dir/*", and "dir/**" are thus distinct: the first
+// matches the directory dir itself, the second the entries directly inside
+// it, and the third everything beneath it at any depth. Worker.Matches only
+// decides about the path it is given and does not consult its parent
+// directories, but the functions that walk directories, such as
+// Worker.ListExcluded, do not descend into a matched directory and consider
+// everything beneath it matched. As in gitignore, a negated pattern cannot
+// bring back a file whose parent directory is matched: "dir/" followed by
+// "!dir/keep" still matches dir/keep when walking, but "dir/*" followed by
+// "!dir/keep" does not, since dir itself is not matched.
+//
+// A pattern starting with two slashes, as in "//build/out", is relative to
+// the root of the workspace, regardless of which file it is in, so that the
+// rules of a monorepo can be kept in one file. The root is determined by
+// Matcher.Sentinels and Matcher.Root; if there is none, the working directory
+// of the Worker is used.
+//
+// On macOS, patterns and names are compared after decomposing accented
+// letters and Hangul syllables, since the file system may report names in
+// a different Unicode normalization form than the one the patterns were
+// written in.
+//
 // Otherwise, the pattern is as defined in filepath.Match:
 //
 //  pattern:
@@ -41,15 +83,127 @@
 //      '\\' c      matches character c
 //      lo '-' hi   matches character c for lo <= c <= hi
 //
-// Unfortunately, filepath.Match may or may not fail, depending on the glob and the string.
-// This package defines the Check function, which attempts to validate a glob beforehand.
-// If there is an error during matching, the function panics with the error. This indicates
-// a bug in the matcher package. Please report it!
+// Unlike filepath.Match, which may or may not fail depending on the glob and
+// the string, this package validates globs completely with the Check function
+// when they are added, and matches them with its own engine, so matching never
+// fails at runtime. A malformed glob that somehow was not checked matches
+// nothing.
+//
+// The patterns are also available without the semantics of rule files in
+// package glob, which compiles them for repeated matching.
+//
+// Predicates
+//
+// Lines in rule files may start with predicates, which are conditions on
+// a file besides its name. Predicates are separated from each other and from
+// the glob by whitespace, and a rule only matches a file if the glob and all
+// of its predicates do. If there is no glob, "*" is assumed.
+// The following predicates are available:
+//
+//  size:[op]n[unit]  the size of the file compared with op (one of <, <=, =,
+//                    >=, >; the default is =) to n bytes, where unit is
+//                    one of K, M, G, and T (powers of 1024)
+//  type:t            the type of the file is t, one of file, dir, symlink,
+//                    pipe, socket, and device
+//  mtime:[op]n unit  the time since the file was last modified compared
+//                    with op to n units, where unit is one of s, m, h, d,
+//                    and w (seconds to weeks)
+//  content:c         the content of the file is c, either binary or text;
+//                    like git, a file is considered binary if there is a NUL
+//                    byte in the first 8000 bytes
+//  mime:m            the MIME type of the file matches the pattern m, such
+//                    as video/*; the type is determined by the extension of
+//                    the file, or failing that, its content
+//  expires:date      the rule is ignored from date on, given as 2025-01-01
+//                    (midnight local time) or in RFC 3339 format
+//
+// For example, the line "size:>10M *.iso" matches ISO images larger than
+// ten megabytes, and "mtime:>30d *.log" matches logs that have not been
+// touched for a month. A temporary exclude can be written as
+// "expires:2025-01-01 build/", which Lint reports once the date has passed.
+// Predicates are evaluated against the result of os.Lstat, or the FileInfo
+// passed to Worker.MatchesInfo. To match a file that is
+// literally named like a predicate, escape the colon, as in "type\:dir".
+// Applications can add their own predicates with RegisterPredicate.
+//
+// Macros
+//
+// A line of the form "define NAME = pattern, pattern, ..." in a rule file
+// defines a macro, which is referenced on later lines as "@NAME" in place of
+// a glob. When the file is loaded, the reference is replaced by a rule for
+// each pattern in the definition, and any predicates on the line apply to
+// all of them. Patterns in a definition may reference other macros, as long
+// as there is no cycle. Macros are only visible in the file that defines
+// them. To match a file whose name starts with "@" or a line that starts
+// with "define", escape the first character with a backslash.
+//
+// Variables
+//
+// A line of the form "set name = value, value, ..." in a rule file sets
+// a variable, which is interpolated on later lines wherever "${name}" occurs.
+// Since a variable holds a list, a line referencing it stands for one line
+// per value, so that
+//
+//  set ext = log, tmp
+//  build/*.${ext}
+//
+// is the same as the two lines "build/*.log" and "build/*.tmp". To use all
+// values on one line instead, as a list for the argument of a predicate,
+// write "${name:list}": it is replaced by the values joined with the path
+// list separator of the OS, which is ":" on Unix and ";" on Windows, so
+// that one rule file serves both. Variables are interpolated in macro
+// definitions and in the values of other variables as well. Like macros,
+// they are only visible in the file that sets them. Environment variables
+// are not interpolated.
+//
+// Brace expansion
+//
+// A glob may contain brace expressions, which list alternatives separated
+// by commas, as in the shell. A line with one stands for a rule for each of
+// the globs it expands to, so "*.{jpg,png,gif}" is the same as three lines
+// for "*.jpg", "*.png", and "*.gif". Brace expressions may be nested, as in
+// "{src,test/{unit,e2e}}/*.o", and are expanded after variables. Commas
+// within braces do not separate the values of a macro or variable. Braces
+// without a comma between them are literal, and so are braces and commas
+// escaped with a backslash, as in "\{a,b}". Globs passed to Check, Add,
+// and MatchPattern are expanded in the same way. In .gitignore and other
+// rule files of other tools, braces are always literal.
+//
+// Embedded rules
+//
+// Rules can also be read from structured files of other tools, such as the
+// "ignore" array of a package.json, by registering an Extractor for their
+// name with RegisterExtractor:
+//
+//	RegisterExtractor("package.json", JSONExtractor("ignore"))
+//
+// Dialects
+//
+// Rule files of other tools are read in their own syntax, which is chosen by
+// the name of the file: .gitignore and .npmignore files are read without
+// predicates, macros, variables, and brace expansion, .dockerignore files
+// with every pattern relative to their directory, .hgignore files with
+// regular expressions and the "syntax:" lines of Mercurial, and
+// .rsync-filter files with the include and exclude rules of rsync. See
+// Dialect and DialectOf. Setting Matcher.Dialect overrides the choice for
+// all files. The Capabilities of a dialect tell which features of patterns
+// it supports. Setting Matcher.GitignoreSemantics makes .gitignore files
+// match exactly as in git.
+//
+// Debugging
+//
+// Setting the environment variable MATCHER_DEBUG to 1 makes every Matcher
+// trace the decisions of its Workers to standard error, along with the
+// configuration files they load. Setting it to a filename appends this
+// output to the file instead.
 package matcher
 
 import (
 	"bufio"
+	"context"
 	"errors"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -59,13 +213,48 @@ var (
 	ErrMissingDir  = errors.New("need path to current directory for worker")
 	ErrGlobIsPath  = errors.New("glob cannot contain path separators")
 	ErrConfigUnset = errors.New("config is unset")
+	ErrNotSubdir   = errors.New("directory is not beneath the working directory")
+
+	// ErrFileTooLarge is returned wrapped in an *os.PathError for rule
+	// files larger than Matcher.MaxFileSize.
+	ErrFileTooLarge = errors.New("rule file too large")
 )
 
+// LocalSuffix is appended to the configuration filename to form
+// the name of local override files.
+const LocalSuffix = ".local"
+
+// configNames returns the names of the configuration files that are read
+// in each directory, in order of precedence: each configuration filename
+// preceded by that of its local override file.
+func (m *Matcher) configNames() []string {
+	names := make([]string, 0, 2*len(m.names))
+	for _, n := range m.names {
+		names = append(names, n+LocalSuffix, n)
+	}
+	return names
+}
+
 // Matcher is the starting point for matching. When creating a matcher,
 // the configuration filename is specified. When creating a Worker,
 // the configuration filename is looked for in the working directory
 // of the Worker, as well as all parent directories.
 //
+// Next to each configuration file, a local override file is looked for,
+// which has the same name with LocalSuffix appended, e.g. ".dunignore.local".
+// It takes precedence over the configuration file, and is meant for personal
+// rules of a developer that are not committed.
+//
+// Several configuration filenames may be given, such as ".rgignore",
+// ".ignore", and ".gitignore", in which case all of them are read in each
+// directory. The files of each name are read with the dialect of the name,
+// and a name given earlier takes precedence over those given after it,
+// along with its local override file.
+//
+// Besides the configuration files of the project, a Worker can be given
+// a system-wide and a per-user rule file. See Scope for how these layers
+// are ordered, and how they can be inspected and turned off.
+//
 // Nothing is matched by default, not even the configuration file.
 // It is therefore recommended to add this, if necessary.
 //
@@ -90,51 +279,384 @@ type Matcher struct {
 	//
 	// It may be convenient to simply ignore the errors, in which case ErrHandler
 	// can be left nil. If an error is returned, NewWorker will abort.
+	//
+	// If ErrHandler is set, it takes precedence over Policy and OnError,
+	// which allow the same and more to be expressed more clearly.
 	ErrHandler func(error) error
 
-	config string
-	global []string
+	// Policy determines what NewWorker does when a configuration file
+	// cannot be read, unless OnError is set. The default is to skip the file.
+	Policy ErrorPolicy
+
+	// OnError, if not nil, is called with the path of each configuration
+	// file that cannot be read and the error, and returns the policy
+	// that applies to it.
+	OnError func(path string, err error) ErrorPolicy
+
+	// Strict makes NewWorker fail if a configuration file exists but cannot
+	// be read, for example because of its permissions or an I/O error,
+	// regardless of ErrHandler and Policy. Otherwise, such files are skipped
+	// by default, which security-sensitive tools may not want, since the
+	// unreadable file might contain rules.
+	Strict bool
+
+	// MaxFileSize is the size in bytes beyond which Workers refuse to read
+	// a rule file, failing with ErrFileTooLarge. If it is zero,
+	// DefaultMaxFileSize is used, and if it is negative, there is no limit.
+	MaxFileSize int64
+
+	// Sentinels are names of files or directories that mark the root of
+	// a project, such as "go.mod", "package.json", ".hg", or "WORKSPACE".
+	// NewWorker stops looking for configuration files in parent directories
+	// at the nearest directory that contains one of them, which becomes the
+	// root of the Worker. This scopes the rules to a single subproject of
+	// a monorepo. See Worker.Root.
+	//
+	// If Sentinels is nil, DefaultSentinels are used, so that the rule files
+	// of directories outside of a repository, such as the home directory,
+	// are not read. Set it to an empty slice to look up to "/".
+	Sentinels []string
+
+	// Root, if it is set, is a directory at which NewWorker stops looking
+	// for configuration files in parent directories, and which becomes the
+	// root of the Worker, whether or not it contains a sentinel. A relative
+	// Root is relative to the working directory of the process.
+	Root string
+
+	// CaseFold makes matching case-insensitive in the given mode.
+	// The default, FoldNone, matches case-sensitively.
+	CaseFold Fold
+
+	// Graphemes makes "?" in patterns match a grapheme cluster, i.e. what
+	// a user perceives as a single character, rather than a single rune.
+	// For example, an "e" followed by a combining accent, a flag, or an
+	// emoji made of several joined emoji are each matched by one "?".
+	// In patterns, combining characters are not joined to a special
+	// character such as "*" or "]" that precedes them.
+	Graphemes bool
+
+	// Metrics receives counters from all Workers created by the Matcher.
+	// It may be left nil.
+	Metrics MetricsSink
+
+	// Events receives the events of evaluating rules from all Workers
+	// created by the Matcher. It may be left nil.
+	Events EventSink
+
+	// Logger receives messages about configuration files that are loaded
+	// or skipped by Workers created by the Matcher. It may be left nil.
+	Logger Logger
+
+	// Trace is the initial trace writer of Workers created by the Matcher.
+	// See Worker.SetTrace.
+	Trace io.Writer
+
+	// Audit is the initial audit log writer of Workers created by the
+	// Matcher. See Worker.SetAudit.
+	Audit io.Writer
+
+	// SystemFile and UserFile are the rule files of the system and user
+	// scopes, e.g. "/etc/dunignore" and "~/.config/dun/ignore" (without
+	// tilde expansion). They are read by NewWorker if set; see Scope.
+	SystemFile string
+	UserFile   string
+
+	// App is the name of the application using the Matcher. If it is set
+	// and UserFile is not, the user scope is read from the file "ignore"
+	// in the configuration directory of the application, as returned
+	// by UserRuleFile.
+	//
+	// In addition, if App is set, the user scope contains the configuration
+	// file in the home directory, such as ~/.dunignore, with lower
+	// precedence, unless it has already been read as part of the project
+	// scope. Without App, no file in the home directory is read.
+	App string
+
+	// Mode determines how strictly Workers parse rule files. The default,
+	// ModeDefault, accepts the syntax described in the package documentation.
+	Mode Mode
+
+	// Dialect is the syntax of the rule files that Workers read. If it is
+	// DialectAuto, the default, it is chosen for each file by its name,
+	// so that a .dockerignore file is read as Docker reads it.
+	Dialect Dialect
+
+	// GitignoreSemantics makes Workers read files in DialectGitignore
+	// exactly as git does, rather than as rule files of this package with
+	// fewer features. Patterns are anchored to the directory of their file
+	// without cleaning them, so that "./a", "a/../b", and "//a" match
+	// nothing, "**" that is not a whole element of the path is a single "*",
+	// and character classes can be negated with "!" and contain POSIX
+	// classes such as "[:digit:]".
+	GitignoreSemantics bool
+
+	// DenyByDefault makes Workers match every file that no rule or layer
+	// decides on, so that nothing passes unless a negated rule includes it,
+	// as in an allowlist for packaging or uploads: "!src/**" followed by
+	// "src/**/*_test.go" lets only the sources that are not tests pass.
+	// Directories are not matched by default, so that walking descends into
+	// them to find the files that are included. Paths that no rule decides
+	// on are passed to os.Lstat to tell whether they are directories, unless
+	// they end in a separator; paths that do not exist are matched.
+	DenyByDefault bool
+
+	// DiscoverDepth, if it is positive, makes NewWorker also read the
+	// configuration files in the subdirectories of the working directory,
+	// down to DiscoverDepth levels beneath it, so that a single Worker
+	// answers for any path in the tree as a Worker created in the directory
+	// of the path would, without walking it with Walk. The rules of such
+	// a file only apply beneath its directory, and take precedence over those
+	// of the files in its parents. Directories that are matched, or that
+	// contain a sentinel and thus belong to another project, are not scanned.
+	DiscoverDepth int
+
+	// PoolSize is the number of Workers that WorkerFor keeps. If it is zero
+	// or negative, DefaultPoolSize is used.
+	PoolSize int
+
+	// Symlinks determines how CopyDir and CopyFS copy symbolic links.
+	// The default, SymlinkCopy, copies the links themselves.
+	Symlinks SymlinkPolicy
+
+	names    []string
+	global   []Rule
+	files    []Rule
+	disabled [numScopes]bool
+	pool     workerPool
 }
 
 // New creates a new Matcher, which contains only global globs.
 // A global glob is a glob that only applies to the basename,
-// and hence does not have any slashes ("/").
+// and hence does not have any slashes ("/"), unless it is added
+// with AddPath.
+//
+// The configs are the names of the configuration files, in order of
+// precedence; empty names are ignored, so New("") creates a Matcher that
+// reads no configuration files.
 //
 // Matcher is safe to use concurrently, as long as you don't add any globs.
 // If it is necessary to add local globs, use a Worker.
-func New(config string) *Matcher {
-	return &Matcher{
-		config: config,
-		global: make([]string, 0),
+func New(configs ...string) *Matcher {
+	m := &Matcher{
+		global: make([]Rule, 0),
+	}
+	for _, c := range configs {
+		if c != "" {
+			m.names = append(m.names, c)
+		}
+	}
+	if out := debugWriter(); out != nil {
+		m.Trace = out
+		m.Logger = log.New(out, "matcher: ", 0)
 	}
+	return m
 }
 
 // Add adds the globs to the global matcher.
-// None of the globs may contain a path character.
+// None of the globs may contain a path character. A glob with brace
+// expressions, such as "*.{jpg,png}", adds each glob it expands to.
 func (m *Matcher) Add(globs ...string) error {
+	defer m.resetPool()
 	return addAll(&m.global, globs)
 }
 
-// Matches returns true if any of the global globs matches.
+// AddPath is like Add, but the globs may contain a path separator, as in
+// rule files. Such a glob is joined to the root of the Matcher, which is Root
+// if it is set and the working directory of the process otherwise, and only
+// matches directories if it has a trailing slash. Globs without a separator
+// are added as by Add.
 //
-// There should be no errors in matching, because globs are checked with the
-// Check function. If there is an error, however, the function panics with the
-// error.
+// Once a glob with a separator is added, Matches resolves relative paths
+// against the root and matches whole paths instead of base names. Workers
+// of the Matcher apply these globs as well.
+func (m *Matcher) AddPath(globs ...string) error {
+	root, err := m.root()
+	if err != nil {
+		return err
+	}
+	var rules []Rule
+	for _, glob := range globs {
+		alts, err := expandPattern(glob)
+		if err != nil {
+			return err
+		}
+		for _, g := range alts {
+			r := pathRule(root, g)
+			rules = append(rules, r)
+			compiled(r.Glob)
+		}
+	}
+	defer m.resetPool()
+	m.global = append(m.global, rules...)
+	return nil
+}
+
+// pathRule returns the rule that AddPath adds for the checked glob g.
+func pathRule(root, g string) Rule {
+	if !strings.Contains(g, "/") {
+		return Rule{Glob: g}
+	}
+	g, dir := trimSlash(g)
+	if strings.Contains(g, "/") {
+		g = join(root, g)
+	}
+	return Rule{Glob: g, DirOnly: dir}
+}
+
+// root returns the absolute directory that AddPath joins globs to.
+func (m *Matcher) root() (string, error) {
+	if m.Root != "" {
+		return filepath.Abs(m.Root)
+	}
+	return os.Getwd()
+}
+
+// Remove removes every glob equal to glob that was added to the global
+// matcher with Add, AddPath, or SetGlobs, and reports whether there was any.
+// Workers that were created before keep it, as they do with globs added
+// afterwards. A glob with brace expressions removes each glob it expands to.
+func (m *Matcher) Remove(glob string) bool {
+	global, removed := withoutGlob(m.global, glob)
+	if root, err := m.root(); err == nil && strings.Contains(glob, "/") {
+		if alts, err := expandBraces(glob); err == nil {
+			for _, g := range alts {
+				var ok bool
+				global, ok = without(global, pathRule(root, g))
+				removed = removed || ok
+			}
+		}
+	}
+	if removed {
+		m.global = global
+		m.resetPool()
+	}
+	return removed
+}
+
+// SetGlobs replaces all globs of the global matcher with globs, as if they
+// were added with Add to a new Matcher. If any of them is invalid, none are
+// changed. Workers that were created before keep the old globs.
+func (m *Matcher) SetGlobs(globs []string) error {
+	var global []Rule
+	if err := addAll(&global, globs); err != nil {
+		return err
+	}
+	m.global = global
+	m.resetPool()
+	return nil
+}
+
+// without returns a copy of rules without those equal to r,
+// and whether there were any; otherwise it returns rules itself.
+func without(rules []Rule, r Rule) ([]Rule, bool) {
+	var kept []Rule
+	for i, x := range rules {
+		if x != r {
+			if kept != nil {
+				kept = append(kept, x)
+			}
+			continue
+		}
+		if kept == nil {
+			kept = append(make([]Rule, 0, len(rules)-1), rules[:i]...)
+		}
+	}
+	if kept == nil {
+		return rules, false
+	}
+	return kept, true
+}
+
+// withoutGlob is without for the rules that Add adds for glob, which are
+// several if it has brace expressions.
+func withoutGlob(rules []Rule, glob string) ([]Rule, bool) {
+	globs, err := expandBraces(glob)
+	if err != nil {
+		globs = []string{glob}
+	}
+	removed := false
+	for _, g := range globs {
+		var ok bool
+		rules, ok = without(rules, Rule{Glob: g})
+		removed = removed || ok
+	}
+	return rules, removed
+}
+
+// Matches returns true if any of the global globs matches. Only the base
+// name of path is matched, unless globs with a path separator were added
+// with AddPath, in which case a relative path is relative to the root of
+// the Matcher.
 func (m *Matcher) Matches(path string) bool {
-	return matchAll(m.global, filepath.Base(path))
+	for _, r := range m.global {
+		if r.DirOnly || strings.Contains(r.Glob, "/") {
+			return matchAll(m.global, m.abs(path), m)
+		}
+	}
+	return matchAll(m.global, filepath.Base(path), m)
+}
+
+// abs returns path resolved against the root of the Matcher.
+func (m *Matcher) abs(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	root, err := m.root()
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(root, path)
 }
 
 // Worker is derived from Matcher, and loads globs from configurations.
 // Globs in configurations may be paths.
 //
-// For each concurrent use, a separate Worker is required.
+// For each concurrent use, a separate Worker is required, which Clone makes
+// cheaply without reading any files again. The exception is deciding
+// matches: Matches, MatchesInfo, and Match may be called from many
+// goroutines at once on a Worker that is not modified meanwhile, as long as
+// it has neither a cache nor profiling enabled, and its sinks are safe for
+// concurrent use.
 type Worker struct {
-	cwd    string
-	local  []string
-	global []string
+	cwd     string
+	root    string
+	local   []Rule
+	global  []Rule
+	above   []Layer
+	below   []Layer
+	hits    *hitCounters
+	profile map[Rule]*PatternProfile
+	cache   *resultCache
+
+	// disabled and disabledFiles are the scopes and rule files
+	// that are turned off in the Worker.
+	disabled      [numScopes]bool
+	disabledFiles map[string]bool
+
+	m       *Matcher
+	configs []config
+
+	// scanned are the directories that discover looked for configuration
+	// files in, which WorkerFor watches for new ones.
+	scanned []string
+
+	// shared is set to 1 once local may be shared with a clone, so that
+	// it must be copied before it is changed in place; see own.
+	shared *uint32
+
+	metrics MetricsSink
+	events  EventSink
+	logger  Logger
+	tracer  io.Writer
+	audit   io.Writer
+	visit   VisitFunc
 }
 
 // NewWorker creates a new Worker.
+//
+// If errors reading configuration files were collected because of the
+// Collect policy, NewWorker returns both the Worker and the errors as
+// ConfigErrors.
 func (m *Matcher) NewWorker(dir string) (*Worker, error) {
 	var err error
 
@@ -149,43 +671,287 @@ func (m *Matcher) NewWorker(dir string) (*Worker, error) {
 		}
 	}
 
-	w := &Worker{
-		cwd:    dir,
-		local:  make([]string, 0),
-		global: m.global,
+	w := m.newWorker(dir)
+	if dirs := m.configDirs(dir); m.isRoot(dirs[len(dirs)-1]) {
+		w.root = dirs[len(dirs)-1]
 	}
+	var errs ConfigErrors
 
 	// Read configuration files in each directory from
 	// the current till we reach the root.
-	// If m.config is not set, we skip this.
-	if m.config != "" {
-		for {
-			err := w.AddFile(filepath.Join(dir, m.config))
-			if err != nil && !os.IsNotExist(err) && m.ErrHandler != nil {
-				err = m.ErrHandler(err)
+	// If no configuration filename is set, we skip this.
+	if len(m.names) > 0 && !m.disabled[ScopeProject] {
+		for _, d := range m.configDirs(dir) {
+			for _, name := range m.configNames() {
+				err := w.load(filepath.Join(d, name), ScopeProject, &errs)
 				if err != nil {
 					return nil, err
 				}
 			}
+		}
+	}
 
-			dir = filepath.Clean(filepath.Join(dir, ".."))
-			if dir == "/" {
-				break
+	if err := w.loadUser(&errs); err != nil {
+		return nil, err
+	}
+	if err := w.discover(&errs); err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return w, errs
+	}
+	return w, nil
+}
+
+// newWorker returns a Worker in the absolute directory dir
+// without any configuration files.
+func (m *Matcher) newWorker(dir string) *Worker {
+	w := &Worker{
+		m:       m,
+		cwd:     dir,
+		local:   make([]Rule, 0),
+		shared:  new(uint32),
+		hits:    new(hitCounters),
+		metrics: m.Metrics,
+		events:  m.Events,
+		logger:  m.Logger,
+		tracer:  m.Trace,
+		audit:   m.Audit,
+	}
+	if !m.disabled[ScopeSession] {
+		w.global = m.global
+	}
+	return w
+}
+
+// loadUser reads the rule files of the user and system scopes
+// for NewWorker.
+func (w *Worker) loadUser(errs *ConfigErrors) error {
+	m := w.m
+	if !m.disabled[ScopeUser] {
+		for _, path := range []string{m.userFile(), m.homeFile()} {
+			if path == "" || w.loaded(path) {
+				continue
+			}
+			if err := w.load(path, ScopeUser, errs); err != nil {
+				return err
 			}
 		}
+		if len(m.files) > 0 {
+			rules := make([]Rule, len(m.files))
+			for i, r := range m.files {
+				rules[i] = w.globalRule(r)
+			}
+			w.insert(rules...)
+		}
 	}
-	return w, nil
+	if m.SystemFile != "" && !m.disabled[ScopeSystem] {
+		if err := w.load(m.SystemFile, ScopeSystem, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load reads a configuration file for NewWorker. A missing file is not an
+// error, and other errors are handled according to the policy of the
+// Matcher; collected errors are appended to errs.
+func (w *Worker) load(path string, s Scope, errs *ConfigErrors) error {
+	return w.handleLoad(path, w.addFile(path, s), errs)
+}
+
+// handleLoad handles the error err from reading the configuration file
+// path as described for load.
+func (w *Worker) handleLoad(path string, err error, errs *ConfigErrors) error {
+	if err == nil {
+		w.logf("loaded %s", path)
+		return nil
+	}
+	if os.IsNotExist(err) {
+		return nil
+	}
+	w.logf("error loading %s: %s", path, err)
+	if !isPatternError(err) && w.m.Strict {
+		return err
+	}
+
+	p, err := w.m.policy(path, err)
+	switch p {
+	case Abort:
+		return err
+	case Collect:
+		*errs = append(*errs, err)
+	}
+	return nil
+}
+
+// isPatternError reports whether err is about invalid lines of a rule file.
+func isPatternError(err error) bool {
+	switch err.(type) {
+	case *BadPatternError, PatternErrors:
+		return true
+	}
+	return false
+}
+
+// Dir returns the working directory of the Worker, which is the cleaned,
+// absolute form of the directory passed to NewWorker.
+func (w *Worker) Dir() string {
+	return w.cwd
+}
+
+// file returns the file that Matches decides on for path, as described
+// there, or nil if path is empty.
+func (w *Worker) file(path string, fi os.FileInfo) *file {
+	if path == "" {
+		return nil
+	}
+	abs, _ := w.Resolve(path)
+	return &file{path: abs, fi: fi, dir: os.IsPathSeparator(path[len(path)-1])}
+}
+
+// Resolve returns the cleaned, absolute form of path that Matches uses,
+// and whether path was relative and hence resolved against the working
+// directory of the Worker. This helps to debug surprising results.
+func (w *Worker) Resolve(path string) (string, bool) {
+	path = filepath.Clean(path)
+	if filepath.IsAbs(path) {
+		return path, false
+	}
+	return filepath.Join(w.cwd, path), true
 }
 
 // Add adds the globs to the local matcher.
-// None of the globs may contain a path character.
+// None of the globs may contain a path character. A glob with brace
+// expressions, such as "*.{jpg,png}", adds each glob it expands to.
 func (w *Worker) Add(glob ...string) error {
-	return addAll(&w.local, glob)
+	var rules []Rule
+	err := addAll(&rules, glob)
+	if err != nil {
+		return err
+	}
+	w.insert(rules...)
+	return nil
+}
+
+// Remove removes every glob equal to glob that was added to the Worker with
+// Add or Push, and reports whether there was any. Rules read from files are
+// kept, and so are the globs of the Matcher. Handles returned by Push for
+// the glob have no effect anymore. A glob with brace expressions removes
+// each glob it expands to.
+func (w *Worker) Remove(glob string) bool {
+	local, removed := withoutGlob(w.local, glob)
+	if removed {
+		w.local, w.shared = local, new(uint32)
+		w.invalidate()
+	}
+	return removed
+}
+
+// Pushed is a set of globs added with Worker.Push.
+type Pushed struct {
+	w     *Worker
+	rules []Rule
+}
+
+// Push adds the globs to the local matcher like Add, and returns a handle
+// whose Close method removes exactly these globs again, leaving everything
+// else, such as the rules loaded from files, in place. This allows temporary
+// rules around a block of work:
+//
+//	p, err := w.Push("*.tmp")
+//	if err != nil {
+//	    return err
+//	}
+//	defer p.Close()
+//
+// If any of the globs is invalid, none of them are added.
+func (w *Worker) Push(glob ...string) (*Pushed, error) {
+	var rules []Rule
+	if err := addAll(&rules, glob); err != nil {
+		return nil, err
+	}
+	w.insert(rules...)
+	return &Pushed{w: w, rules: rules}, nil
+}
+
+// Close removes the globs from the Worker they were pushed to. If the same
+// glob has also been added otherwise, only one of them is removed, so that
+// the other one keeps having effect. Close always returns nil, and calling
+// it more than once has no further effect.
+func (p *Pushed) Close() error {
+	for _, r := range p.rules {
+		l := p.w.local
+		for i := len(l) - 1; i >= 0; i-- {
+			if l[i] == r {
+				p.w.own()
+				l = p.w.local
+				p.w.local = append(l[:i], l[i+1:]...)
+				p.w.invalidate()
+				break
+			}
+		}
+	}
+	p.rules = nil
+	return nil
 }
 
 // AddFile loads a file containing globs. The format of the file
-// is similar to gitignore.
+// is similar to gitignore. Files compressed with gzip are decompressed
+// transparently.
 func (w *Worker) AddFile(path string) error {
+	return w.addFile(path, ScopeSession)
+}
+
+// AddReader loads globs from r in the same format as AddFile, for rules
+// that do not come from a file, such as those generated from a database.
+// The name is used as the Source of the rules and for error reporting,
+// and it chooses the dialect as for files. Globs containing a path separator
+// are anchored to the working directory of the Worker.
+func (w *Worker) AddReader(r io.Reader, name string) error {
+	return w.addReader(r, name, w.cwd, ScopeSession)
+}
+
+// AddString is like AddReader for the lines in s, with the name "(string)".
+func (w *Worker) AddString(s string) error {
+	return w.AddReader(strings.NewReader(s), "(string)")
+}
+
+// Progress describes how far AddFileContext has got with reading a file.
+type Progress struct {
+	// Bytes is the number of bytes read from the file, before it is
+	// decompressed.
+	Bytes int64
+
+	// Lines is the number of lines read.
+	Lines int
+
+	// Rules is the number of rules added.
+	Rules int
+}
+
+// progressInterval is the number of lines after which AddFileContext
+// checks its context and reports progress.
+const progressInterval = 1024
+
+// AddFileContext is like AddFile, but meant for enormous, generated rule
+// files. The file is read incrementally, so that only the rules are kept in
+// memory, and at regular intervals, ctx is checked and progress, if it
+// is not nil, is called, as well as once at the end. If ctx is done, reading
+// stops, the rules added from the file so far are removed again, and the
+// error of ctx is returned.
+//
+// Matcher.MaxFileSize applies as for AddFile, so it must be set to a negative
+// value for files larger than DefaultMaxFileSize.
+func (w *Worker) AddFileContext(ctx context.Context, path string, progress func(Progress)) error {
+	return w.addFileContext(ctx, path, ScopeSession, progress)
+}
+
+func (w *Worker) addFile(path string, s Scope) error {
+	return w.addFileContext(context.Background(), path, s, nil)
+}
+
+func (w *Worker) addFileContext(ctx context.Context, path string, s Scope, progress func(Progress)) error {
 	abs, err := filepath.Abs(path)
 	if err != nil {
 		return err
@@ -194,31 +960,172 @@ func (w *Worker) AddFile(path string) error {
 	if err != nil {
 		return err
 	}
+	defer f.Close()
+
+	err = w.read(ctx, f, path, filepath.Dir(abs), s, progress)
+	if _, ok := err.(PatternErrors); err != nil && !ok {
+		return err
+	}
+	c := config{path: abs, name: path, scope: s}
+	if fi, err := f.Stat(); err == nil {
+		c.modTime, c.size = fi.ModTime(), fi.Size()
+	}
+	w.configs = append(w.configs, c)
+	w.count(MetricConfigsLoaded)
+	return err
+}
+
+// addReader reads globs from r in the same format as AddFile into the
+// given scope. The name is used for error reporting, and globs containing
+// a path separator are joined to base.
+func (w *Worker) addReader(r io.Reader, name, base string, scope Scope) error {
+	return w.read(context.Background(), r, name, base, scope, nil)
+}
+
+// read is addReader with the context and progress of AddFileContext.
+func (w *Worker) read(ctx context.Context, r io.Reader, name, base string, scope Scope, progress func(Progress)) error {
+	cr := &countReader{r: r}
+	r, err := decompress(cr, name)
+	if err != nil {
+		return err
+	}
+	if max := w.m.maxFileSize(); max > 0 {
+		r = &limitReader{r: r, n: max, name: name}
+	}
+	if extract, ok := lookupExtractor(name); ok {
+		lines, err := extract(r)
+		if err != nil {
+			return &os.PathError{Op: "extract", Path: name, Err: err}
+		}
+		r = strings.NewReader(strings.Join(lines, "\n"))
+	}
+
+	// The rules of the file end up after those already in its scope.
+	start := len(w.local)
+	for start > 0 && w.local[start-1].Scope > scope {
+		start--
+	}
 
-	var line int
-	base := filepath.Dir(abs)
-	sc := bufio.NewScanner(f)
+	var (
+		line, added int
+		errs        PatternErrors
+	)
+	p := newParser(w.m.dialect(name))
+	if !p.dialect.valid() {
+		return &os.PathError{Op: "read", Path: name, Err: ErrUnknownDialect}
+	}
+	p.mode = w.m.Mode
+	p.git = w.m.GitignoreSemantics && p.dialect == DialectGitignore
+	report := func() {
+		if progress != nil {
+			progress(Progress{Bytes: cr.n, Lines: line, Rules: added})
+		}
+	}
+	sc := bufio.NewScanner(r)
 	for sc.Scan() {
 		line++
+		if line%progressInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				w.own()
+				w.local = append(w.local[:start], w.local[start+added:]...)
+				w.invalidate()
+				return err
+			}
+			report()
+		}
 
 		s := Clean(sc.Text())
 		if s == "" {
 			continue
 		}
-		err = Check(s)
+		rules, err := p.parseLine(s)
+		if err != nil && p.mode == ModeLenient {
+			if rules, err = p.repair(s, err); err != nil {
+				w.logf("skipping line %d of %s: %s", line, name, err.(*BadPatternError).Err)
+				continue
+			}
+		}
 		if err != nil {
 			pe := err.(*BadPatternError)
 			pe.Line = line
-			pe.File = path
+			pe.File = name
+			if p.mode == ModeCollect {
+				errs = append(errs, pe)
+				continue
+			}
 			return pe
 		}
 
-		if strings.Contains(s, "/") {
-			s = filepath.Join(base, s)
+		for _, r := range rules {
+			if p.git {
+				r.Glob = gitAnchor(r.Glob, base)
+			} else {
+				w.anchorRule(&r, base)
+			}
+			r.Source, r.Line, r.Scope = name, line, scope
+			w.insert(r)
+			added++
 		}
-		w.local = append(w.local, s)
 	}
-	return sc.Err()
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if p.dialect == DialectRsync {
+		// The first matching rule decides, so the last must come first.
+		rules := w.local[start : start+added]
+		for i, j := 0, len(rules)-1; i < j; i, j = i+1, j-1 {
+			rules[i], rules[j] = rules[j], rules[i]
+		}
+	}
+	report()
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// countReader counts the bytes read from r.
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// anchor joins glob to base if it contains a path separator, or to the
+// workspace root if it starts with "//".
+func (w *Worker) anchor(glob, base string) string {
+	switch {
+	case strings.HasPrefix(glob, "//"):
+		return join(w.workspace(), glob[2:])
+	case strings.Contains(glob, "/"):
+		return join(base, glob)
+	default:
+		return glob
+	}
+}
+
+// anchorRule anchors the glob of r, which was read from a file in base,
+// or builds base into it if it is a regular expression.
+func (w *Worker) anchorRule(r *Rule, base string) {
+	if r.Regexp {
+		r.Glob = anchorRegexp(r.Glob, base)
+		return
+	}
+	r.Glob = w.anchor(r.Glob, base)
+}
+
+// workspace returns the root of the Worker, or its working directory
+// if it has none.
+func (w *Worker) workspace() string {
+	if w.root != "" {
+		return w.root
+	}
+	return w.cwd
 }
 
 // Reset clears the set of local globs,
@@ -226,66 +1133,277 @@ func (w *Worker) AddFile(path string) error {
 // through loading configs.
 func (w *Worker) Reset() {
 	w.local = w.local[:0]
+	w.invalidate()
 }
 
-// Matches returns true if any of the global or local globs matches.
+// Matches returns true if any of the global or local globs matches,
+// and the one of them with the highest precedence is not negated.
 //
-// There should be no errors in matching, because globs are checked with the
-// Check function. If there is an error, however, the function panics with the
-// error.
+// A relative path is relative to the working directory of the Worker. The
+// path is cleaned lexically: "." elements are dropped and ".." elements drop
+// the element before them, so "./foo" and "foo/./bar" are the same as "foo"
+// and "foo/bar". A trailing separator, as in "build/", says that the path is
+// a directory, so rules that only match directories match it without calling
+// os.Lstat. The empty path is never matched.
 func (w *Worker) Matches(path string) bool {
-	path = filepath.Clean(path)
-	if path == "" {
+	return w.MatchesInfo(path, nil)
+}
+
+// MatchesInfo is like Matches, but rules with predicates are evaluated
+// against fi instead of the result of os.Lstat on the path.
+// If fi is nil, os.Lstat is called only when a predicate needs it.
+func (w *Worker) MatchesInfo(path string, fi os.FileInfo) bool {
+	f := w.file(path, fi)
+	if f == nil {
 		return false
 	}
-	if !filepath.IsAbs(path) {
-		path = filepath.Clean(filepath.Join(w.cwd, path))
-	}
+	path = f.path
 
-	for _, l := range [][]string{w.global, w.local} {
-		if matchAll(l, path) {
-			return true
+	if m, ok := w.cached(path); ok && w.audit == nil {
+		if m {
+			w.count(MetricMatches)
+		} else {
+			w.count(MetricMisses)
 		}
+		w.tracef("%s: cached decision %v", path, m)
+		return m
 	}
-	return false
+	m := w.decide(f)
+	if w.cache != nil && !f.used {
+		w.cache.results[path] = m
+	}
+	return m
 }
 
-func match(pattern, s string) bool {
-	if pattern == "" {
-		return false
+// MatchResult describes what decided whether a path is matched.
+type MatchResult struct {
+	// Pattern is the rule that decided, as it would be written in a rule
+	// file, with its predicates. Globs containing a path separator are
+	// joined to the directory of their file.
+	Pattern string
+
+	// Source and Line are the file and line the rule was read from.
+	// Source is empty for globs added with Add.
+	Source string
+	Line   int
+
+	// Rule is the rule that decided. If it is negated, the path is not
+	// matched because of it.
+	Rule Rule
+
+	// Layer is the layer that matched the path, if the decision was made
+	// by a layer rather than a rule. Pattern, Source, and Line are empty
+	// then.
+	Layer Layer
+}
+
+// Match is like Matches, but also returns what decided, so that it can be
+// shown to users wondering why a file is ignored. If nothing decided, the
+// MatchResult is the zero value, and the path is only matched if it is by
+// Matcher.DenyByDefault. A negated rule that decides is returned as
+// well, along with false.
+//
+// The cache of the Worker is not consulted, but the decision is counted
+// and traced as for Matches.
+func (w *Worker) Match(path string) (MatchResult, bool) {
+	f := w.file(path, nil)
+	if f == nil {
+		return MatchResult{}, false
+	}
+	return w.decision(f)
+}
+
+// decide returns whether f is matched, without consulting the cache.
+func (w *Worker) decide(f *file) bool {
+	_, m := w.decision(f)
+	return m
+}
+
+// decision returns what decided whether f is matched, and the decision.
+func (w *Worker) decision(f *file) (MatchResult, bool) {
+	res, m := w.decideRules(f)
+	if w.audit != nil {
+		w.record(f.path, res, m)
+	}
+	return res, m
+}
+
+func (w *Worker) decideRules(f *file) (MatchResult, bool) {
+	path := f.path
+	for _, l := range w.above {
+		if l.Matches(path) {
+			w.count(MetricMatches)
+			w.tracef("%s: matched by layer %T", path, l)
+			w.emit(EventMatched, path, Rule{})
+			return MatchResult{Layer: l}, true
+		}
+	}
+	for i, l := range [][]Rule{w.global, w.local} {
+		if i == 0 && w.disabled[ScopeSession] {
+			continue
+		}
+		if r, ok := w.matchFirst(l, f); ok {
+			w.hit(r, path)
+			res := MatchResult{Pattern: r.line(), Source: r.Source, Line: r.Line, Rule: r}
+			if r.Negate {
+				w.count(MetricMisses)
+				w.tracef("%s: excluded by %s", path, r.describe())
+				w.emit(EventNegated, path, r)
+				return res, false
+			}
+			w.count(MetricMatches)
+			w.tracef("%s: matched by %s", path, r.describe())
+			w.emit(EventMatched, path, r)
+			return res, true
+		}
+	}
+	for _, l := range w.below {
+		if l.Matches(path) {
+			w.count(MetricMatches)
+			w.tracef("%s: matched by layer %T", path, l)
+			w.emit(EventMatched, path, Rule{})
+			return MatchResult{Layer: l}, true
+		}
 	}
-	if !strings.Contains(pattern, "/") {
-		s = filepath.Base(s)
+	if w.m.DenyByDefault && !(Rule{DirOnly: true}).test(f) {
+		w.count(MetricMatches)
+		w.tracef("%s: matched by default", path)
+		w.emit(EventMatched, path, Rule{})
+		return MatchResult{}, true
 	}
-	m, err := filepath.Match(pattern, s)
+	w.count(MetricMisses)
+	w.tracef("%s: no match", path)
+	return MatchResult{}, false
+}
+
+// MatchPattern reports whether path is matched by pattern, as it would be
+// by a rule consisting of pattern alone. A pattern without a path separator
+// is matched against the last element of path, so "*.o" matches "obj/a.o";
+// a pattern with a separator is matched against the whole path. Unlike in
+// rule files, such a pattern is not joined to any directory, so it should be
+// of the same form as path, either relative or absolute.
+//
+// The only possible error is a BadPatternError for an invalid pattern.
+func MatchPattern(pattern, path string) (bool, error) {
+	globs, err := expandPattern(pattern)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
-	return m
+	return matchGlobs(globs, path), nil
 }
 
-func matchAll(patterns []string, s string) bool {
+// MatchAny reports whether path is matched by any of the patterns, with the
+// same semantics as MatchPattern. All patterns are checked before matching,
+// so an invalid pattern is reported even if an earlier one matches.
+//
+// The only possible error is a BadPatternError for the first invalid pattern.
+func MatchAny(patterns []string, path string) (bool, error) {
+	var globs []string
 	for _, p := range patterns {
-		if match(p, s) {
+		g, err := expandPattern(p)
+		if err != nil {
+			return false, err
+		}
+		globs = append(globs, g...)
+	}
+	return matchGlobs(globs, path), nil
+}
+
+// matchGlobs reports whether path is matched by any of the checked globs.
+func matchGlobs(globs []string, path string) bool {
+	for _, g := range globs {
+		if match(g, path) {
 			return true
 		}
 	}
 	return false
 }
 
-func add(list *[]string, glob string) error {
-	err := Check(glob)
+// match reports whether s is matched by the checked pattern, which is
+// compiled once and then kept in a cache.
+func match(pattern, s string) bool {
+	return compiled(pattern).Match(s)
+}
+
+// match is like the function match, but applies the options of m
+// that change how patterns are matched.
+func (m *Matcher) match(pattern, s string) bool {
+	if decomposeNames {
+		pattern, s = nfd(pattern), nfd(s)
+	}
+	if m.CaseFold != FoldNone {
+		pattern, s = m.CaseFold.fold(pattern), m.CaseFold.fold(s)
+	}
+	if m.Graphemes {
+		// The placeholders for clusters depend on s, so the pattern
+		// cannot be cached.
+		pattern, s = graphemes(pattern, s)
+		return compilePattern(pattern).Match(s)
+	}
+	return match(pattern, s)
+}
+
+// matchRule reports whether the glob or regular expression of r
+// matches path.
+func (m *Matcher) matchRule(r Rule, path string) bool {
+	if !r.Regexp {
+		return m.match(r.Glob, path)
+	}
+	if m.CaseFold != FoldNone {
+		return matchRegexp("(?i)"+r.Glob, path)
+	}
+	return matchRegexp(r.Glob, path)
+}
+
+func matchAll(rules []Rule, s string, m *Matcher) bool {
+	_, ok := matchFirst(rules, &file{path: s}, m)
+	return ok
+}
+
+func matchFirst(rules []Rule, f *file, m *Matcher) (Rule, bool) {
+	return precedence(rules, func(r Rule) bool {
+		return m.matchRule(r, f.path) && r.test(f)
+	})
+}
+
+// precedence returns the first rule for which ok is true, going through the
+// rules in order of precedence: the rules of a file come before those of the
+// files after it, but within a file, later rules come first, so that they
+// can make exceptions to earlier ones.
+func precedence(rules []Rule, ok func(Rule) bool) (Rule, bool) {
+	for i := 0; i < len(rules); {
+		j := i + 1
+		for j < len(rules) && rules[j].Source == rules[i].Source && rules[j].Scope == rules[i].Scope {
+			j++
+		}
+		for k := j - 1; k >= i; k-- {
+			if ok(rules[k]) {
+				return rules[k], true
+			}
+		}
+		i = j
+	}
+	return Rule{}, false
+}
+
+func add(list *[]Rule, glob string) error {
+	globs, err := expandPattern(glob)
 	if err != nil {
 		return err
 	}
-	if strings.Contains(glob, "/") {
-		return ErrGlobIsPath
+	for _, g := range globs {
+		if strings.Contains(g, "/") {
+			return ErrGlobIsPath
+		}
+	}
+	for _, g := range globs {
+		*list = append(*list, Rule{Glob: g})
+		compiled(g)
 	}
-	*list = append(*list, glob)
 	return nil
 }
 
-func addAll(list *[]string, globs []string) error {
+func addAll(list *[]Rule, globs []string) error {
 	for _, g := range globs {
 		err := add(list, g)
 		if err != nil {
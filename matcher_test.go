@@ -5,7 +5,14 @@
 package matcher
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -123,3 +130,567 @@ func TestMatcher(fw *testing.T) {
 		}
 	}
 }
+
+func TestLocalOverride(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+	ioutil.WriteFile(filepath.Join(dir, "match.conf"), []byte("*.o\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "match.conf.local"), []byte("*.swp\n"), 0644)
+	ioutil.WriteFile(filepath.Join(sub, "match.conf.local"), []byte("notes\n"), 0644)
+
+	w, err := New("match.conf").NewWorker(sub)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	for _, p := range []string{"main.o", "main.swp", "notes"} {
+		if !w.Matches(p) {
+			fw.Errorf("w.Matches(%q) = false, expected true", p)
+		}
+	}
+
+	// The local override is consulted before the configuration file.
+	var sources []string
+	for _, r := range w.Rules() {
+		sources = append(sources, filepath.Base(r.Source))
+	}
+	expected := []string{"match.conf.local", "match.conf.local", "match.conf"}
+	if !reflect.DeepEqual(sources, expected) {
+		fw.Errorf("rules come from %q, expected %q", sources, expected)
+	}
+}
+
+func TestResolve(fw *testing.T) {
+	w, err := New("").NewWorker("tests/../tests/dead/")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	dir, _ := filepath.Abs("tests/dead")
+	if w.Dir() != dir {
+		fw.Errorf("w.Dir() = %q, expected %q", w.Dir(), dir)
+	}
+
+	tests := []struct {
+		Path     string
+		Abs      string
+		Relative bool
+	}{
+		{"foo", filepath.Join(dir, "foo"), true},
+		{"./good/../foo/", filepath.Join(dir, "foo"), true},
+		{"..", filepath.Dir(dir), true},
+		{"/tmp//x/", "/tmp/x", false},
+	}
+	for _, t := range tests {
+		abs, rel := w.Resolve(t.Path)
+		if abs != t.Abs || rel != t.Relative {
+			fw.Errorf("w.Resolve(%q) = (%q, %v), expected (%q, %v)", t.Path, abs, rel, t.Abs, t.Relative)
+		}
+	}
+}
+
+func TestPathForms(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "build"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "foo"), nil, 0644)
+
+	w, err := New("").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if err := w.AddReader(strings.NewReader("build/\ngen/\nfoo\nsrc/bar\n"), "rules"); err != nil {
+		fw.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"":             false,
+		".":            false,
+		"foo":          true,
+		"./foo":        true,
+		"foo/":         true,
+		"x/../foo":     true,
+		"src/bar":      true,
+		"src/./bar":    true,
+		"./src//bar":   true,
+		"src/x/../bar": true,
+		"build":        true,
+		"build/":       true,
+		"./build/.":    true,
+		"gen":          false,
+		"gen/":         true,
+		"./gen/":       true,
+		"gen/.":        false,
+	}
+	for k, v := range tests {
+		p := filepath.FromSlash(k)
+		if m := w.Matches(p); m != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", p, m, v)
+		}
+		if _, m := w.Match(p); m != v {
+			fw.Errorf("w.Match(%q) matched = %v, expected %v", p, m, v)
+		}
+	}
+
+	// A trailing separator does not override the info that is given.
+	fi, err := os.Lstat(filepath.Join(dir, "foo"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if w.MatchesInfo("gen"+string(filepath.Separator), fi) {
+		fw.Errorf("w.MatchesInfo(%q, fi of a file) = true, expected false", "gen/")
+	}
+
+	// A decision that relied on the hint is not cached.
+	w.EnableCache(0)
+	if !w.Matches("gen/") || w.Matches("gen") {
+		fw.Errorf("with cache: w.Matches(gen/), w.Matches(gen) = %v, %v, expected true, false", w.Matches("gen/"), w.Matches("gen"))
+	}
+}
+
+func TestMatchPattern(fw *testing.T) {
+	tests := map[[2]string]bool{
+		{"*.o", "obj/a.o"}:         true,
+		{"*.o", "obj/a.go"}:        false,
+		{"obj/*.o", "obj/a.o"}:     true,
+		{"obj/*.o", "src/obj/a.o"}: false,
+		{"/src/*", "/src/main.go"}: true,
+		{"\\*", "*"}:               true,
+	}
+	for k, v := range tests {
+		m, err := MatchPattern(k[0], k[1])
+		if err != nil || m != v {
+			fw.Errorf("MatchPattern(%q, %q) = (%v, %v), expected %v", k[0], k[1], m, err, v)
+		}
+	}
+
+	for _, p := range []string{"a[", ""} {
+		if _, err := MatchPattern(p, "a"); err == nil {
+			fw.Errorf("MatchPattern(%q) succeeded", p)
+		} else if _, ok := err.(*BadPatternError); !ok {
+			fw.Errorf("MatchPattern(%q) returned %T, expected *BadPatternError", p, err)
+		}
+	}
+}
+
+func TestMatchAny(fw *testing.T) {
+	patterns := []string{"*.o", "build/*", "vendor"}
+	tests := map[string]bool{
+		"obj/a.o":       true,
+		"build/main":    true,
+		"src/build/x":   false,
+		"src/vendor":    true,
+		"main.go":       false,
+		"build/sub/one": false,
+	}
+	for k, v := range tests {
+		m, err := MatchAny(patterns, k)
+		if err != nil || m != v {
+			fw.Errorf("MatchAny(%q, %q) = (%v, %v), expected %v", patterns, k, m, err, v)
+		}
+	}
+
+	if m, err := MatchAny(nil, "a"); m || err != nil {
+		fw.Errorf("MatchAny(nil) = (%v, %v), expected false", m, err)
+	}
+	if _, err := MatchAny([]string{"*", "a["}, "a"); err == nil {
+		fw.Errorf("MatchAny with invalid pattern after a match succeeded")
+	}
+}
+
+func TestWorkspacePattern(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "services", "api")
+	os.MkdirAll(sub, 0755)
+	ioutil.WriteFile(filepath.Join(dir, "WORKSPACE"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(sub, "rules"), []byte("//build/*\nbuild/*\n"), 0644)
+
+	m := New("rules")
+	m.Sentinels = []string{"WORKSPACE"}
+	w, err := m.NewWorker(sub)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	tests := map[string]bool{
+		filepath.Join(dir, "build", "x"):        true,
+		filepath.Join(sub, "build", "x"):        true,
+		filepath.Join(dir, "services", "x"):     false,
+		filepath.Join(dir, "build", "sub", "x"): false,
+	}
+	for k, v := range tests {
+		if m := w.Matches(k); m != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, m, v)
+		}
+	}
+
+	w, err = New("rules").NewWorker(sub)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if w.Matches(filepath.Join(dir, "build", "x")) {
+		fw.Errorf("without a workspace root, //build/* is not relative to the working directory")
+	}
+}
+
+func TestPush(fw *testing.T) {
+	w := testWorker(fw)
+	w.Add("*.tmp")
+	n := len(w.Rules())
+
+	p, err := w.Push("*.tmp", "*.log")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if !w.Matches("a.log") || len(w.Rules()) != n+2 {
+		fw.Errorf("w.Push() did not add the globs: %v", w.Rules())
+	}
+	p.Close()
+	p.Close()
+	if w.Matches("a.log") || !w.Matches("a.tmp") || len(w.Rules()) != n {
+		fw.Errorf("p.Close() did not remove exactly the pushed globs: %v", w.Rules())
+	}
+
+	if _, err := w.Push("*.c", "a/b"); err != ErrGlobIsPath {
+		fw.Errorf("w.Push(a/b) = %v, expected %v", err, ErrGlobIsPath)
+	}
+	if w.Matches("main.c") {
+		fw.Errorf("failed w.Push changed the worker")
+	}
+}
+
+// TestDirectorySemantics checks the distinctions between "dir/", "dir/*",
+// and "dir/**", and how negations behave with each of them, both for the
+// decision about a single path and for walking the tree.
+func TestDirectorySemantics(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"dir/sub", "other"} {
+		os.MkdirAll(filepath.Join(dir, d), 0755)
+	}
+	for _, f := range []string{"dir/a.txt", "dir/keep", "dir/sub/b.txt", "dir/sub/keep", "other/dir"} {
+		ioutil.WriteFile(filepath.Join(dir, f), nil, 0644)
+	}
+
+	type result struct {
+		Excluded []string
+		Matches  map[string]bool
+	}
+	all := []string{"dir/a.txt", "dir/keep", "dir/sub/b.txt", "dir/sub/keep"}
+	tests := map[string]result{
+		"dir/": {all, map[string]bool{
+			"dir": true, "other/dir": false, "dir/a.txt": false,
+		}},
+		"dir/*": {all, map[string]bool{
+			"dir": false, "dir/a.txt": true, "dir/sub": true, "dir/sub/b.txt": false,
+		}},
+		"dir/**": {all, map[string]bool{
+			"dir": false, "dir/a.txt": true, "dir/sub": true, "dir/sub/b.txt": true,
+		}},
+		"dir\n": {append(all, "other/dir"), map[string]bool{
+			"dir": true, "other/dir": true,
+		}},
+		"dir/\n!dir/keep": {all, map[string]bool{
+			"dir": true, "dir/keep": false,
+		}},
+		"dir/*\n!dir/keep": {[]string{"dir/a.txt", "dir/sub/b.txt", "dir/sub/keep"}, map[string]bool{
+			"dir/keep": false, "dir/a.txt": true,
+		}},
+		"dir/**\n!dir/keep": {[]string{"dir/a.txt", "dir/sub/b.txt", "dir/sub/keep"}, map[string]bool{
+			"dir/keep": false, "dir/sub/keep": true,
+		}},
+		"dir/**\n!dir/sub/keep": {all, map[string]bool{
+			"dir/sub": true, "dir/sub/keep": false,
+		}},
+		"dir/**\n!dir/sub/\n!dir/sub/keep": {[]string{"dir/a.txt", "dir/keep", "dir/sub/b.txt"}, map[string]bool{
+			"dir/sub": false, "dir/sub/b.txt": true, "dir/sub/keep": false,
+		}},
+		"dir/**\n!**/keep": {[]string{"dir/a.txt", "dir/sub/b.txt", "dir/sub/keep"}, map[string]bool{
+			"dir/keep": false, "dir/sub/keep": false,
+		}},
+		"!dir/keep\ndir/*": {all, map[string]bool{
+			"dir/keep": true,
+		}},
+		"**/keep": {[]string{"dir/keep", "dir/sub/keep"}, map[string]bool{
+			"dir/keep": true, "keep": true,
+		}},
+		"dir/**/keep": {[]string{"dir/keep", "dir/sub/keep"}, map[string]bool{
+			"dir/keep": true, "dir/sub/keep": true, "keep": false,
+		}},
+		"*.txt\n!a.txt": {[]string{"dir/sub/b.txt"}, map[string]bool{
+			"dir/a.txt": false, "dir/sub/b.txt": true,
+		}},
+		"other/dir/": {nil, map[string]bool{
+			"other/dir": false,
+		}},
+	}
+
+	for k, v := range tests {
+		ioutil.WriteFile(filepath.Join(dir, "match.conf"), []byte(k), 0644)
+		w, err := New("match.conf").NewWorker(dir)
+		if err != nil {
+			fw.Fatal(err)
+		}
+		excluded, err := w.ListExcluded(".")
+		if err != nil {
+			fw.Fatal(err)
+		}
+		if !reflect.DeepEqual(excluded, v.Excluded) {
+			fw.Errorf("with %q: w.ListExcluded() = %q, expected %q", k, excluded, v.Excluded)
+		}
+		for p, e := range v.Matches {
+			if m := w.Matches(p); m != e {
+				fw.Errorf("with %q: w.Matches(%q) = %v, expected %v", k, p, m, e)
+			}
+		}
+	}
+}
+
+func TestDenyByDefault(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"src/sub", "docs"} {
+		os.MkdirAll(filepath.Join(dir, d), 0755)
+	}
+	for _, f := range []string{"src/a.go", "src/a_test.go", "src/sub/b.go", "docs/README", "LICENSE"} {
+		ioutil.WriteFile(filepath.Join(dir, f), nil, 0644)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "match.conf"), []byte("!src/**\nsrc/**/*_test.go\n!LICENSE\n"), 0644)
+
+	m := New("match.conf")
+	m.DenyByDefault = true
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	included, err := w.ListIncluded(".")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	expected := []string{"LICENSE", "src/a.go", "src/sub/b.go"}
+	if !reflect.DeepEqual(included, expected) {
+		fw.Errorf("w.ListIncluded() = %q, expected %q", included, expected)
+	}
+
+	tests := map[string]bool{
+		"docs":          false,
+		"docs/README":   true,
+		"src/a_test.go": true,
+		"src/a.go":      false,
+		"missing":       true,
+		"missing/":      false,
+		"match.conf":    true,
+	}
+	for k, v := range tests {
+		if r := w.Matches(k); r != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+	if res, ok := w.Match("docs/README"); !ok || res != (MatchResult{}) {
+		fw.Errorf("w.Match(%q) = %v, %v, expected the zero MatchResult, true", "docs/README", res, ok)
+	}
+}
+
+func TestAddFileContext(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var buf bytes.Buffer
+	for i := 0; i < 5*progressInterval; i++ {
+		fmt.Fprintf(&buf, "file%d\n", i)
+	}
+	path := filepath.Join(dir, "generated")
+	ioutil.WriteFile(path, buf.Bytes(), 0644)
+
+	w := testWorker(fw)
+	n := len(w.Rules())
+	var last Progress
+	var calls int
+	err = w.AddFileContext(context.Background(), path, func(p Progress) {
+		calls++
+		last = p
+	})
+	if err != nil {
+		fw.Fatal(err)
+	}
+	expected := Progress{Bytes: int64(buf.Len()), Lines: 5 * progressInterval, Rules: 5 * progressInterval}
+	if last != expected || calls != 6 {
+		fw.Errorf("last progress = %+v after %d calls, expected %+v after 6", last, calls, expected)
+	}
+	if !w.Matches("file4711") || len(w.Rules()) != n+5*progressInterval {
+		fw.Errorf("w.AddFileContext() did not add the rules of the file")
+	}
+
+	w = testWorker(fw)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = w.AddFileContext(ctx, path, func(p Progress) {
+		if p.Lines >= 2*progressInterval {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		fw.Errorf("w.AddFileContext() after cancel = %v, expected %v", err, context.Canceled)
+	}
+	if w.Matches("file1") || len(w.Rules()) != n || len(w.ConfigFiles()) != 1 {
+		fw.Errorf("w.AddFileContext() kept rules of a canceled file")
+	}
+}
+
+func TestAddReader(fw *testing.T) {
+	w := testWorker(fw)
+	if err := w.AddReader(strings.NewReader("*.gen\nout/*\n"), "db"); err != nil {
+		fw.Fatal(err)
+	}
+	if err := w.AddString("# generated\n*.cache\n"); err != nil {
+		fw.Fatal(err)
+	}
+	tests := map[string]bool{
+		"a.gen":       true,
+		"sub/a.cache": true,
+		"out/x":       true,
+		"sub/out/x":   false,
+	}
+	for k, v := range tests {
+		if r := w.Matches(k); r != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+	if res, _ := w.Match("a.gen"); res.Source != "db" || res.Line != 1 {
+		fw.Errorf("w.Match(%q) = %+v, expected source db at line 1", "a.gen", res)
+	}
+
+	err := w.AddString("ok\n[\n")
+	if pe, ok := err.(*BadPatternError); !ok || pe.File != "(string)" || pe.Line != 2 {
+		fw.Errorf("w.AddString() = %v, expected error at (string):2", err)
+	}
+}
+
+func TestMatchResult(fw *testing.T) {
+	w := testWorker(fw)
+	conf, _ := filepath.Abs(filepath.Join("tests", "match.conf"))
+	tests := map[string]MatchResult{
+		"match.conf": {Pattern: "match.conf"},
+		"brain/foo":  {Pattern: filepath.Join(filepath.Dir(conf), "brain/foo"), Source: conf, Line: 6},
+		"jack":       {},
+	}
+	for k, v := range tests {
+		res, ok := w.Match(k)
+		if ok != (v.Pattern != "") || res.Pattern != v.Pattern || res.Source != v.Source || res.Line != v.Line {
+			fw.Errorf("w.Match(%q) = (%+v, %v), expected %+v", k, res, ok, v)
+		}
+	}
+
+	o, _ := w.Overlay("jack")
+	if res, ok := w.Match("jack"); !ok || res.Layer != o {
+		fw.Errorf("w.Match() of a path matched by an overlay = (%+v, %v)", res, ok)
+	}
+}
+
+func TestRemove(fw *testing.T) {
+	m := New("match.conf")
+	m.Add("*.o", "*.a", "*.o")
+	before, err := m.NewWorker("tests")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if !m.Remove("*.o") || m.Remove("*.o") || m.Matches("a.o") || !m.Matches("a.a") {
+		fw.Error("m.Remove(\"*.o\") did not remove both globs")
+	}
+	if !before.Matches("a.o") {
+		fw.Error("m.Remove() changed a Worker created before")
+	}
+
+	w, err := m.NewWorker("tests")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if w.Matches("a.o") {
+		fw.Error("m.Remove() did not affect a new Worker")
+	}
+	w.Add("*.tmp")
+	p, _ := w.Push("*.tmp")
+	c := w.Clone()
+	if !w.Remove("*.tmp") || w.Remove("*.tmp") || w.Matches("a.tmp") || !c.Matches("a.tmp") {
+		fw.Error("w.Remove(\"*.tmp\") did not remove the globs of w only")
+	}
+	p.Close()
+	if w.Remove("foo") || !w.Matches("foo") {
+		fw.Error("w.Remove() removed a rule read from a file")
+	}
+
+	if err := m.SetGlobs([]string{"*.x", "a/b"}); err != ErrGlobIsPath || !m.Matches("a.a") {
+		fw.Errorf("m.SetGlobs() with a path = %v, expected %v and no change", err, ErrGlobIsPath)
+	}
+	if err := m.SetGlobs([]string{"*.x"}); err != nil || m.Matches("a.a") || !m.Matches("a.x") {
+		fw.Errorf("m.SetGlobs() = %v, expected only *.x to match", err)
+	}
+}
+
+func TestAddPath(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "src", "gen"), 0755)
+
+	m := New("")
+	m.Root = dir
+	if err := m.AddPath("/build", "src/*.o", "gen/", "*.tmp", "docs/{api,site}/"); err != nil {
+		fw.Fatal(err)
+	}
+	tests := map[string]bool{
+		"build":                           true,
+		"src/build":                       false,
+		"src/a.o":                         true,
+		"a.o":                             false,
+		"src/sub/a.o":                     false,
+		filepath.Join(dir, "src", "a.o"):  true,
+		"src/gen":                         true,
+		"gen":                             false,
+		"x/a.tmp":                         true,
+		"a.tmp":                           true,
+		filepath.Join(dir, "docs", "api"): false,
+	}
+	for k, v := range tests {
+		if r := m.Matches(k); r != v {
+			fw.Errorf("m.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+
+	w, err := m.NewWorker(filepath.Join(dir, "src"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if !w.Matches("a.o") || w.Matches("sub/a.o") || !w.Matches("../build") {
+		fw.Error("the Worker does not apply the globs added with AddPath")
+	}
+
+	if err := m.AddPath("a["); err == nil {
+		fw.Error("m.AddPath() with an invalid glob succeeded")
+	}
+	if !m.Remove("src/*.o") || m.Matches("src/a.o") {
+		fw.Error("m.Remove(\"src/*.o\") did not remove the glob added with AddPath")
+	}
+	if !m.Remove("gen/") || m.Matches("src/gen") {
+		fw.Error("m.Remove(\"gen/\") did not remove the glob added with AddPath")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoRule is returned by MatchSet.Remove for a pattern that is not
+// a rule of the Worker.
+var ErrNoRule = errors.New("no such rule")
+
+// MatchSet holds the decisions of a Worker for all files beneath a directory,
+// so that the effect of changing one rule can be computed without walking
+// the directory again, as is needed to show the results of editing a rule
+// file while it is being edited.
+//
+// A MatchSet has its own copy of the Worker, which its changes apply to.
+// The files are not watched, so it becomes stale when they change.
+type MatchSet struct {
+	w     *Worker
+	root  string
+	files []setFile
+}
+
+type setFile struct {
+	path, abs string
+	fi        os.FileInfo
+
+	// matched is the decision for the file itself, and excluded whether it
+	// is excluded because of it or because of a matched parent directory.
+	matched, excluded bool
+}
+
+// MatchSet walks root and returns the decisions of the Worker for all files
+// and directories beneath it. A relative root is relative to the working
+// directory of the Worker, and the names that the MatchSet reports are
+// formed as by Glob.
+func (w *Worker) MatchSet(root string) (*MatchSet, error) {
+	s := &MatchSet{w: w.quiet(), root: root}
+	err := s.w.walk(root, true, func(path, abs string, fi os.FileInfo, excluded bool) error {
+		s.files = append(s.files, setFile{path: path, abs: abs, fi: fi, excluded: excluded})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range s.files {
+		f := &s.files[i]
+		f.matched = f.excluded && s.w.MatchesInfo(f.abs, f.fi)
+	}
+	return s, nil
+}
+
+// Excluded returns the names of the files that are matched, including all
+// files within matched directories, as ListExcluded does.
+func (s *MatchSet) Excluded() []string {
+	return s.list(true)
+}
+
+// Included returns the names of the files that are not matched,
+// as ListIncluded does.
+func (s *MatchSet) Included() []string {
+	return s.list(false)
+}
+
+func (s *MatchSet) list(excluded bool) []string {
+	var names []string
+	for _, f := range s.files {
+		if f.excluded == excluded && !f.fi.IsDir() {
+			names = append(names, f.path)
+		}
+	}
+	return names
+}
+
+// Add adds pattern to the Worker of the MatchSet, as a line of a rule file
+// in root, and returns which files it changed. Only the files that the
+// pattern matches, and those within them, are decided again.
+//
+// The only possible error for an invalid pattern is BadPatternError.
+func (s *MatchSet) Add(pattern string) (Effect, error) {
+	rules, err := s.w.line(newParser(DialectNative), pattern, s.w.abs(s.root))
+	if err != nil || len(rules) == 0 {
+		return Effect{}, err
+	}
+	s.w.insert(rules...)
+	return s.update(rules), nil
+}
+
+// Remove removes the rule that pattern, as a line of a rule file in root,
+// would add from the Worker of the MatchSet, and returns which files that
+// changed. The rule may also have been read from a file; if it occurs more
+// than once, the one that takes precedence is removed. It is ErrNoRule if
+// there is no such rule.
+func (s *MatchSet) Remove(pattern string) (Effect, error) {
+	rules, err := s.w.line(newParser(DialectNative), pattern, s.w.abs(s.root))
+	if err != nil || len(rules) == 0 {
+		return Effect{}, err
+	}
+
+	s.w.own()
+	l := s.w.local
+	for _, r := range rules {
+		i := len(l) - 1
+		for i >= 0 && !sameRule(l[i], r) {
+			i--
+		}
+		if i < 0 {
+			return Effect{}, ErrNoRule
+		}
+		l = append(l[:i], l[i+1:]...)
+	}
+	s.w.local = l
+	s.w.invalidate()
+	return s.update(rules), nil
+}
+
+// sameRule returns whether a and b are the same rule, regardless of
+// where they were read from.
+func sameRule(a, b Rule) bool {
+	return a.Glob == b.Glob && a.Negate == b.Negate && a.DirOnly == b.DirOnly &&
+		a.Cond == b.Cond && a.Regexp == b.Regexp
+}
+
+// update decides the files that the changed rules match again, as well as
+// all files within them, and returns how that changed them.
+func (s *MatchSet) update(changed []Rule) Effect {
+	var e Effect
+	var pruned string
+	sep := string(filepath.Separator)
+	for i := range s.files {
+		f := &s.files[i]
+		if pruned != "" && !strings.HasPrefix(f.abs, pruned+sep) {
+			pruned = ""
+		}
+		for _, r := range changed {
+			if s.w.m.matchRule(r, f.abs) {
+				f.matched = s.w.MatchesInfo(f.abs, f.fi)
+				break
+			}
+		}
+
+		excluded := pruned != "" || f.matched
+		if excluded && pruned == "" && f.fi.IsDir() {
+			pruned = f.abs
+		}
+		if excluded == f.excluded {
+			continue
+		}
+		f.excluded = excluded
+		if f.fi.IsDir() {
+			continue
+		}
+		if excluded {
+			e.Excluded = append(e.Excluded, f.path)
+		} else {
+			e.Included = append(e.Included, f.path)
+		}
+	}
+	return e
+}
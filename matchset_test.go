@@ -0,0 +1,81 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchSet(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, k := range []string{"main.go", "main.o", "lib/a.o", "lib/a.go", "gen/x.go", "gen/sub/y.o"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(k)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, k), nil, 0644)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "match.conf"), []byte("*.o\n"), 0644)
+
+	m := New("match.conf")
+	m.Root = dir
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	s, err := w.MatchSet(".")
+	if err != nil {
+		fw.Fatal(err)
+	}
+
+	// check compares the MatchSet with a fresh walk of a Worker
+	// with the same rules.
+	check := func(step string) {
+		c := w.Clone()
+		c.local = append([]Rule(nil), s.w.local...)
+		expected, _ := c.ListExcluded(".")
+		if got := s.Excluded(); !reflect.DeepEqual(got, expected) {
+			fw.Errorf("after %s: s.Excluded() = %q, expected %q", step, got, expected)
+		}
+	}
+
+	tests := []struct {
+		add     bool
+		pattern string
+		effect  Effect
+	}{
+		{true, "gen/", Effect{Excluded: []string{"gen/x.go"}}},
+		{true, "!a.o", Effect{Included: []string{"lib/a.o"}}},
+		{true, "*.go", Effect{Excluded: []string{"lib/a.go", "main.go"}}},
+		{false, "gen/", Effect{}},
+		{false, "*.o", Effect{Included: []string{"gen/sub/y.o", "main.o"}}},
+		{false, "*.go", Effect{Included: []string{"gen/x.go", "lib/a.go", "main.go"}}},
+	}
+	for _, t := range tests {
+		var e Effect
+		var err error
+		if t.add {
+			e, err = s.Add(t.pattern)
+		} else {
+			e, err = s.Remove(t.pattern)
+		}
+		if err != nil || !reflect.DeepEqual(e, t.effect) {
+			fw.Errorf("changing %q = (%+v, %v), expected %+v", t.pattern, e, err, t.effect)
+		}
+		check(t.pattern)
+	}
+
+	if _, err := s.Remove("*.c"); err != ErrNoRule {
+		fw.Errorf("s.Remove(%q) = %v, expected %v", "*.c", err, ErrNoRule)
+	}
+	if n := len(w.Rules()); n != 1 {
+		fw.Errorf("the Worker has %d rules after changing the MatchSet, expected 1", n)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+// Metric identifies a counter that is reported to a MetricsSink.
+type Metric int
+
+const (
+	// MetricMatches counts calls to Worker.Matches that returned true.
+	MetricMatches Metric = iota
+
+	// MetricMisses counts calls to Worker.Matches that returned false.
+	MetricMisses
+
+	// MetricConfigsLoaded counts configuration files that were read
+	// successfully, including those read by NewWorker.
+	MetricConfigsLoaded
+
+	// MetricCacheHits counts rule files that were served from a cache
+	// instead of being fetched or read anew.
+	MetricCacheHits
+)
+
+var metricNames = []string{
+	MetricMatches:       "matches",
+	MetricMisses:        "misses",
+	MetricConfigsLoaded: "configs_loaded",
+	MetricCacheHits:     "cache_hits",
+}
+
+// String returns a name for the metric that is suitable for use with
+// expvar or Prometheus, such as "configs_loaded".
+func (m Metric) String() string {
+	if m < 0 || int(m) >= len(metricNames) {
+		return "unknown"
+	}
+	return metricNames[m]
+}
+
+// MetricsSink receives counters from a Worker, so that services embedding
+// the matcher can export them. A sink that is shared between Workers used
+// concurrently must be safe for concurrent use.
+type MetricsSink interface {
+	// Count increments the counter m by n.
+	Count(m Metric, n int)
+}
+
+func (w *Worker) count(m Metric) {
+	if w.metrics != nil {
+		w.metrics.Count(m, 1)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import "testing"
+
+type countingSink map[Metric]int
+
+func (s countingSink) Count(m Metric, n int) { s[m] += n }
+
+func TestMetrics(fw *testing.T) {
+	sink := make(countingSink)
+	m := New("match.conf")
+	m.Metrics = sink
+	w, err := m.NewWorker("tests/dead/good")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	for _, p := range []string{"1", "2", "3", "match.conf"} {
+		w.Matches(p)
+	}
+
+	expected := countingSink{
+		MetricMatches:       2,
+		MetricMisses:        2,
+		MetricConfigsLoaded: 3,
+	}
+	for k, v := range expected {
+		if sink[k] != v {
+			fw.Errorf("counter %s = %d, expected %d", k, sink[k], v)
+		}
+	}
+	if s := MetricCacheHits.String(); s != "cache_hits" {
+		fw.Errorf("MetricCacheHits.String() = %q", s)
+	}
+}
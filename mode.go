@@ -0,0 +1,175 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mode determines how strictly Workers parse rule files.
+type Mode int
+
+const (
+	// ModeDefault accepts the syntax described in the package documentation,
+	// including its extensions of gitignore, and fails to load a rule file
+	// with a line that is not valid in it.
+	ModeDefault Mode = iota
+
+	// ModeStrict only accepts what gitignore accepts, which is useful for
+	// linting repositories. Lines in native and gitignore files that use
+	// predicates, macros, variables, brace expressions, or a leading "//"
+	// fail with ErrExtension. Other dialects are parsed as in ModeDefault.
+	ModeStrict
+
+	// ModeLenient never fails to load a rule file because of a line in it,
+	// which is useful for tools that read files written by end users.
+	// A line with an invalid predicate, macro, or variable, or with too many
+	// alternatives in its brace expressions, is read as a plain glob, runs
+	// of stars within an element such as "a**" are read as a single star,
+	// and lines that still cannot be parsed are skipped and logged.
+	ModeLenient
+
+	// ModeCollect parses as ModeDefault does, but like git, it skips lines
+	// that are not valid instead of failing at the first, so that one typo
+	// does not disable the rest of a rule file. The file is loaded with the
+	// valid lines, and the errors of the others are returned together in
+	// PatternErrors.
+	ModeCollect
+)
+
+// PatternErrors are the errors of the invalid lines of a rule file that was
+// loaded nonetheless in ModeCollect, in the order of the lines.
+type PatternErrors []*BadPatternError
+
+func (e PatternErrors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// Is reports whether any of the errors is target, as errors.Is does, so that
+// errors.Is finds them with toolchains before Go 1.20, which do not follow
+// Unwrap.
+func (e PatternErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches target, as errors.As does,
+// and sets target to it. See Is.
+func (e PatternErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the errors, so that errors.Is and errors.As find them
+// from Go 1.20 on.
+func (e PatternErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// extension returns the column at which the cleaned line s of a native or
+// gitignore file uses an extension of the gitignore syntax, and whether it
+// does so at all.
+func extension(s string) (int, bool) {
+	if strings.HasPrefix(s, "define ") || strings.HasPrefix(s, "set ") {
+		return 0, true
+	}
+	column := 0
+	if strings.HasPrefix(s, "!") {
+		s = s[1:]
+		column++
+	}
+	if strings.HasPrefix(s, "//") || strings.HasPrefix(s, "@") {
+		return column, true
+	}
+	tok := s
+	if end := strings.IndexAny(s, " \t"); end >= 0 {
+		tok = s[:end]
+	}
+	if i := strings.IndexByte(tok, ':'); i >= 0 {
+		if _, ok := lookupPredicate(tok[:i]); ok {
+			return column, true
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case strings.HasPrefix(s[i:], "${"):
+			return column + utf8.RuneCountInString(s[:i]), true
+		}
+	}
+	return 0, false
+}
+
+// repair interprets the cleaned line s that failed to parse with err
+// as described for ModeLenient. If it cannot, the error is returned.
+func (p *parser) repair(s string, err error) ([]Rule, error) {
+	switch err.(*BadPatternError).Err {
+	case ErrBadPredicate, ErrBadMacro, ErrUndefinedMacro, ErrMacroCycle, ErrMacroExpansion, ErrBadVariable, ErrUndefinedVariable, ErrVariableExpansion, ErrBraceExpansion:
+		if p.dialect != DialectNative {
+			break
+		}
+		r, err := parseGitignore(s)
+		if err == nil {
+			return []Rule{r}, nil
+		}
+		if err.(*BadPatternError).Err == ErrDualStar {
+			return p.repair(s, err)
+		}
+	case ErrDualStar:
+		if t := collapseStars(s); t != s {
+			rules, err := p.parseLine(t)
+			if err != nil {
+				rules, err = p.repair(t, err)
+			}
+			if err == nil {
+				return rules, nil
+			}
+		}
+	}
+	return nil, err
+}
+
+// collapseStars replaces runs of unescaped stars in s by a single star,
+// except in elements of a path that consist of exactly two stars.
+func collapseStars(s string) string {
+	elems := strings.Split(s, "/")
+	for i, e := range elems {
+		if e == "**" || e == "!**" {
+			continue
+		}
+		var b strings.Builder
+		for j := 0; j < len(e); j++ {
+			switch {
+			case e[j] == '\\' && j+1 < len(e):
+				b.WriteString(e[j : j+2])
+				j++
+			case e[j] == '*' && j > 0 && e[j-1] == '*' && (j < 2 || e[j-2] != '\\'):
+			default:
+				b.WriteByte(e[j])
+			}
+		}
+		elems[i] = b.String()
+	}
+	return strings.Join(elems, "/")
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModeStrict(fw *testing.T) {
+	tests := map[string]int{
+		"*.o":             -1,
+		"!build/":         -1,
+		"size:>1M":        0,
+		"!type:dir cache": 1,
+		"define X = a":    0,
+		"set x = a":       0,
+		"@X":              0,
+		"//out":           0,
+		"a/${x}/b":        2,
+		"a\\${x}":         -1,
+		"mtime\\:1d":      -1,
+		"notapred:x":      -1,
+		"*.{c,h}":         2,
+		"a{b}":            -1,
+		"\\{a,b}":         -1,
+	}
+	for k, v := range tests {
+		p := newParser(DialectNative)
+		p.mode = ModeStrict
+		_, err := p.parseLine(Clean(k))
+		if v < 0 {
+			if err != nil {
+				fw.Errorf("strict parsing %q = %v, expected nil", k, err)
+			}
+			continue
+		}
+		if pe, ok := err.(*BadPatternError); !ok || pe.Err != ErrExtension || pe.Column != v {
+			fw.Errorf("strict parsing %q = %v, expected %v at column %d", k, err, ErrExtension, v)
+		}
+	}
+}
+
+func TestModeLenient(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	conf := "size:big\n@undefined\nfoo**\n[z-a]\na/**/b**\n*.o\n"
+	ioutil.WriteFile(filepath.Join(dir, "match.conf"), []byte(conf), 0644)
+
+	m := New("match.conf")
+	m.Policy = Abort
+	if _, err := m.NewWorker(dir); err == nil {
+		fw.Fatal("m.NewWorker() succeeded in ModeDefault, expected error")
+	}
+
+	m.Mode = ModeLenient
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	tests := map[string]bool{
+		"size:big":   true,
+		"@undefined": true,
+		"foobar":     true,
+		"z":          false,
+		"a/x/bcd":    true,
+		"x.o":        true,
+	}
+	for k, v := range tests {
+		if r := w.Matches(k); r != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+	if n := len(w.Rules()); n != 5 {
+		fw.Errorf("len(w.Rules()) = %d, expected 5", n)
+	}
+}
+
+func TestModeCollect(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	conf := "*.o\na[\n*.tmp\n@undefined\n!keep.o\n"
+	path := filepath.Join(dir, "match.conf")
+	ioutil.WriteFile(path, []byte(conf), 0644)
+
+	m := New("match.conf")
+	m.Mode = ModeCollect
+	w := m.newWorker(dir)
+	err = w.AddFile(path)
+	errs, ok := err.(PatternErrors)
+	if !ok || len(errs) != 2 || errs[0].Line != 2 || errs[0].Err != ErrIncompleteClass || errs[1].Line != 4 || errs[1].Err != ErrUndefinedMacro {
+		fw.Fatalf("w.AddFile() = %v, expected errors for lines 2 and 4", err)
+	}
+	if !errors.Is(err, ErrUndefinedMacro) {
+		fw.Errorf("errors.Is(%v, ErrUndefinedMacro) = false", err)
+	}
+	var pe *BadPatternError
+	if !errors.As(err, &pe) || pe.Line != 2 {
+		fw.Errorf("errors.As(%v) = %v, expected the error of line 2", err, pe)
+	}
+	// Toolchains before Go 1.20 only find them through Is and As.
+	if pe = nil; !errs.Is(ErrUndefinedMacro) || errs.Is(ErrBadPredicate) || !errs.As(&pe) || pe.Line != 2 {
+		fw.Errorf("errs.Is and errs.As do not find the errors of %v", errs)
+	}
+	for k, v := range map[string]bool{"a.o": true, "a.tmp": true, "keep.o": false} {
+		if r := w.Matches(k); r != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, r, v)
+		}
+	}
+	if files := w.ConfigFiles(); len(files) != 1 || files[0] != path {
+		fw.Errorf("w.ConfigFiles() = %q, expected %q", files, path)
+	}
+
+	m.Policy = Collect
+	w, err = m.NewWorker(dir)
+	if errs, ok := err.(ConfigErrors); !ok || len(errs) != 1 || w == nil || !w.Matches("a.tmp") {
+		fw.Errorf("m.NewWorker() = %v, expected the rule file loaded and its errors collected", err)
+	}
+	m.Policy = Skip
+	m.Strict = true
+	if w, err := m.NewWorker(dir); err != nil || !w.Matches("a.o") {
+		fw.Errorf("m.NewWorker() with Strict = %v, expected the rule file loaded", err)
+	}
+}
+
+func TestCollapseStars(fw *testing.T) {
+	tests := map[string]string{
+		"a**":       "a*",
+		"***":       "*",
+		"**/a***b":  "**/a*b",
+		"a\\**":     "a\\**",
+		"a\\***":    "a\\**",
+		"!**/x**/y": "!**/x*/y",
+	}
+	for k, v := range tests {
+		if s := collapseStars(k); s != v {
+			fw.Errorf("collapseStars(%q) = %q, expected %q", k, s, v)
+		}
+	}
+}
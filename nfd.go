@@ -0,0 +1,62 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// decomposeNames is whether patterns and names are decomposed before they
+// are matched. This is the case on macOS, where HFS+ stores names decomposed
+// and APFS preserves whatever form they were created in, so that a pattern
+// written with "é" must match a file reported as "e" and a combining accent,
+// and vice versa. Since decomposed characters consist of several runes,
+// they are only matched by a single "?" or character class if
+// Matcher.Graphemes is set. It is replaced in tests.
+var decomposeNames = runtime.GOOS == "darwin"
+
+// Constants of the algorithmic decomposition of Hangul syllables,
+// as given in chapter 3.12 of the Unicode Standard.
+const (
+	hangulBase  = 0xAC00
+	hangulL     = 0x1100
+	hangulV     = 0x1161
+	hangulT     = 0x11A7
+	hangulVN    = 21
+	hangulTN    = 28
+	hangulCount = 19 * hangulVN * hangulTN
+)
+
+// nfd returns s in canonical decomposed form for the characters covered by
+// decompositions and for Hangul syllables. Characters outside of these are
+// left as they are, and combining marks are not reordered.
+func nfd(s string) string {
+	i := 0
+	for i < len(s) && s[i] < utf8.RuneSelf {
+		i++
+	}
+	if i == len(s) {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteString(s[:i])
+	for _, r := range s[i:] {
+		if d, ok := decompositions[r]; ok {
+			b.WriteString(d)
+		} else if n := r - hangulBase; 0 <= n && n < hangulCount {
+			b.WriteRune(hangulL + n/(hangulVN*hangulTN))
+			b.WriteRune(hangulV + n%(hangulVN*hangulTN)/hangulTN)
+			if t := n % hangulTN; t != 0 {
+				b.WriteRune(hangulT + t)
+			}
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
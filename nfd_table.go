@@ -0,0 +1,816 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+// decompositions maps precomposed characters to their full canonical
+// decomposition (NFD). It was generated from version 14.0.0 of the Unicode
+// Character Database and covers the Latin, Greek, and Cyrillic blocks,
+// including Latin Extended Additional and Greek Extended.
+var decompositions = map[rune]string{
+	0x00C0: "A\u0300",                  // À
+	0x00C1: "A\u0301",                  // Á
+	0x00C2: "A\u0302",                  // Â
+	0x00C3: "A\u0303",                  // Ã
+	0x00C4: "A\u0308",                  // Ä
+	0x00C5: "A\u030A",                  // Å
+	0x00C7: "C\u0327",                  // Ç
+	0x00C8: "E\u0300",                  // È
+	0x00C9: "E\u0301",                  // É
+	0x00CA: "E\u0302",                  // Ê
+	0x00CB: "E\u0308",                  // Ë
+	0x00CC: "I\u0300",                  // Ì
+	0x00CD: "I\u0301",                  // Í
+	0x00CE: "I\u0302",                  // Î
+	0x00CF: "I\u0308",                  // Ï
+	0x00D1: "N\u0303",                  // Ñ
+	0x00D2: "O\u0300",                  // Ò
+	0x00D3: "O\u0301",                  // Ó
+	0x00D4: "O\u0302",                  // Ô
+	0x00D5: "O\u0303",                  // Õ
+	0x00D6: "O\u0308",                  // Ö
+	0x00D9: "U\u0300",                  // Ù
+	0x00DA: "U\u0301",                  // Ú
+	0x00DB: "U\u0302",                  // Û
+	0x00DC: "U\u0308",                  // Ü
+	0x00DD: "Y\u0301",                  // Ý
+	0x00E0: "a\u0300",                  // à
+	0x00E1: "a\u0301",                  // á
+	0x00E2: "a\u0302",                  // â
+	0x00E3: "a\u0303",                  // ã
+	0x00E4: "a\u0308",                  // ä
+	0x00E5: "a\u030A",                  // å
+	0x00E7: "c\u0327",                  // ç
+	0x00E8: "e\u0300",                  // è
+	0x00E9: "e\u0301",                  // é
+	0x00EA: "e\u0302",                  // ê
+	0x00EB: "e\u0308",                  // ë
+	0x00EC: "i\u0300",                  // ì
+	0x00ED: "i\u0301",                  // í
+	0x00EE: "i\u0302",                  // î
+	0x00EF: "i\u0308",                  // ï
+	0x00F1: "n\u0303",                  // ñ
+	0x00F2: "o\u0300",                  // ò
+	0x00F3: "o\u0301",                  // ó
+	0x00F4: "o\u0302",                  // ô
+	0x00F5: "o\u0303",                  // õ
+	0x00F6: "o\u0308",                  // ö
+	0x00F9: "u\u0300",                  // ù
+	0x00FA: "u\u0301",                  // ú
+	0x00FB: "u\u0302",                  // û
+	0x00FC: "u\u0308",                  // ü
+	0x00FD: "y\u0301",                  // ý
+	0x00FF: "y\u0308",                  // ÿ
+	0x0100: "A\u0304",                  // Ā
+	0x0101: "a\u0304",                  // ā
+	0x0102: "A\u0306",                  // Ă
+	0x0103: "a\u0306",                  // ă
+	0x0104: "A\u0328",                  // Ą
+	0x0105: "a\u0328",                  // ą
+	0x0106: "C\u0301",                  // Ć
+	0x0107: "c\u0301",                  // ć
+	0x0108: "C\u0302",                  // Ĉ
+	0x0109: "c\u0302",                  // ĉ
+	0x010A: "C\u0307",                  // Ċ
+	0x010B: "c\u0307",                  // ċ
+	0x010C: "C\u030C",                  // Č
+	0x010D: "c\u030C",                  // č
+	0x010E: "D\u030C",                  // Ď
+	0x010F: "d\u030C",                  // ď
+	0x0112: "E\u0304",                  // Ē
+	0x0113: "e\u0304",                  // ē
+	0x0114: "E\u0306",                  // Ĕ
+	0x0115: "e\u0306",                  // ĕ
+	0x0116: "E\u0307",                  // Ė
+	0x0117: "e\u0307",                  // ė
+	0x0118: "E\u0328",                  // Ę
+	0x0119: "e\u0328",                  // ę
+	0x011A: "E\u030C",                  // Ě
+	0x011B: "e\u030C",                  // ě
+	0x011C: "G\u0302",                  // Ĝ
+	0x011D: "g\u0302",                  // ĝ
+	0x011E: "G\u0306",                  // Ğ
+	0x011F: "g\u0306",                  // ğ
+	0x0120: "G\u0307",                  // Ġ
+	0x0121: "g\u0307",                  // ġ
+	0x0122: "G\u0327",                  // Ģ
+	0x0123: "g\u0327",                  // ģ
+	0x0124: "H\u0302",                  // Ĥ
+	0x0125: "h\u0302",                  // ĥ
+	0x0128: "I\u0303",                  // Ĩ
+	0x0129: "i\u0303",                  // ĩ
+	0x012A: "I\u0304",                  // Ī
+	0x012B: "i\u0304",                  // ī
+	0x012C: "I\u0306",                  // Ĭ
+	0x012D: "i\u0306",                  // ĭ
+	0x012E: "I\u0328",                  // Į
+	0x012F: "i\u0328",                  // į
+	0x0130: "I\u0307",                  // İ
+	0x0134: "J\u0302",                  // Ĵ
+	0x0135: "j\u0302",                  // ĵ
+	0x0136: "K\u0327",                  // Ķ
+	0x0137: "k\u0327",                  // ķ
+	0x0139: "L\u0301",                  // Ĺ
+	0x013A: "l\u0301",                  // ĺ
+	0x013B: "L\u0327",                  // Ļ
+	0x013C: "l\u0327",                  // ļ
+	0x013D: "L\u030C",                  // Ľ
+	0x013E: "l\u030C",                  // ľ
+	0x0143: "N\u0301",                  // Ń
+	0x0144: "n\u0301",                  // ń
+	0x0145: "N\u0327",                  // Ņ
+	0x0146: "n\u0327",                  // ņ
+	0x0147: "N\u030C",                  // Ň
+	0x0148: "n\u030C",                  // ň
+	0x014C: "O\u0304",                  // Ō
+	0x014D: "o\u0304",                  // ō
+	0x014E: "O\u0306",                  // Ŏ
+	0x014F: "o\u0306",                  // ŏ
+	0x0150: "O\u030B",                  // Ő
+	0x0151: "o\u030B",                  // ő
+	0x0154: "R\u0301",                  // Ŕ
+	0x0155: "r\u0301",                  // ŕ
+	0x0156: "R\u0327",                  // Ŗ
+	0x0157: "r\u0327",                  // ŗ
+	0x0158: "R\u030C",                  // Ř
+	0x0159: "r\u030C",                  // ř
+	0x015A: "S\u0301",                  // Ś
+	0x015B: "s\u0301",                  // ś
+	0x015C: "S\u0302",                  // Ŝ
+	0x015D: "s\u0302",                  // ŝ
+	0x015E: "S\u0327",                  // Ş
+	0x015F: "s\u0327",                  // ş
+	0x0160: "S\u030C",                  // Š
+	0x0161: "s\u030C",                  // š
+	0x0162: "T\u0327",                  // Ţ
+	0x0163: "t\u0327",                  // ţ
+	0x0164: "T\u030C",                  // Ť
+	0x0165: "t\u030C",                  // ť
+	0x0168: "U\u0303",                  // Ũ
+	0x0169: "u\u0303",                  // ũ
+	0x016A: "U\u0304",                  // Ū
+	0x016B: "u\u0304",                  // ū
+	0x016C: "U\u0306",                  // Ŭ
+	0x016D: "u\u0306",                  // ŭ
+	0x016E: "U\u030A",                  // Ů
+	0x016F: "u\u030A",                  // ů
+	0x0170: "U\u030B",                  // Ű
+	0x0171: "u\u030B",                  // ű
+	0x0172: "U\u0328",                  // Ų
+	0x0173: "u\u0328",                  // ų
+	0x0174: "W\u0302",                  // Ŵ
+	0x0175: "w\u0302",                  // ŵ
+	0x0176: "Y\u0302",                  // Ŷ
+	0x0177: "y\u0302",                  // ŷ
+	0x0178: "Y\u0308",                  // Ÿ
+	0x0179: "Z\u0301",                  // Ź
+	0x017A: "z\u0301",                  // ź
+	0x017B: "Z\u0307",                  // Ż
+	0x017C: "z\u0307",                  // ż
+	0x017D: "Z\u030C",                  // Ž
+	0x017E: "z\u030C",                  // ž
+	0x01A0: "O\u031B",                  // Ơ
+	0x01A1: "o\u031B",                  // ơ
+	0x01AF: "U\u031B",                  // Ư
+	0x01B0: "u\u031B",                  // ư
+	0x01CD: "A\u030C",                  // Ǎ
+	0x01CE: "a\u030C",                  // ǎ
+	0x01CF: "I\u030C",                  // Ǐ
+	0x01D0: "i\u030C",                  // ǐ
+	0x01D1: "O\u030C",                  // Ǒ
+	0x01D2: "o\u030C",                  // ǒ
+	0x01D3: "U\u030C",                  // Ǔ
+	0x01D4: "u\u030C",                  // ǔ
+	0x01D5: "U\u0308\u0304",            // Ǖ
+	0x01D6: "u\u0308\u0304",            // ǖ
+	0x01D7: "U\u0308\u0301",            // Ǘ
+	0x01D8: "u\u0308\u0301",            // ǘ
+	0x01D9: "U\u0308\u030C",            // Ǚ
+	0x01DA: "u\u0308\u030C",            // ǚ
+	0x01DB: "U\u0308\u0300",            // Ǜ
+	0x01DC: "u\u0308\u0300",            // ǜ
+	0x01DE: "A\u0308\u0304",            // Ǟ
+	0x01DF: "a\u0308\u0304",            // ǟ
+	0x01E0: "A\u0307\u0304",            // Ǡ
+	0x01E1: "a\u0307\u0304",            // ǡ
+	0x01E2: "\u00C6\u0304",             // Ǣ
+	0x01E3: "\u00E6\u0304",             // ǣ
+	0x01E6: "G\u030C",                  // Ǧ
+	0x01E7: "g\u030C",                  // ǧ
+	0x01E8: "K\u030C",                  // Ǩ
+	0x01E9: "k\u030C",                  // ǩ
+	0x01EA: "O\u0328",                  // Ǫ
+	0x01EB: "o\u0328",                  // ǫ
+	0x01EC: "O\u0328\u0304",            // Ǭ
+	0x01ED: "o\u0328\u0304",            // ǭ
+	0x01EE: "\u01B7\u030C",             // Ǯ
+	0x01EF: "\u0292\u030C",             // ǯ
+	0x01F0: "j\u030C",                  // ǰ
+	0x01F4: "G\u0301",                  // Ǵ
+	0x01F5: "g\u0301",                  // ǵ
+	0x01F8: "N\u0300",                  // Ǹ
+	0x01F9: "n\u0300",                  // ǹ
+	0x01FA: "A\u030A\u0301",            // Ǻ
+	0x01FB: "a\u030A\u0301",            // ǻ
+	0x01FC: "\u00C6\u0301",             // Ǽ
+	0x01FD: "\u00E6\u0301",             // ǽ
+	0x01FE: "\u00D8\u0301",             // Ǿ
+	0x01FF: "\u00F8\u0301",             // ǿ
+	0x0200: "A\u030F",                  // Ȁ
+	0x0201: "a\u030F",                  // ȁ
+	0x0202: "A\u0311",                  // Ȃ
+	0x0203: "a\u0311",                  // ȃ
+	0x0204: "E\u030F",                  // Ȅ
+	0x0205: "e\u030F",                  // ȅ
+	0x0206: "E\u0311",                  // Ȇ
+	0x0207: "e\u0311",                  // ȇ
+	0x0208: "I\u030F",                  // Ȉ
+	0x0209: "i\u030F",                  // ȉ
+	0x020A: "I\u0311",                  // Ȋ
+	0x020B: "i\u0311",                  // ȋ
+	0x020C: "O\u030F",                  // Ȍ
+	0x020D: "o\u030F",                  // ȍ
+	0x020E: "O\u0311",                  // Ȏ
+	0x020F: "o\u0311",                  // ȏ
+	0x0210: "R\u030F",                  // Ȑ
+	0x0211: "r\u030F",                  // ȑ
+	0x0212: "R\u0311",                  // Ȓ
+	0x0213: "r\u0311",                  // ȓ
+	0x0214: "U\u030F",                  // Ȕ
+	0x0215: "u\u030F",                  // ȕ
+	0x0216: "U\u0311",                  // Ȗ
+	0x0217: "u\u0311",                  // ȗ
+	0x0218: "S\u0326",                  // Ș
+	0x0219: "s\u0326",                  // ș
+	0x021A: "T\u0326",                  // Ț
+	0x021B: "t\u0326",                  // ț
+	0x021E: "H\u030C",                  // Ȟ
+	0x021F: "h\u030C",                  // ȟ
+	0x0226: "A\u0307",                  // Ȧ
+	0x0227: "a\u0307",                  // ȧ
+	0x0228: "E\u0327",                  // Ȩ
+	0x0229: "e\u0327",                  // ȩ
+	0x022A: "O\u0308\u0304",            // Ȫ
+	0x022B: "o\u0308\u0304",            // ȫ
+	0x022C: "O\u0303\u0304",            // Ȭ
+	0x022D: "o\u0303\u0304",            // ȭ
+	0x022E: "O\u0307",                  // Ȯ
+	0x022F: "o\u0307",                  // ȯ
+	0x0230: "O\u0307\u0304",            // Ȱ
+	0x0231: "o\u0307\u0304",            // ȱ
+	0x0232: "Y\u0304",                  // Ȳ
+	0x0233: "y\u0304",                  // ȳ
+	0x0374: "\u02B9",                   // ʹ
+	0x037E: ";",                        // ;
+	0x0385: "\u00A8\u0301",             // ΅
+	0x0386: "\u0391\u0301",             // Ά
+	0x0387: "\u00B7",                   // ·
+	0x0388: "\u0395\u0301",             // Έ
+	0x0389: "\u0397\u0301",             // Ή
+	0x038A: "\u0399\u0301",             // Ί
+	0x038C: "\u039F\u0301",             // Ό
+	0x038E: "\u03A5\u0301",             // Ύ
+	0x038F: "\u03A9\u0301",             // Ώ
+	0x0390: "\u03B9\u0308\u0301",       // ΐ
+	0x03AA: "\u0399\u0308",             // Ϊ
+	0x03AB: "\u03A5\u0308",             // Ϋ
+	0x03AC: "\u03B1\u0301",             // ά
+	0x03AD: "\u03B5\u0301",             // έ
+	0x03AE: "\u03B7\u0301",             // ή
+	0x03AF: "\u03B9\u0301",             // ί
+	0x03B0: "\u03C5\u0308\u0301",       // ΰ
+	0x03CA: "\u03B9\u0308",             // ϊ
+	0x03CB: "\u03C5\u0308",             // ϋ
+	0x03CC: "\u03BF\u0301",             // ό
+	0x03CD: "\u03C5\u0301",             // ύ
+	0x03CE: "\u03C9\u0301",             // ώ
+	0x03D3: "\u03D2\u0301",             // ϓ
+	0x03D4: "\u03D2\u0308",             // ϔ
+	0x0400: "\u0415\u0300",             // Ѐ
+	0x0401: "\u0415\u0308",             // Ё
+	0x0403: "\u0413\u0301",             // Ѓ
+	0x0407: "\u0406\u0308",             // Ї
+	0x040C: "\u041A\u0301",             // Ќ
+	0x040D: "\u0418\u0300",             // Ѝ
+	0x040E: "\u0423\u0306",             // Ў
+	0x0419: "\u0418\u0306",             // Й
+	0x0439: "\u0438\u0306",             // й
+	0x0450: "\u0435\u0300",             // ѐ
+	0x0451: "\u0435\u0308",             // ё
+	0x0453: "\u0433\u0301",             // ѓ
+	0x0457: "\u0456\u0308",             // ї
+	0x045C: "\u043A\u0301",             // ќ
+	0x045D: "\u0438\u0300",             // ѝ
+	0x045E: "\u0443\u0306",             // ў
+	0x0476: "\u0474\u030F",             // Ѷ
+	0x0477: "\u0475\u030F",             // ѷ
+	0x04C1: "\u0416\u0306",             // Ӂ
+	0x04C2: "\u0436\u0306",             // ӂ
+	0x04D0: "\u0410\u0306",             // Ӑ
+	0x04D1: "\u0430\u0306",             // ӑ
+	0x04D2: "\u0410\u0308",             // Ӓ
+	0x04D3: "\u0430\u0308",             // ӓ
+	0x04D6: "\u0415\u0306",             // Ӗ
+	0x04D7: "\u0435\u0306",             // ӗ
+	0x04DA: "\u04D8\u0308",             // Ӛ
+	0x04DB: "\u04D9\u0308",             // ӛ
+	0x04DC: "\u0416\u0308",             // Ӝ
+	0x04DD: "\u0436\u0308",             // ӝ
+	0x04DE: "\u0417\u0308",             // Ӟ
+	0x04DF: "\u0437\u0308",             // ӟ
+	0x04E2: "\u0418\u0304",             // Ӣ
+	0x04E3: "\u0438\u0304",             // ӣ
+	0x04E4: "\u0418\u0308",             // Ӥ
+	0x04E5: "\u0438\u0308",             // ӥ
+	0x04E6: "\u041E\u0308",             // Ӧ
+	0x04E7: "\u043E\u0308",             // ӧ
+	0x04EA: "\u04E8\u0308",             // Ӫ
+	0x04EB: "\u04E9\u0308",             // ӫ
+	0x04EC: "\u042D\u0308",             // Ӭ
+	0x04ED: "\u044D\u0308",             // ӭ
+	0x04EE: "\u0423\u0304",             // Ӯ
+	0x04EF: "\u0443\u0304",             // ӯ
+	0x04F0: "\u0423\u0308",             // Ӱ
+	0x04F1: "\u0443\u0308",             // ӱ
+	0x04F2: "\u0423\u030B",             // Ӳ
+	0x04F3: "\u0443\u030B",             // ӳ
+	0x04F4: "\u0427\u0308",             // Ӵ
+	0x04F5: "\u0447\u0308",             // ӵ
+	0x04F8: "\u042B\u0308",             // Ӹ
+	0x04F9: "\u044B\u0308",             // ӹ
+	0x1E00: "A\u0325",                  // Ḁ
+	0x1E01: "a\u0325",                  // ḁ
+	0x1E02: "B\u0307",                  // Ḃ
+	0x1E03: "b\u0307",                  // ḃ
+	0x1E04: "B\u0323",                  // Ḅ
+	0x1E05: "b\u0323",                  // ḅ
+	0x1E06: "B\u0331",                  // Ḇ
+	0x1E07: "b\u0331",                  // ḇ
+	0x1E08: "C\u0327\u0301",            // Ḉ
+	0x1E09: "c\u0327\u0301",            // ḉ
+	0x1E0A: "D\u0307",                  // Ḋ
+	0x1E0B: "d\u0307",                  // ḋ
+	0x1E0C: "D\u0323",                  // Ḍ
+	0x1E0D: "d\u0323",                  // ḍ
+	0x1E0E: "D\u0331",                  // Ḏ
+	0x1E0F: "d\u0331",                  // ḏ
+	0x1E10: "D\u0327",                  // Ḑ
+	0x1E11: "d\u0327",                  // ḑ
+	0x1E12: "D\u032D",                  // Ḓ
+	0x1E13: "d\u032D",                  // ḓ
+	0x1E14: "E\u0304\u0300",            // Ḕ
+	0x1E15: "e\u0304\u0300",            // ḕ
+	0x1E16: "E\u0304\u0301",            // Ḗ
+	0x1E17: "e\u0304\u0301",            // ḗ
+	0x1E18: "E\u032D",                  // Ḙ
+	0x1E19: "e\u032D",                  // ḙ
+	0x1E1A: "E\u0330",                  // Ḛ
+	0x1E1B: "e\u0330",                  // ḛ
+	0x1E1C: "E\u0327\u0306",            // Ḝ
+	0x1E1D: "e\u0327\u0306",            // ḝ
+	0x1E1E: "F\u0307",                  // Ḟ
+	0x1E1F: "f\u0307",                  // ḟ
+	0x1E20: "G\u0304",                  // Ḡ
+	0x1E21: "g\u0304",                  // ḡ
+	0x1E22: "H\u0307",                  // Ḣ
+	0x1E23: "h\u0307",                  // ḣ
+	0x1E24: "H\u0323",                  // Ḥ
+	0x1E25: "h\u0323",                  // ḥ
+	0x1E26: "H\u0308",                  // Ḧ
+	0x1E27: "h\u0308",                  // ḧ
+	0x1E28: "H\u0327",                  // Ḩ
+	0x1E29: "h\u0327",                  // ḩ
+	0x1E2A: "H\u032E",                  // Ḫ
+	0x1E2B: "h\u032E",                  // ḫ
+	0x1E2C: "I\u0330",                  // Ḭ
+	0x1E2D: "i\u0330",                  // ḭ
+	0x1E2E: "I\u0308\u0301",            // Ḯ
+	0x1E2F: "i\u0308\u0301",            // ḯ
+	0x1E30: "K\u0301",                  // Ḱ
+	0x1E31: "k\u0301",                  // ḱ
+	0x1E32: "K\u0323",                  // Ḳ
+	0x1E33: "k\u0323",                  // ḳ
+	0x1E34: "K\u0331",                  // Ḵ
+	0x1E35: "k\u0331",                  // ḵ
+	0x1E36: "L\u0323",                  // Ḷ
+	0x1E37: "l\u0323",                  // ḷ
+	0x1E38: "L\u0323\u0304",            // Ḹ
+	0x1E39: "l\u0323\u0304",            // ḹ
+	0x1E3A: "L\u0331",                  // Ḻ
+	0x1E3B: "l\u0331",                  // ḻ
+	0x1E3C: "L\u032D",                  // Ḽ
+	0x1E3D: "l\u032D",                  // ḽ
+	0x1E3E: "M\u0301",                  // Ḿ
+	0x1E3F: "m\u0301",                  // ḿ
+	0x1E40: "M\u0307",                  // Ṁ
+	0x1E41: "m\u0307",                  // ṁ
+	0x1E42: "M\u0323",                  // Ṃ
+	0x1E43: "m\u0323",                  // ṃ
+	0x1E44: "N\u0307",                  // Ṅ
+	0x1E45: "n\u0307",                  // ṅ
+	0x1E46: "N\u0323",                  // Ṇ
+	0x1E47: "n\u0323",                  // ṇ
+	0x1E48: "N\u0331",                  // Ṉ
+	0x1E49: "n\u0331",                  // ṉ
+	0x1E4A: "N\u032D",                  // Ṋ
+	0x1E4B: "n\u032D",                  // ṋ
+	0x1E4C: "O\u0303\u0301",            // Ṍ
+	0x1E4D: "o\u0303\u0301",            // ṍ
+	0x1E4E: "O\u0303\u0308",            // Ṏ
+	0x1E4F: "o\u0303\u0308",            // ṏ
+	0x1E50: "O\u0304\u0300",            // Ṑ
+	0x1E51: "o\u0304\u0300",            // ṑ
+	0x1E52: "O\u0304\u0301",            // Ṓ
+	0x1E53: "o\u0304\u0301",            // ṓ
+	0x1E54: "P\u0301",                  // Ṕ
+	0x1E55: "p\u0301",                  // ṕ
+	0x1E56: "P\u0307",                  // Ṗ
+	0x1E57: "p\u0307",                  // ṗ
+	0x1E58: "R\u0307",                  // Ṙ
+	0x1E59: "r\u0307",                  // ṙ
+	0x1E5A: "R\u0323",                  // Ṛ
+	0x1E5B: "r\u0323",                  // ṛ
+	0x1E5C: "R\u0323\u0304",            // Ṝ
+	0x1E5D: "r\u0323\u0304",            // ṝ
+	0x1E5E: "R\u0331",                  // Ṟ
+	0x1E5F: "r\u0331",                  // ṟ
+	0x1E60: "S\u0307",                  // Ṡ
+	0x1E61: "s\u0307",                  // ṡ
+	0x1E62: "S\u0323",                  // Ṣ
+	0x1E63: "s\u0323",                  // ṣ
+	0x1E64: "S\u0301\u0307",            // Ṥ
+	0x1E65: "s\u0301\u0307",            // ṥ
+	0x1E66: "S\u030C\u0307",            // Ṧ
+	0x1E67: "s\u030C\u0307",            // ṧ
+	0x1E68: "S\u0323\u0307",            // Ṩ
+	0x1E69: "s\u0323\u0307",            // ṩ
+	0x1E6A: "T\u0307",                  // Ṫ
+	0x1E6B: "t\u0307",                  // ṫ
+	0x1E6C: "T\u0323",                  // Ṭ
+	0x1E6D: "t\u0323",                  // ṭ
+	0x1E6E: "T\u0331",                  // Ṯ
+	0x1E6F: "t\u0331",                  // ṯ
+	0x1E70: "T\u032D",                  // Ṱ
+	0x1E71: "t\u032D",                  // ṱ
+	0x1E72: "U\u0324",                  // Ṳ
+	0x1E73: "u\u0324",                  // ṳ
+	0x1E74: "U\u0330",                  // Ṵ
+	0x1E75: "u\u0330",                  // ṵ
+	0x1E76: "U\u032D",                  // Ṷ
+	0x1E77: "u\u032D",                  // ṷ
+	0x1E78: "U\u0303\u0301",            // Ṹ
+	0x1E79: "u\u0303\u0301",            // ṹ
+	0x1E7A: "U\u0304\u0308",            // Ṻ
+	0x1E7B: "u\u0304\u0308",            // ṻ
+	0x1E7C: "V\u0303",                  // Ṽ
+	0x1E7D: "v\u0303",                  // ṽ
+	0x1E7E: "V\u0323",                  // Ṿ
+	0x1E7F: "v\u0323",                  // ṿ
+	0x1E80: "W\u0300",                  // Ẁ
+	0x1E81: "w\u0300",                  // ẁ
+	0x1E82: "W\u0301",                  // Ẃ
+	0x1E83: "w\u0301",                  // ẃ
+	0x1E84: "W\u0308",                  // Ẅ
+	0x1E85: "w\u0308",                  // ẅ
+	0x1E86: "W\u0307",                  // Ẇ
+	0x1E87: "w\u0307",                  // ẇ
+	0x1E88: "W\u0323",                  // Ẉ
+	0x1E89: "w\u0323",                  // ẉ
+	0x1E8A: "X\u0307",                  // Ẋ
+	0x1E8B: "x\u0307",                  // ẋ
+	0x1E8C: "X\u0308",                  // Ẍ
+	0x1E8D: "x\u0308",                  // ẍ
+	0x1E8E: "Y\u0307",                  // Ẏ
+	0x1E8F: "y\u0307",                  // ẏ
+	0x1E90: "Z\u0302",                  // Ẑ
+	0x1E91: "z\u0302",                  // ẑ
+	0x1E92: "Z\u0323",                  // Ẓ
+	0x1E93: "z\u0323",                  // ẓ
+	0x1E94: "Z\u0331",                  // Ẕ
+	0x1E95: "z\u0331",                  // ẕ
+	0x1E96: "h\u0331",                  // ẖ
+	0x1E97: "t\u0308",                  // ẗ
+	0x1E98: "w\u030A",                  // ẘ
+	0x1E99: "y\u030A",                  // ẙ
+	0x1E9B: "\u017F\u0307",             // ẛ
+	0x1EA0: "A\u0323",                  // Ạ
+	0x1EA1: "a\u0323",                  // ạ
+	0x1EA2: "A\u0309",                  // Ả
+	0x1EA3: "a\u0309",                  // ả
+	0x1EA4: "A\u0302\u0301",            // Ấ
+	0x1EA5: "a\u0302\u0301",            // ấ
+	0x1EA6: "A\u0302\u0300",            // Ầ
+	0x1EA7: "a\u0302\u0300",            // ầ
+	0x1EA8: "A\u0302\u0309",            // Ẩ
+	0x1EA9: "a\u0302\u0309",            // ẩ
+	0x1EAA: "A\u0302\u0303",            // Ẫ
+	0x1EAB: "a\u0302\u0303",            // ẫ
+	0x1EAC: "A\u0323\u0302",            // Ậ
+	0x1EAD: "a\u0323\u0302",            // ậ
+	0x1EAE: "A\u0306\u0301",            // Ắ
+	0x1EAF: "a\u0306\u0301",            // ắ
+	0x1EB0: "A\u0306\u0300",            // Ằ
+	0x1EB1: "a\u0306\u0300",            // ằ
+	0x1EB2: "A\u0306\u0309",            // Ẳ
+	0x1EB3: "a\u0306\u0309",            // ẳ
+	0x1EB4: "A\u0306\u0303",            // Ẵ
+	0x1EB5: "a\u0306\u0303",            // ẵ
+	0x1EB6: "A\u0323\u0306",            // Ặ
+	0x1EB7: "a\u0323\u0306",            // ặ
+	0x1EB8: "E\u0323",                  // Ẹ
+	0x1EB9: "e\u0323",                  // ẹ
+	0x1EBA: "E\u0309",                  // Ẻ
+	0x1EBB: "e\u0309",                  // ẻ
+	0x1EBC: "E\u0303",                  // Ẽ
+	0x1EBD: "e\u0303",                  // ẽ
+	0x1EBE: "E\u0302\u0301",            // Ế
+	0x1EBF: "e\u0302\u0301",            // ế
+	0x1EC0: "E\u0302\u0300",            // Ề
+	0x1EC1: "e\u0302\u0300",            // ề
+	0x1EC2: "E\u0302\u0309",            // Ể
+	0x1EC3: "e\u0302\u0309",            // ể
+	0x1EC4: "E\u0302\u0303",            // Ễ
+	0x1EC5: "e\u0302\u0303",            // ễ
+	0x1EC6: "E\u0323\u0302",            // Ệ
+	0x1EC7: "e\u0323\u0302",            // ệ
+	0x1EC8: "I\u0309",                  // Ỉ
+	0x1EC9: "i\u0309",                  // ỉ
+	0x1ECA: "I\u0323",                  // Ị
+	0x1ECB: "i\u0323",                  // ị
+	0x1ECC: "O\u0323",                  // Ọ
+	0x1ECD: "o\u0323",                  // ọ
+	0x1ECE: "O\u0309",                  // Ỏ
+	0x1ECF: "o\u0309",                  // ỏ
+	0x1ED0: "O\u0302\u0301",            // Ố
+	0x1ED1: "o\u0302\u0301",            // ố
+	0x1ED2: "O\u0302\u0300",            // Ồ
+	0x1ED3: "o\u0302\u0300",            // ồ
+	0x1ED4: "O\u0302\u0309",            // Ổ
+	0x1ED5: "o\u0302\u0309",            // ổ
+	0x1ED6: "O\u0302\u0303",            // Ỗ
+	0x1ED7: "o\u0302\u0303",            // ỗ
+	0x1ED8: "O\u0323\u0302",            // Ộ
+	0x1ED9: "o\u0323\u0302",            // ộ
+	0x1EDA: "O\u031B\u0301",            // Ớ
+	0x1EDB: "o\u031B\u0301",            // ớ
+	0x1EDC: "O\u031B\u0300",            // Ờ
+	0x1EDD: "o\u031B\u0300",            // ờ
+	0x1EDE: "O\u031B\u0309",            // Ở
+	0x1EDF: "o\u031B\u0309",            // ở
+	0x1EE0: "O\u031B\u0303",            // Ỡ
+	0x1EE1: "o\u031B\u0303",            // ỡ
+	0x1EE2: "O\u031B\u0323",            // Ợ
+	0x1EE3: "o\u031B\u0323",            // ợ
+	0x1EE4: "U\u0323",                  // Ụ
+	0x1EE5: "u\u0323",                  // ụ
+	0x1EE6: "U\u0309",                  // Ủ
+	0x1EE7: "u\u0309",                  // ủ
+	0x1EE8: "U\u031B\u0301",            // Ứ
+	0x1EE9: "u\u031B\u0301",            // ứ
+	0x1EEA: "U\u031B\u0300",            // Ừ
+	0x1EEB: "u\u031B\u0300",            // ừ
+	0x1EEC: "U\u031B\u0309",            // Ử
+	0x1EED: "u\u031B\u0309",            // ử
+	0x1EEE: "U\u031B\u0303",            // Ữ
+	0x1EEF: "u\u031B\u0303",            // ữ
+	0x1EF0: "U\u031B\u0323",            // Ự
+	0x1EF1: "u\u031B\u0323",            // ự
+	0x1EF2: "Y\u0300",                  // Ỳ
+	0x1EF3: "y\u0300",                  // ỳ
+	0x1EF4: "Y\u0323",                  // Ỵ
+	0x1EF5: "y\u0323",                  // ỵ
+	0x1EF6: "Y\u0309",                  // Ỷ
+	0x1EF7: "y\u0309",                  // ỷ
+	0x1EF8: "Y\u0303",                  // Ỹ
+	0x1EF9: "y\u0303",                  // ỹ
+	0x1F00: "\u03B1\u0313",             // ἀ
+	0x1F01: "\u03B1\u0314",             // ἁ
+	0x1F02: "\u03B1\u0313\u0300",       // ἂ
+	0x1F03: "\u03B1\u0314\u0300",       // ἃ
+	0x1F04: "\u03B1\u0313\u0301",       // ἄ
+	0x1F05: "\u03B1\u0314\u0301",       // ἅ
+	0x1F06: "\u03B1\u0313\u0342",       // ἆ
+	0x1F07: "\u03B1\u0314\u0342",       // ἇ
+	0x1F08: "\u0391\u0313",             // Ἀ
+	0x1F09: "\u0391\u0314",             // Ἁ
+	0x1F0A: "\u0391\u0313\u0300",       // Ἂ
+	0x1F0B: "\u0391\u0314\u0300",       // Ἃ
+	0x1F0C: "\u0391\u0313\u0301",       // Ἄ
+	0x1F0D: "\u0391\u0314\u0301",       // Ἅ
+	0x1F0E: "\u0391\u0313\u0342",       // Ἆ
+	0x1F0F: "\u0391\u0314\u0342",       // Ἇ
+	0x1F10: "\u03B5\u0313",             // ἐ
+	0x1F11: "\u03B5\u0314",             // ἑ
+	0x1F12: "\u03B5\u0313\u0300",       // ἒ
+	0x1F13: "\u03B5\u0314\u0300",       // ἓ
+	0x1F14: "\u03B5\u0313\u0301",       // ἔ
+	0x1F15: "\u03B5\u0314\u0301",       // ἕ
+	0x1F18: "\u0395\u0313",             // Ἐ
+	0x1F19: "\u0395\u0314",             // Ἑ
+	0x1F1A: "\u0395\u0313\u0300",       // Ἒ
+	0x1F1B: "\u0395\u0314\u0300",       // Ἓ
+	0x1F1C: "\u0395\u0313\u0301",       // Ἔ
+	0x1F1D: "\u0395\u0314\u0301",       // Ἕ
+	0x1F20: "\u03B7\u0313",             // ἠ
+	0x1F21: "\u03B7\u0314",             // ἡ
+	0x1F22: "\u03B7\u0313\u0300",       // ἢ
+	0x1F23: "\u03B7\u0314\u0300",       // ἣ
+	0x1F24: "\u03B7\u0313\u0301",       // ἤ
+	0x1F25: "\u03B7\u0314\u0301",       // ἥ
+	0x1F26: "\u03B7\u0313\u0342",       // ἦ
+	0x1F27: "\u03B7\u0314\u0342",       // ἧ
+	0x1F28: "\u0397\u0313",             // Ἠ
+	0x1F29: "\u0397\u0314",             // Ἡ
+	0x1F2A: "\u0397\u0313\u0300",       // Ἢ
+	0x1F2B: "\u0397\u0314\u0300",       // Ἣ
+	0x1F2C: "\u0397\u0313\u0301",       // Ἤ
+	0x1F2D: "\u0397\u0314\u0301",       // Ἥ
+	0x1F2E: "\u0397\u0313\u0342",       // Ἦ
+	0x1F2F: "\u0397\u0314\u0342",       // Ἧ
+	0x1F30: "\u03B9\u0313",             // ἰ
+	0x1F31: "\u03B9\u0314",             // ἱ
+	0x1F32: "\u03B9\u0313\u0300",       // ἲ
+	0x1F33: "\u03B9\u0314\u0300",       // ἳ
+	0x1F34: "\u03B9\u0313\u0301",       // ἴ
+	0x1F35: "\u03B9\u0314\u0301",       // ἵ
+	0x1F36: "\u03B9\u0313\u0342",       // ἶ
+	0x1F37: "\u03B9\u0314\u0342",       // ἷ
+	0x1F38: "\u0399\u0313",             // Ἰ
+	0x1F39: "\u0399\u0314",             // Ἱ
+	0x1F3A: "\u0399\u0313\u0300",       // Ἲ
+	0x1F3B: "\u0399\u0314\u0300",       // Ἳ
+	0x1F3C: "\u0399\u0313\u0301",       // Ἴ
+	0x1F3D: "\u0399\u0314\u0301",       // Ἵ
+	0x1F3E: "\u0399\u0313\u0342",       // Ἶ
+	0x1F3F: "\u0399\u0314\u0342",       // Ἷ
+	0x1F40: "\u03BF\u0313",             // ὀ
+	0x1F41: "\u03BF\u0314",             // ὁ
+	0x1F42: "\u03BF\u0313\u0300",       // ὂ
+	0x1F43: "\u03BF\u0314\u0300",       // ὃ
+	0x1F44: "\u03BF\u0313\u0301",       // ὄ
+	0x1F45: "\u03BF\u0314\u0301",       // ὅ
+	0x1F48: "\u039F\u0313",             // Ὀ
+	0x1F49: "\u039F\u0314",             // Ὁ
+	0x1F4A: "\u039F\u0313\u0300",       // Ὂ
+	0x1F4B: "\u039F\u0314\u0300",       // Ὃ
+	0x1F4C: "\u039F\u0313\u0301",       // Ὄ
+	0x1F4D: "\u039F\u0314\u0301",       // Ὅ
+	0x1F50: "\u03C5\u0313",             // ὐ
+	0x1F51: "\u03C5\u0314",             // ὑ
+	0x1F52: "\u03C5\u0313\u0300",       // ὒ
+	0x1F53: "\u03C5\u0314\u0300",       // ὓ
+	0x1F54: "\u03C5\u0313\u0301",       // ὔ
+	0x1F55: "\u03C5\u0314\u0301",       // ὕ
+	0x1F56: "\u03C5\u0313\u0342",       // ὖ
+	0x1F57: "\u03C5\u0314\u0342",       // ὗ
+	0x1F59: "\u03A5\u0314",             // Ὑ
+	0x1F5B: "\u03A5\u0314\u0300",       // Ὓ
+	0x1F5D: "\u03A5\u0314\u0301",       // Ὕ
+	0x1F5F: "\u03A5\u0314\u0342",       // Ὗ
+	0x1F60: "\u03C9\u0313",             // ὠ
+	0x1F61: "\u03C9\u0314",             // ὡ
+	0x1F62: "\u03C9\u0313\u0300",       // ὢ
+	0x1F63: "\u03C9\u0314\u0300",       // ὣ
+	0x1F64: "\u03C9\u0313\u0301",       // ὤ
+	0x1F65: "\u03C9\u0314\u0301",       // ὥ
+	0x1F66: "\u03C9\u0313\u0342",       // ὦ
+	0x1F67: "\u03C9\u0314\u0342",       // ὧ
+	0x1F68: "\u03A9\u0313",             // Ὠ
+	0x1F69: "\u03A9\u0314",             // Ὡ
+	0x1F6A: "\u03A9\u0313\u0300",       // Ὢ
+	0x1F6B: "\u03A9\u0314\u0300",       // Ὣ
+	0x1F6C: "\u03A9\u0313\u0301",       // Ὤ
+	0x1F6D: "\u03A9\u0314\u0301",       // Ὥ
+	0x1F6E: "\u03A9\u0313\u0342",       // Ὦ
+	0x1F6F: "\u03A9\u0314\u0342",       // Ὧ
+	0x1F70: "\u03B1\u0300",             // ὰ
+	0x1F71: "\u03B1\u0301",             // ά
+	0x1F72: "\u03B5\u0300",             // ὲ
+	0x1F73: "\u03B5\u0301",             // έ
+	0x1F74: "\u03B7\u0300",             // ὴ
+	0x1F75: "\u03B7\u0301",             // ή
+	0x1F76: "\u03B9\u0300",             // ὶ
+	0x1F77: "\u03B9\u0301",             // ί
+	0x1F78: "\u03BF\u0300",             // ὸ
+	0x1F79: "\u03BF\u0301",             // ό
+	0x1F7A: "\u03C5\u0300",             // ὺ
+	0x1F7B: "\u03C5\u0301",             // ύ
+	0x1F7C: "\u03C9\u0300",             // ὼ
+	0x1F7D: "\u03C9\u0301",             // ώ
+	0x1F80: "\u03B1\u0313\u0345",       // ᾀ
+	0x1F81: "\u03B1\u0314\u0345",       // ᾁ
+	0x1F82: "\u03B1\u0313\u0300\u0345", // ᾂ
+	0x1F83: "\u03B1\u0314\u0300\u0345", // ᾃ
+	0x1F84: "\u03B1\u0313\u0301\u0345", // ᾄ
+	0x1F85: "\u03B1\u0314\u0301\u0345", // ᾅ
+	0x1F86: "\u03B1\u0313\u0342\u0345", // ᾆ
+	0x1F87: "\u03B1\u0314\u0342\u0345", // ᾇ
+	0x1F88: "\u0391\u0313\u0345",       // ᾈ
+	0x1F89: "\u0391\u0314\u0345",       // ᾉ
+	0x1F8A: "\u0391\u0313\u0300\u0345", // ᾊ
+	0x1F8B: "\u0391\u0314\u0300\u0345", // ᾋ
+	0x1F8C: "\u0391\u0313\u0301\u0345", // ᾌ
+	0x1F8D: "\u0391\u0314\u0301\u0345", // ᾍ
+	0x1F8E: "\u0391\u0313\u0342\u0345", // ᾎ
+	0x1F8F: "\u0391\u0314\u0342\u0345", // ᾏ
+	0x1F90: "\u03B7\u0313\u0345",       // ᾐ
+	0x1F91: "\u03B7\u0314\u0345",       // ᾑ
+	0x1F92: "\u03B7\u0313\u0300\u0345", // ᾒ
+	0x1F93: "\u03B7\u0314\u0300\u0345", // ᾓ
+	0x1F94: "\u03B7\u0313\u0301\u0345", // ᾔ
+	0x1F95: "\u03B7\u0314\u0301\u0345", // ᾕ
+	0x1F96: "\u03B7\u0313\u0342\u0345", // ᾖ
+	0x1F97: "\u03B7\u0314\u0342\u0345", // ᾗ
+	0x1F98: "\u0397\u0313\u0345",       // ᾘ
+	0x1F99: "\u0397\u0314\u0345",       // ᾙ
+	0x1F9A: "\u0397\u0313\u0300\u0345", // ᾚ
+	0x1F9B: "\u0397\u0314\u0300\u0345", // ᾛ
+	0x1F9C: "\u0397\u0313\u0301\u0345", // ᾜ
+	0x1F9D: "\u0397\u0314\u0301\u0345", // ᾝ
+	0x1F9E: "\u0397\u0313\u0342\u0345", // ᾞ
+	0x1F9F: "\u0397\u0314\u0342\u0345", // ᾟ
+	0x1FA0: "\u03C9\u0313\u0345",       // ᾠ
+	0x1FA1: "\u03C9\u0314\u0345",       // ᾡ
+	0x1FA2: "\u03C9\u0313\u0300\u0345", // ᾢ
+	0x1FA3: "\u03C9\u0314\u0300\u0345", // ᾣ
+	0x1FA4: "\u03C9\u0313\u0301\u0345", // ᾤ
+	0x1FA5: "\u03C9\u0314\u0301\u0345", // ᾥ
+	0x1FA6: "\u03C9\u0313\u0342\u0345", // ᾦ
+	0x1FA7: "\u03C9\u0314\u0342\u0345", // ᾧ
+	0x1FA8: "\u03A9\u0313\u0345",       // ᾨ
+	0x1FA9: "\u03A9\u0314\u0345",       // ᾩ
+	0x1FAA: "\u03A9\u0313\u0300\u0345", // ᾪ
+	0x1FAB: "\u03A9\u0314\u0300\u0345", // ᾫ
+	0x1FAC: "\u03A9\u0313\u0301\u0345", // ᾬ
+	0x1FAD: "\u03A9\u0314\u0301\u0345", // ᾭ
+	0x1FAE: "\u03A9\u0313\u0342\u0345", // ᾮ
+	0x1FAF: "\u03A9\u0314\u0342\u0345", // ᾯ
+	0x1FB0: "\u03B1\u0306",             // ᾰ
+	0x1FB1: "\u03B1\u0304",             // ᾱ
+	0x1FB2: "\u03B1\u0300\u0345",       // ᾲ
+	0x1FB3: "\u03B1\u0345",             // ᾳ
+	0x1FB4: "\u03B1\u0301\u0345",       // ᾴ
+	0x1FB6: "\u03B1\u0342",             // ᾶ
+	0x1FB7: "\u03B1\u0342\u0345",       // ᾷ
+	0x1FB8: "\u0391\u0306",             // Ᾰ
+	0x1FB9: "\u0391\u0304",             // Ᾱ
+	0x1FBA: "\u0391\u0300",             // Ὰ
+	0x1FBB: "\u0391\u0301",             // Ά
+	0x1FBC: "\u0391\u0345",             // ᾼ
+	0x1FBE: "\u03B9",                   // ι
+	0x1FC1: "\u00A8\u0342",             // ῁
+	0x1FC2: "\u03B7\u0300\u0345",       // ῂ
+	0x1FC3: "\u03B7\u0345",             // ῃ
+	0x1FC4: "\u03B7\u0301\u0345",       // ῄ
+	0x1FC6: "\u03B7\u0342",             // ῆ
+	0x1FC7: "\u03B7\u0342\u0345",       // ῇ
+	0x1FC8: "\u0395\u0300",             // Ὲ
+	0x1FC9: "\u0395\u0301",             // Έ
+	0x1FCA: "\u0397\u0300",             // Ὴ
+	0x1FCB: "\u0397\u0301",             // Ή
+	0x1FCC: "\u0397\u0345",             // ῌ
+	0x1FCD: "\u1FBF\u0300",             // ῍
+	0x1FCE: "\u1FBF\u0301",             // ῎
+	0x1FCF: "\u1FBF\u0342",             // ῏
+	0x1FD0: "\u03B9\u0306",             // ῐ
+	0x1FD1: "\u03B9\u0304",             // ῑ
+	0x1FD2: "\u03B9\u0308\u0300",       // ῒ
+	0x1FD3: "\u03B9\u0308\u0301",       // ΐ
+	0x1FD6: "\u03B9\u0342",             // ῖ
+	0x1FD7: "\u03B9\u0308\u0342",       // ῗ
+	0x1FD8: "\u0399\u0306",             // Ῐ
+	0x1FD9: "\u0399\u0304",             // Ῑ
+	0x1FDA: "\u0399\u0300",             // Ὶ
+	0x1FDB: "\u0399\u0301",             // Ί
+	0x1FDD: "\u1FFE\u0300",             // ῝
+	0x1FDE: "\u1FFE\u0301",             // ῞
+	0x1FDF: "\u1FFE\u0342",             // ῟
+	0x1FE0: "\u03C5\u0306",             // ῠ
+	0x1FE1: "\u03C5\u0304",             // ῡ
+	0x1FE2: "\u03C5\u0308\u0300",       // ῢ
+	0x1FE3: "\u03C5\u0308\u0301",       // ΰ
+	0x1FE4: "\u03C1\u0313",             // ῤ
+	0x1FE5: "\u03C1\u0314",             // ῥ
+	0x1FE6: "\u03C5\u0342",             // ῦ
+	0x1FE7: "\u03C5\u0308\u0342",       // ῧ
+	0x1FE8: "\u03A5\u0306",             // Ῠ
+	0x1FE9: "\u03A5\u0304",             // Ῡ
+	0x1FEA: "\u03A5\u0300",             // Ὺ
+	0x1FEB: "\u03A5\u0301",             // Ύ
+	0x1FEC: "\u03A1\u0314",             // Ῥ
+	0x1FED: "\u00A8\u0300",             // ῭
+	0x1FEE: "\u00A8\u0301",             // ΅
+	0x1FEF: "`",                        // `
+	0x1FF2: "\u03C9\u0300\u0345",       // ῲ
+	0x1FF3: "\u03C9\u0345",             // ῳ
+	0x1FF4: "\u03C9\u0301\u0345",       // ῴ
+	0x1FF6: "\u03C9\u0342",             // ῶ
+	0x1FF7: "\u03C9\u0342\u0345",       // ῷ
+	0x1FF8: "\u039F\u0300",             // Ὸ
+	0x1FF9: "\u039F\u0301",             // Ό
+	0x1FFA: "\u03A9\u0300",             // Ὼ
+	0x1FFB: "\u03A9\u0301",             // Ώ
+	0x1FFC: "\u03A9\u0345",             // ῼ
+	0x1FFD: "\u00B4",                   // ´
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import "testing"
+
+func TestNFD(fw *testing.T) {
+	tests := map[string]string{
+		"plain.txt":          "plain.txt",
+		"caf\u00E9":          "cafe\u0301",
+		"cafe\u0301":         "cafe\u0301",
+		"\u1EC7":             "e\u0323\u0302",
+		"\u0419":             "\u0418\u0306",
+		"\uD55C\uAE00":       "\u1112\u1161\u11AB\u1100\u1173\u11AF",
+		"\uAC00":             "\u1100\u1161",
+		"\u00C5ngstr\u00F6m": "A\u030Angstro\u0308m",
+		"\U0001F44D\u00E9":   "\U0001F44De\u0301",
+	}
+	for k, v := range tests {
+		if s := nfd(k); s != v {
+			fw.Errorf("nfd(%+q) = %+q, expected %+q", k, s, v)
+		}
+	}
+}
+
+func TestDecomposeNames(fw *testing.T) {
+	defer func(d bool) { decomposeNames = d }(decomposeNames)
+	m := New("")
+	tests := [][2]string{
+		{"caf\u00E9*", "cafe\u0301.txt"},
+		{"cafe\u0301*", "caf\u00E9.txt"},
+		{"\uD55C*", "\u1112\u1161\u11AB\u1100\u1173\u11AF"},
+	}
+	for _, k := range tests {
+		decomposeNames = false
+		if m.match(k[0], k[1]) {
+			fw.Errorf("match(%+q, %+q) succeeded without decomposition", k[0], k[1])
+		}
+		decomposeNames = true
+		if !m.match(k[0], k[1]) {
+			fw.Errorf("match(%+q, %+q) failed with decomposition", k[0], k[1])
+		}
+	}
+}
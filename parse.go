@@ -0,0 +1,329 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// parser holds the state of reading a single rule file,
+// such as the macros and variables it defines.
+type parser struct {
+	dialect Dialect
+	mode    Mode
+	macros  map[string][]string
+	vars    map[string][]string
+
+	// syntax is the current syntax of a .hgignore file.
+	syntax string
+
+	// git is whether a .gitignore file is read with
+	// Matcher.GitignoreSemantics.
+	git bool
+}
+
+func newParser(d Dialect) *parser {
+	return &parser{
+		dialect: d,
+		macros:  make(map[string][]string),
+		vars:    make(map[string][]string),
+		syntax:  "regexp",
+	}
+}
+
+// parseLine parses a line of a rule file that has been cleaned
+// and is not empty. The returned error is always a BadPatternError,
+// whose columns are those of s.
+func (p *parser) parseLine(s string) ([]Rule, error) {
+	rules, err := p.parse(s)
+	if err != nil {
+		err.(*BadPatternError).locate(s)
+	}
+	return rules, err
+}
+
+// parse is parseLine without locating errors.
+func (p *parser) parse(s string) ([]Rule, error) {
+	if p.mode == ModeStrict && (p.dialect == DialectNative || p.dialect == DialectGitignore) {
+		if column, ok := extension(s); ok {
+			return nil, &BadPatternError{Err: ErrExtension, Column: column, Line: -1}
+		}
+		// Braces are literal in gitignore files.
+		if open, _, _, ok := braces(s); ok && p.dialect == DialectNative {
+			return nil, &BadPatternError{Err: ErrExtension, Column: utf8.RuneCountInString(s[:open]), Line: -1}
+		}
+	}
+
+	switch p.dialect {
+	case DialectGitignore:
+		if p.git {
+			s = gitGlob(s)
+		}
+		r, err := parseGitignore(s)
+		if err != nil {
+			return nil, err
+		}
+		return []Rule{r}, nil
+	case DialectDocker:
+		return parseDocker(s)
+	case DialectHg:
+		return p.parseHg(s)
+	case DialectRsync:
+		return parseRsync(s)
+	}
+
+	switch {
+	case strings.HasPrefix(s, "define "):
+		return nil, p.define(strings.TrimPrefix(s, "define "))
+	case strings.HasPrefix(s, "set "):
+		return nil, p.set(strings.TrimPrefix(s, "set "))
+	}
+
+	lines, err := p.interpolate(s)
+	if err != nil {
+		return nil, &BadPatternError{Err: err, Column: 0, Line: -1}
+	}
+	var rules []Rule
+	for _, l := range lines {
+		alts, err := expandBraces(l)
+		if err != nil {
+			return nil, &BadPatternError{Err: err, Column: 0, Line: -1}
+		}
+		for _, a := range alts {
+			rs, err := p.rules(a)
+			if err != nil {
+				if len(alts) > 1 {
+					open, _, _, _ := braces(l)
+					err.(*BadPatternError).Column = utf8.RuneCountInString(l[:open])
+				}
+				return nil, err
+			}
+			rules = append(rules, rs...)
+		}
+	}
+	return rules, nil
+}
+
+// rules parses a line after variables have been interpolated.
+func (p *parser) rules(s string) ([]Rule, error) {
+	r, err := parseRule(s)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(r.Glob, "@") {
+		return []Rule{r}, nil
+	}
+
+	globs, err := p.expand(r.Glob[1:], make(map[string]bool))
+	if err != nil {
+		return nil, &BadPatternError{Err: err, Column: 0, Line: -1}
+	}
+	rules := make([]Rule, 0, len(globs))
+	for _, v := range globs {
+		alts, err := expandPattern(v)
+		if err != nil {
+			err.(*BadPatternError).Column = 0
+			return nil, err
+		}
+		for _, g := range alts {
+			e := r
+			g, dir := trimSlash(g)
+			e.Glob, e.DirOnly = g, r.DirOnly || dir
+			rules = append(rules, e)
+		}
+	}
+	return rules, nil
+}
+
+// define parses the remainder of a macro definition, "NAME = a, b, ...".
+func (p *parser) define(s string) error {
+	name, values, err := p.assignment(s)
+	if err == ErrBadVariable {
+		err = ErrBadMacro
+	}
+	if err != nil {
+		return &BadPatternError{Err: err, Column: 0, Line: -1}
+	}
+	p.macros[name] = values
+	return nil
+}
+
+// set parses the remainder of a variable definition, "name = a, b, ...".
+func (p *parser) set(s string) error {
+	name, values, err := p.assignment(s)
+	if err != nil {
+		return &BadPatternError{Err: err, Column: 0, Line: -1}
+	}
+	p.vars[name] = values
+	return nil
+}
+
+// assignment parses "name = a, b, ...", and interpolates variables in the
+// values. It returns ErrBadVariable if the syntax is wrong.
+func (p *parser) assignment(s string) (string, []string, error) {
+	i := strings.IndexByte(s, '=')
+	if i < 0 {
+		return "", nil, ErrBadVariable
+	}
+	name := strings.TrimSpace(s[:i])
+	if !isIdent(name) {
+		return "", nil, ErrBadVariable
+	}
+
+	var values []string
+	for _, v := range splitList(s[i+1:]) {
+		if v == "" {
+			return "", nil, ErrBadVariable
+		}
+		vs, err := p.interpolate(v)
+		if err != nil {
+			return "", nil, err
+		}
+		if values = append(values, vs...); len(values) > maxAlternatives {
+			return "", nil, ErrVariableExpansion
+		}
+	}
+	return name, values, nil
+}
+
+// interpolate replaces references of the form ${name} in s with the values
+// of the variable. Because a variable holds a list of values, the result
+// contains s once for each combination of values. A reference of the form
+// ${name:list} is replaced by all values at once, joined with the path list
+// separator of the OS. Like brace expressions, s may expand to at most
+// maxAlternatives strings.
+func (p *parser) interpolate(s string) ([]string, error) {
+	out := []string{""}
+	appendAll := func(suffixes ...string) {
+		next := make([]string, 0, len(out)*len(suffixes))
+		for _, o := range out {
+			for _, x := range suffixes {
+				next = append(next, o+x)
+			}
+		}
+		out = next
+	}
+
+	var start int
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, ErrBadVariable
+			}
+			name := s[i+2 : i+end]
+			list := strings.HasSuffix(name, ":list")
+			name = strings.TrimSuffix(name, ":list")
+			values, ok := p.vars[name]
+			if !ok {
+				return nil, ErrUndefinedVariable
+			}
+			if list {
+				values = []string{strings.Join(values, string(filepath.ListSeparator))}
+			}
+			if len(out)*len(values) > maxAlternatives {
+				return nil, ErrVariableExpansion
+			}
+			appendAll(s[start:i])
+			appendAll(values...)
+			i += end
+			start = i + 1
+		}
+	}
+	if start < len(s) {
+		appendAll(s[start:])
+	}
+	return out, nil
+}
+
+// expand returns the globs that the macro name stands for. Like brace
+// expressions, a macro may expand to at most maxAlternatives globs.
+func (p *parser) expand(name string, seen map[string]bool) ([]string, error) {
+	values, ok := p.macros[name]
+	if !ok {
+		return nil, ErrUndefinedMacro
+	}
+	if seen[name] {
+		return nil, ErrMacroCycle
+	}
+	seen[name] = true
+	defer delete(seen, name)
+
+	var globs []string
+	for _, v := range values {
+		if !strings.HasPrefix(v, "@") {
+			globs = append(globs, v)
+			continue
+		}
+		sub, err := p.expand(v[1:], seen)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, sub...)
+	}
+	if len(globs) > maxAlternatives {
+		return nil, ErrMacroExpansion
+	}
+	return globs, nil
+}
+
+// splitList splits s at commas that are neither escaped nor within braces,
+// and trims spaces around each element.
+func splitList(s string) []string {
+	var list []string
+	var start int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			if end, _ := braceEnd(s, i); end >= 0 {
+				i = end
+			}
+		case ',':
+			list = append(list, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(list, strings.TrimSpace(s[start:]))
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case i > 0 && '0' <= r && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// line parses s as a line of a rule file in the directory base, with globs
+// anchored accordingly. Blank lines and comments yield no rules.
+// The returned error is always a BadPatternError.
+func (w *Worker) line(p *parser, s, base string) ([]Rule, error) {
+	s = Clean(s)
+	if s == "" {
+		return nil, nil
+	}
+	rules, err := p.parseLine(s)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		w.anchorRule(&rules[i], base)
+	}
+	return rules, nil
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseAll parses the lines of a rule file, and returns the globs of the
+// resulting rules, or the error of the first line that failed.
+func parseAll(lines ...string) ([]string, error) {
+	var globs []string
+	p := newParser(DialectNative)
+	for _, l := range lines {
+		rules, err := p.parseLine(Clean(l))
+		if err != nil {
+			return nil, err.(*BadPatternError).Err
+		}
+		for _, r := range rules {
+			globs = append(globs, r.Glob)
+		}
+	}
+	return globs, nil
+}
+
+func TestMacros(fw *testing.T) {
+	type result struct {
+		Globs []string
+		Err   error
+	}
+	tests := map[string]result{
+		"define SRC = *.go, *.c\n@SRC":                  {[]string{"*.go", "*.c"}, nil},
+		"define A = x\ndefine B = @A, y\n@B":            {[]string{"x", "y"}, nil},
+		"define A = x\ndefine A = y\n@A":                {[]string{"y"}, nil},
+		"define LIST = a\\,b, c\n@LIST":                 {[]string{"a\\,b", "c"}, nil},
+		"define MEDIA = *.mp4\nsize:>1M @MEDIA":         {[]string{"*.mp4"}, nil},
+		"\\@SRC":                                        {[]string{"\\@SRC"}, nil},
+		"@SRC":                                          {nil, ErrUndefinedMacro},
+		"define A = @B\ndefine B = @A\n@A":              {nil, ErrMacroCycle},
+		"define 1A = x":                                 {nil, ErrBadMacro},
+		"define A x":                                    {nil, ErrBadMacro},
+		"define A = x,,y":                               {nil, ErrBadMacro},
+		"define BAD = [\n@BAD":                          {nil, ErrIncompleteClass},
+		"define A = x\n@B":                              {nil, ErrUndefinedMacro},
+		"define A = x\ndefine B = @A, @A\n@B\n@A\n\\@A": {[]string{"x", "x", "x", "\\@A"}, nil},
+	}
+
+	// Macros referencing each other several times grow exponentially,
+	// so the number of globs they expand to is capped.
+	lines := []string{"define A0 = x"}
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, fmt.Sprintf("define A%d = @A%d, @A%d", i, i-1, i-1))
+	}
+	tests[strings.Join(append(lines, "@A40"), "\n")] = result{nil, ErrMacroExpansion}
+	tests[strings.Join(append(lines, "@A11"), "\n")] = result{nil, ErrMacroExpansion}
+
+	for k, v := range tests {
+		globs, err := parseAll(strings.Split(k, "\n")...)
+		if err != v.Err || !reflect.DeepEqual(globs, v.Globs) {
+			fw.Errorf("parsing %q = (%q, %v), expected (%q, %v)", k, globs, err, v.Globs, v.Err)
+		}
+	}
+	if globs, err := parseAll(append(lines, "@A10")...); err != nil || len(globs) != 1024 {
+		fw.Errorf("parsing @A10 = (%d globs, %v), expected 1024 globs", len(globs), err)
+	}
+}
+
+func TestVariables(fw *testing.T) {
+	type result struct {
+		Globs []string
+		Err   error
+	}
+	sep := string(filepath.ListSeparator)
+	tests := map[string]result{
+		"set ext = log, tmp\n*.${ext}":                  {[]string{"*.log", "*.tmp"}, nil},
+		"set a = x, y\nset b = 1, 2\n${a}${b}":          {[]string{"x1", "x2", "y1", "y2"}, nil},
+		"set a = x\nset b = ${a}.bak, y\n${b}":          {[]string{"x.bak", "y"}, nil},
+		"set ext = o\ndefine OBJ = *.${ext}, *.a\n@OBJ": {[]string{"*.o", "*.a"}, nil},
+		"set dir = build\ntype:dir ${dir}":              {[]string{"build"}, nil},
+		"set x = 1\nfoo\\${x}":                          {[]string{"foo\\${x}"}, nil},
+		"$HOME/x":                                       {[]string{"$HOME/x"}, nil},
+		"${undefined}":                                  {nil, ErrUndefinedVariable},
+		"set x = 1\n${x":                                {nil, ErrBadVariable},
+		"set = 1":                                       {nil, ErrBadVariable},
+		"set x 1":                                       {nil, ErrBadVariable},
+		"set x = ${y}":                                  {nil, ErrUndefinedVariable},
+		"define A = ${y}":                               {nil, ErrUndefinedVariable},
+		"set ext = log, tmp\ndefine L = *.${ext}\n@L\n${ext}": {[]string{"*.log", "*.tmp", "log", "tmp"}, nil},
+		"set d = a, b\nx${d:list}":                            {[]string{"xa" + sep + "b"}, nil},
+		"set d = a, b\n${d:list}/${d}":                        {[]string{"a" + sep + "b/a", "a" + sep + "b/b"}, nil},
+		"set d = a, b\nset e = ${d:list}\n${e}":               {[]string{"a" + sep + "b"}, nil},
+		"${d:list}":                                           {nil, ErrUndefinedVariable},
+	}
+	// Lists used together grow exponentially, so their product is capped.
+	set := "set a = 0, 1, 2, 3, 4, 5, 6, 7"
+	tests[set+"\n"+strings.Repeat("${a}", 10)] = result{nil, ErrVariableExpansion}
+	tests[set+"\nset b = "+strings.Repeat("${a}", 4)] = result{nil, ErrVariableExpansion}
+	tests[set+"\nset b = ${a}${a}\nset c = ${b}${a}, ${b}${a}, ${b}${a}\n${a}"] = result{nil, ErrVariableExpansion}
+
+	for k, v := range tests {
+		globs, err := parseAll(strings.Split(k, "\n")...)
+		if err != v.Err || !reflect.DeepEqual(globs, v.Globs) {
+			fw.Errorf("parsing %q = (%q, %v), expected (%q, %v)", k, globs, err, v.Globs, v.Err)
+		}
+	}
+
+	if globs, err := parseAll(set, strings.Repeat("${a}", 3)); err != nil || len(globs) != 512 {
+		fw.Errorf("parsing 3 references = (%d globs, %v), expected 512 globs", len(globs), err)
+	}
+}
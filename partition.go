@@ -0,0 +1,73 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Partition reads a list of paths from r and splits it into the paths that
+// the Worker matches and those it does not, keeping their order. The paths
+// are separated by NUL bytes, as output by "git ls-files -z" or
+// "find -print0", or by newlines if there is no NUL byte in the input.
+// Relative paths are relative to the working directory of the Worker.
+//
+// A path beneath a directory that the Worker matches is matched as well,
+// as when walking directories, since the lists usually contain only files.
+// The decision for each directory is made only once.
+//
+// The input is read into a single string, of which the returned paths are
+// substrings, so that the paths are not copied.
+func (w *Worker) Partition(r io.Reader) (matched, unmatched []string, err error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := string(b)
+	sep := "\x00"
+	if !strings.Contains(s, sep) {
+		sep = "\n"
+	}
+
+	dirs := make(map[string]bool)
+	for s != "" {
+		var p string
+		if i := strings.Index(s, sep); i >= 0 {
+			p, s = s[:i], s[i+len(sep):]
+		} else {
+			p, s = s, ""
+		}
+		if sep == "\n" {
+			p = strings.TrimSuffix(p, "\r")
+		}
+		if p == "" {
+			continue
+		}
+		if w.inMatchedDir(p, dirs) || w.Matches(p) {
+			matched = append(matched, p)
+		} else {
+			unmatched = append(unmatched, p)
+		}
+	}
+	return matched, unmatched, nil
+}
+
+// inMatchedDir returns whether any of the directories above p is matched,
+// remembering the decisions in dirs.
+func (w *Worker) inMatchedDir(p string, dirs map[string]bool) bool {
+	d := filepath.Dir(p)
+	if d == "." || d == p || d == filepath.Dir(d) {
+		return false
+	}
+	m, ok := dirs[d]
+	if !ok {
+		m = w.inMatchedDir(d, dirs) || w.Matches(d)
+		dirs[d] = m
+	}
+	return m
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/goulash/matcher/glob"
+)
+
+// Pattern is the glob of a rule compiled for matching paths, so that it is
+// not parsed again for every path it is matched against. It is split into
+// the elements of the path if it contains "**", and its literal prefix and
+// suffix, as well as its character classes, are prepared in advance.
+// It is safe for concurrent use.
+//
+// Workers compile the globs of their rules when they are added, and keep
+// them in a cache shared by all Workers, so Pattern is only needed to match
+// paths without a Worker.
+type Pattern struct {
+	pattern string
+
+	// base is whether the pattern has no separator, so that it is matched
+	// against the last element of a path.
+	base bool
+
+	// seg is the compiled pattern if it has no "**" element, and elems
+	// are its elements otherwise.
+	seg   segment
+	elems []element
+
+	// alts are the compiled globs that the brace expressions of the
+	// pattern expand to, if it has any, in which case only they are set.
+	alts []*Pattern
+}
+
+// element is an element of a path pattern containing "**".
+type element struct {
+	dualStar bool
+	seg      segment
+}
+
+// segment is a glob without "**" compiled for matching. Unescaped whitespace
+// at its end, which glob.Compile does not accept, but which may end an
+// element of a path pattern, is split off as trail and compared literally.
+// If the rest is empty, it matches only the empty string, and if glob is
+// nil otherwise, the pattern is malformed and nothing is matched, so that
+// matching cannot fail even for a pattern that was not checked.
+type segment struct {
+	empty bool
+	glob  *glob.Glob
+	trail string
+}
+
+// escapes is whether a backslash escapes the next character in globs,
+// as it does in package glob everywhere but on Windows.
+const escapes = filepath.Separator != '\\'
+
+// compileSegment compiles pattern, which should have been checked and has
+// no "**" elements.
+func compileSegment(pattern string) segment {
+	i := len(strings.TrimRight(pattern, " \t\n"))
+	if i < len(pattern) && escapes {
+		// An escaped whitespace character belongs to the glob.
+		n := 0
+		for n < i && pattern[i-n-1] == '\\' {
+			n++
+		}
+		if n%2 == 1 {
+			i++
+		}
+	}
+	s := segment{empty: i == 0, trail: pattern[i:]}
+	if !s.empty {
+		s.glob, _ = glob.Compile(pattern[:i])
+	}
+	return s
+}
+
+// match reports whether the segment matches the whole string s.
+func (g segment) match(s string) bool {
+	if !strings.HasSuffix(s, g.trail) {
+		return false
+	}
+	s = s[:len(s)-len(g.trail)]
+	if g.empty {
+		return s == ""
+	}
+	return g.glob != nil && g.glob.MatchString(s)
+}
+
+// CompilePattern checks pattern as Check does and compiles it. The Pattern
+// matches a path if a rule consisting of pattern alone would, as described
+// for MatchPattern. The only possible error is a BadPatternError.
+func CompilePattern(pattern string) (*Pattern, error) {
+	globs, err := expandPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(globs) == 1 {
+		return compilePattern(pattern), nil
+	}
+	p := &Pattern{pattern: pattern, alts: make([]*Pattern, len(globs))}
+	for i, g := range globs {
+		p.alts[i] = compiled(g)
+	}
+	return p, nil
+}
+
+// compilePattern compiles pattern, which should have been checked.
+func compilePattern(pattern string) *Pattern {
+	p := &Pattern{pattern: pattern, base: !strings.Contains(pattern, "/")}
+	if !hasDualStar(pattern) {
+		p.seg = compileSegment(pattern)
+		return p
+	}
+	for _, e := range strings.Split(pattern, "/") {
+		if e == "**" {
+			p.elems = append(p.elems, element{dualStar: true})
+			continue
+		}
+		p.elems = append(p.elems, element{seg: compileSegment(e)})
+	}
+	return p
+}
+
+// String returns the source of the pattern.
+func (p *Pattern) String() string {
+	return p.pattern
+}
+
+// Match reports whether path is matched by p.
+func (p *Pattern) Match(path string) bool {
+	if p.pattern == "" {
+		return false
+	}
+	if p.alts != nil {
+		for _, a := range p.alts {
+			if a.Match(path) {
+				return true
+			}
+		}
+		return false
+	}
+	if p.base {
+		path = filepath.Base(path)
+	}
+	if p.elems != nil {
+		return matchElems(p.elems, strings.Split(path, "/"))
+	}
+	return p.seg.match(path)
+}
+
+// matchElems matches the elements of a path against those of a pattern
+// containing "**". A "**" in the middle or at the start matches zero or more
+// elements, but at the end it matches one or more, i.e. everything beneath
+// a directory, but not the directory itself.
+func matchElems(pattern []element, elems []string) bool {
+	for i, p := range pattern {
+		if p.dualStar {
+			rest := pattern[i+1:]
+			if len(rest) == 0 {
+				return len(elems) > i
+			}
+			for j := i; j <= len(elems); j++ {
+				if matchElems(rest, elems[j:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if i >= len(elems) {
+			return false
+		}
+		if !p.seg.match(elems[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(elems)
+}
+
+// maxCompiled is the number of compiled patterns or regular expressions
+// beyond which a compileCache is cleared, so that programs adding ever new
+// rules do not keep all of them forever.
+const maxCompiled = 1 << 14
+
+// compileCache holds compiled patterns or regular expressions by their
+// source. It is safe for concurrent use. They are looked up in a read-only
+// map without locking; new ones are added to a copy of it, which replaces
+// it once it has been missed about as often as it has entries, as in
+// sync.Map, but without boxing every source to look it up.
+type compileCache struct {
+	read atomic.Value // map[string]interface{}
+
+	mu      sync.Mutex
+	dirty   map[string]interface{}
+	misses  int
+	compile func(string) interface{}
+}
+
+// get returns the compiled form of s, compiling it if necessary.
+func (c *compileCache) get(s string) interface{} {
+	read, _ := c.read.Load().(map[string]interface{})
+	if v, ok := read[s]; ok {
+		return v
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	read, _ = c.read.Load().(map[string]interface{})
+	if v, ok := read[s]; ok {
+		return v
+	}
+	if c.dirty == nil {
+		if len(read) >= maxCompiled {
+			read = nil
+		}
+		c.dirty = make(map[string]interface{}, len(read)+1)
+		for k, v := range read {
+			c.dirty[k] = v
+		}
+	}
+	v, ok := c.dirty[s]
+	if !ok {
+		v = c.compile(s)
+		c.dirty[s] = v
+	}
+	if c.misses++; c.misses >= len(c.dirty) {
+		c.read.Store(c.dirty)
+		c.dirty, c.misses = nil, 0
+	}
+	return v
+}
+
+// patternCache holds the compiled globs of rules.
+var patternCache = compileCache{compile: func(s string) interface{} {
+	return compilePattern(s)
+}}
+
+// compiled returns the compiled form of a checked pattern.
+func compiled(pattern string) *Pattern {
+	return patternCache.get(pattern).(*Pattern)
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompilePattern(fw *testing.T) {
+	tests := map[[2]string]bool{
+		{"*.o", "obj/a.o"}:             true,
+		{"*.o", "a.c"}:                 false,
+		{"obj/*.o", "obj/a.o"}:         true,
+		{"obj/*.o", "x/obj/a.o"}:       false,
+		{"**/obj", "x/y/obj"}:          true,
+		{"obj/**", "obj"}:              false,
+		{"obj/**", "obj/a/b"}:          true,
+		{"/a/**/b", "/a/b"}:            true,
+		{"/a/**/b", "/a/x/y/b"}:        true,
+		{"a \\ /**/b", "a  /b"}:        true,
+		{"a /**/b", "a /x/b"}:          true,
+		{"a* /**/b", "ab /x/b"}:        true,
+		{"a* /**/b", "ab/x/b"}:         false,
+		{"a\\  /**", "a  /x"}:          true,
+		{"[a-c]?", "x/bz"}:             true,
+		{"\\[x]", "[x]"}:               true,
+		{"**/*.tar.gz", "d/a.tar.gz"}:  true,
+		{"**/*.tar.gz", "d/a.tar.gzx"}: false,
+	}
+	for k, v := range tests {
+		p, err := CompilePattern(k[0])
+		if err != nil {
+			fw.Errorf("CompilePattern(%q) = %v", k[0], err)
+			continue
+		}
+		if m := p.Match(k[1]); m != v {
+			fw.Errorf("CompilePattern(%q).Match(%q) = %v, expected %v", k[0], k[1], m, v)
+		}
+		if p.String() != k[0] {
+			fw.Errorf("CompilePattern(%q).String() = %q", k[0], p.String())
+		}
+	}
+
+	if _, err := CompilePattern("a["); err == nil {
+		fw.Errorf("CompilePattern(%q) succeeded, expected error", "a[")
+	}
+	for _, s := range []string{"a[", "*[", "[^]", "a[/**/b"} {
+		if compilePattern(s).Match("a[/b") {
+			fw.Errorf("unchecked %q matches", s)
+		}
+	}
+}
+
+func TestCompileCacheLimit(fw *testing.T) {
+	c := compileCache{compile: func(s string) interface{} { return s }}
+	for i := 0; i < 2*maxCompiled; i++ {
+		c.get(fmt.Sprint(i))
+	}
+	read, _ := c.read.Load().(map[string]interface{})
+	if len(read) > 2*maxCompiled || len(c.dirty) > 2*maxCompiled {
+		fw.Errorf("compileCache holds %d and %d entries, expected at most %d", len(read), len(c.dirty), 2*maxCompiled)
+	}
+	if v := c.get("7"); v != "7" {
+		fw.Errorf("c.get(%q) = %v", "7", v)
+	}
+}
+
+// benchRules are thousands of patterns of the kinds found in rule files.
+var benchRules = func() []string {
+	var rules []string
+	for i := 0; i < 1000; i++ {
+		rules = append(rules,
+			fmt.Sprintf("*.ext%d", i),
+			fmt.Sprintf("build%d/*", i),
+			fmt.Sprintf("cache%d-[0-9]*", i),
+		)
+	}
+	return rules
+}()
+
+var benchPath = "src/pkg/module/file.ext999x"
+
+// BenchmarkPatterns matches a path against thousands of patterns, once by
+// parsing each pattern for every match as filepath.Match does, and once with
+// the patterns compiled in advance.
+func BenchmarkPatterns(b *testing.B) {
+	b.Run("filepath", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, r := range benchRules {
+				s := benchPath
+				if !strings.Contains(r, "/") {
+					s = filepath.Base(s)
+				}
+				filepath.Match(r, s)
+			}
+		}
+	})
+	b.Run("compiled", func(b *testing.B) {
+		ps := make([]*Pattern, len(benchRules))
+		for i, r := range benchRules {
+			ps[i] = compilePattern(r)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, p := range ps {
+				p.Match(benchPath)
+			}
+		}
+	})
+}
+
+// BenchmarkWorkerPatterns is like BenchmarkPatterns, but with the patterns
+// as the rules of a Worker, which looks up their compiled form in a cache.
+func BenchmarkWorkerPatterns(b *testing.B) {
+	w, err := New("").NewWorker(".")
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := w.AddString(strings.Join(benchRules, "\n")); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Matches(benchPath)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultPoolSize is the default of Matcher.PoolSize.
+const DefaultPoolSize = 64
+
+// workerPool contains the Workers of WorkerFor, with the least recently
+// used at the back of the list.
+type workerPool struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     list.List
+}
+
+// poolEntry is a Worker in the pool, along with the state of the rule
+// files and directories that it was created from.
+type poolEntry struct {
+	dir    string
+	w      *Worker
+	paths  []string
+	stamps []stamp
+}
+
+// fresh reports whether the Worker of e is still up to date, given the
+// paths of the rule files that a Worker for its directory would read.
+func (e *poolEntry) fresh(paths []string) bool {
+	if len(e.paths) < len(paths) {
+		return false
+	}
+	for i, p := range paths {
+		if e.paths[i] != p {
+			return false
+		}
+	}
+	return equalStamps(e.stamps, stamps(e.paths))
+}
+
+// stamp is the state of a rule file or directory at some point in time.
+type stamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// WorkerFor returns a Worker for dir, like NewWorker, but keeps the Workers
+// it creates in a pool, so that servers handling requests for many paths
+// do not read the same configuration files over and over again. The pool
+// holds at most PoolSize Workers, and the least recently used one is
+// dropped when it is full.
+//
+// On every call, the rule files that a Worker for dir would read or has
+// read are checked, which is much cheaper than reading them, and so are
+// the directories that DiscoverDepth made it look for rule files in. If
+// any of them has been created, changed, or removed since the pooled
+// Worker was created, a new one is created in its place. The pool is
+// emptied when globs are added to the Matcher or scopes are changed.
+//
+// The returned Worker is a clone of the pooled one, so that it can be
+// changed and used without affecting other callers. If NewWorker returns
+// an error, the Worker is not pooled. WorkerFor is safe to call
+// concurrently, as long as the Matcher is not changed at the same time.
+func (m *Matcher) WorkerFor(dir string) (*Worker, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := m.configPaths(dir)
+	before := stamps(paths)
+
+	p := &m.pool
+	p.mu.Lock()
+	if e, ok := p.entries[dir]; ok {
+		entry := e.Value.(*poolEntry)
+		if entry.fresh(paths) {
+			p.lru.MoveToFront(e)
+			w := entry.w.Clone()
+			p.mu.Unlock()
+			return w, nil
+		}
+		p.lru.Remove(e)
+		delete(p.entries, dir)
+	}
+	p.mu.Unlock()
+
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		return w, err
+	}
+	var read []string
+	for _, c := range w.configs {
+		read = append(read, c.path)
+	}
+	read = append(read, w.scanned...)
+	entry := &poolEntry{
+		dir:    dir,
+		w:      w,
+		paths:  append(paths, read...),
+		stamps: append(before, stamps(read)...),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.entries == nil {
+		p.entries = make(map[string]*list.Element)
+	}
+	if e, ok := p.entries[dir]; ok {
+		p.lru.Remove(e)
+	}
+	p.entries[dir] = p.lru.PushFront(entry)
+	for p.lru.Len() > m.poolSize() {
+		e := p.lru.Back()
+		p.lru.Remove(e)
+		delete(p.entries, e.Value.(*poolEntry).dir)
+	}
+	return w.Clone(), nil
+}
+
+func (m *Matcher) poolSize() int {
+	if m.PoolSize <= 0 {
+		return DefaultPoolSize
+	}
+	return m.PoolSize
+}
+
+// resetPool empties the pool of WorkerFor.
+func (m *Matcher) resetPool() {
+	m.pool.mu.Lock()
+	m.pool.entries = nil
+	m.pool.lru.Init()
+	m.pool.mu.Unlock()
+}
+
+// configPaths returns the paths of the rule files that a Worker for dir
+// reads if they exist, not including those found by discovery.
+func (m *Matcher) configPaths(dir string) []string {
+	var paths []string
+	if len(m.names) > 0 && !m.disabled[ScopeProject] {
+		for _, d := range m.configDirs(dir) {
+			for _, name := range m.configNames() {
+				paths = append(paths, filepath.Join(d, name))
+			}
+		}
+	}
+	if !m.disabled[ScopeUser] {
+		paths = append(paths, m.userFile(), m.homeFile())
+	}
+	if !m.disabled[ScopeSystem] {
+		paths = append(paths, m.SystemFile)
+	}
+	return paths
+}
+
+// stamps returns the state of the files or directories at paths,
+// whether they exist or not.
+func stamps(paths []string) []stamp {
+	stamps := make([]stamp, len(paths))
+	for i, p := range paths {
+		if p == "" {
+			continue
+		}
+		if fi, err := os.Stat(p); err == nil {
+			stamps[i] = stamp{exists: true, modTime: fi.ModTime(), size: fi.Size()}
+		}
+	}
+	return stamps
+}
+
+func equalStamps(a, b []stamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].exists != b[i].exists || !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkerFor(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+	ioutil.WriteFile(filepath.Join(dir, "rules"), []byte("*.a\n"), 0644)
+
+	m := New("rules")
+	pooled := func(d string) *Worker {
+		m.pool.mu.Lock()
+		defer m.pool.mu.Unlock()
+		if e, ok := m.pool.entries[d]; ok {
+			return e.Value.(*poolEntry).w
+		}
+		return nil
+	}
+
+	w, err := m.WorkerFor(sub)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	first := pooled(sub)
+	if first == nil || first == w || !w.Matches("x.a") {
+		fw.Fatalf("m.WorkerFor() did not pool a working Worker")
+	}
+	w.Add("*.b")
+	if w, _ = m.WorkerFor(sub); pooled(sub) != first || w.Matches("x.b") {
+		fw.Errorf("m.WorkerFor() did not return a fresh clone of the pooled Worker")
+	}
+
+	ioutil.WriteFile(filepath.Join(sub, "rules.local"), []byte("*.c\n"), 0644)
+	if w, _ = m.WorkerFor(sub); pooled(sub) == first || !w.Matches("x.c") {
+		fw.Errorf("m.WorkerFor() did not notice a new configuration file")
+	}
+	ioutil.WriteFile(filepath.Join(dir, "rules"), []byte("*.aa\n"), 0644)
+	if w, _ = m.WorkerFor(sub); w.Matches("x.a") || !w.Matches("x.aa") {
+		fw.Errorf("m.WorkerFor() did not notice a changed configuration file")
+	}
+
+	m.Add("*.g")
+	if w, _ = m.WorkerFor(sub); !w.Matches("x.g") {
+		fw.Errorf("m.WorkerFor() returned a Worker without the new global glob")
+	}
+
+	m.PoolSize = 1
+	m.WorkerFor(dir)
+	if m.pool.lru.Len() != 1 || pooled(dir) == nil || pooled(sub) != nil {
+		fw.Errorf("m.WorkerFor() did not drop the least recently used Worker")
+	}
+}
+
+func TestWorkerForDiscover(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"a", "b", "c/d"} {
+		os.MkdirAll(filepath.Join(dir, d), 0755)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "a", "rules"), []byte("*.a\n"), 0644)
+
+	m := New("rules")
+	m.Root = dir
+	m.DiscoverDepth = 2
+	tests := []struct {
+		desc     string
+		change   func()
+		path     string
+		expected bool
+	}{
+		{"", func() {}, "a/x.a", true},
+		{"a changed nested file", func() {
+			ioutil.WriteFile(filepath.Join(dir, "a", "rules"), []byte("*.aa\n"), 0644)
+		}, "a/x.aa", true},
+		{"a new nested file", func() {
+			ioutil.WriteFile(filepath.Join(dir, "b", "rules"), []byte("*.b\n"), 0644)
+		}, "b/x.b", true},
+		{"a new file in a new directory", func() {
+			os.Mkdir(filepath.Join(dir, "c", "e"), 0755)
+			ioutil.WriteFile(filepath.Join(dir, "c", "e", "rules"), []byte("*.e\n"), 0644)
+		}, "c/e/x.e", true},
+		{"a new sentinel", func() {
+			os.Mkdir(filepath.Join(dir, "b", ".git"), 0755)
+		}, "b/x.b", false},
+	}
+	for _, t := range tests {
+		t.change()
+		w, err := m.WorkerFor(dir)
+		if err != nil {
+			fw.Fatal(err)
+		}
+		if w.Matches(t.path) != t.expected {
+			fw.Errorf("m.WorkerFor() did not notice %s: w.Matches(%q) = %v", t.desc, t.path, !t.expected)
+		}
+	}
+}
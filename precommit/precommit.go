@@ -0,0 +1,123 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package precommit implements git pre-commit hooks that refuse to commit
+// files matched by a set of forbidden rules, such as credentials or build
+// outputs. A hook is a small program:
+//
+//	func main() {
+//		precommit.Main(".forbidden")
+//	}
+//
+// which is run from the hook script with the staged files:
+//
+//	git diff --cached --name-only -z --diff-filter=ACMR | forbidden-check
+//
+// Paths are read from the arguments, or if there are none, from standard
+// input, separated by newlines or NUL characters.
+package precommit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/goulash/matcher"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK        = 0
+	ExitForbidden = 1
+	ExitError     = 2
+)
+
+// Forbidden returns the paths that w matches, in the order given.
+func Forbidden(w *matcher.Worker, paths []string) []string {
+	var bad []string
+	for _, p := range paths {
+		if w.Matches(p) {
+			bad = append(bad, p)
+		}
+	}
+	return bad
+}
+
+// Run checks the paths in args, or if there are none, those read from stdin,
+// against w. Forbidden paths are reported on stderr. It returns ExitForbidden
+// if there are any, ExitError if stdin cannot be read, and ExitOK otherwise.
+func Run(w *matcher.Worker, args []string, stdin io.Reader, stderr io.Writer) int {
+	paths := args
+	if len(paths) == 0 {
+		var err error
+		paths, err = readPaths(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "precommit: %s\n", err)
+			return ExitError
+		}
+	}
+
+	bad := Forbidden(w, paths)
+	if len(bad) == 0 {
+		return ExitOK
+	}
+	fmt.Fprintf(stderr, "precommit: refusing to commit %d forbidden file(s):\n", len(bad))
+	for _, p := range bad {
+		fmt.Fprintf(stderr, "\t%s\n", p)
+	}
+	return ExitForbidden
+}
+
+// readPaths reads paths separated by NUL characters if there are any,
+// and by newlines otherwise. Empty paths are skipped.
+func readPaths(r io.Reader) ([]string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sep := []byte{'\n'}
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = []byte{0}
+	}
+
+	var paths []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, sep); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	for sc.Scan() {
+		if p := string(bytes.TrimSuffix(sc.Bytes(), []byte{'\r'})); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, sc.Err()
+}
+
+// Main runs a hook in the current directory, which is the root of the
+// repository when git runs hooks, with the forbidden rules in the rule
+// file named file. It exits the program with the code returned by Run.
+func Main(file string) {
+	os.Exit(hook(file, os.Args[1:], os.Stdin, os.Stderr))
+}
+
+func hook(file string, args []string, stdin io.Reader, stderr io.Writer) int {
+	w, err := matcher.New("").NewWorker(".")
+	if err == nil {
+		err = w.AddFile(file)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "precommit: %s\n", err)
+		return ExitError
+	}
+	return Run(w, args, stdin, stderr)
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package precommit
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goulash/matcher"
+)
+
+func TestReadPaths(fw *testing.T) {
+	tests := map[string][]string{
+		"a\nb/c\n":     {"a", "b/c"},
+		"a\r\nb":       {"a", "b"},
+		"a\x00b c\x00": {"a", "b c"},
+		"a\nb\x00":     {"a\nb"},
+		"":             nil,
+	}
+	for k, v := range tests {
+		paths, err := readPaths(strings.NewReader(k))
+		if err != nil || !reflect.DeepEqual(paths, v) {
+			fw.Errorf("readPaths(%q) = (%q, %v), expected %q", k, paths, err, v)
+		}
+	}
+}
+
+func TestRun(fw *testing.T) {
+	w, err := matcher.New("").NewWorker(".")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.pem", "id_rsa")
+
+	var stderr bytes.Buffer
+	if c := Run(w, []string{"main.go", "README"}, nil, &stderr); c != ExitOK || stderr.Len() != 0 {
+		fw.Errorf("Run() = %d, %q, expected %d", c, stderr.String(), ExitOK)
+	}
+	stdin := strings.NewReader("main.go\x00keys/server.pem\x00.ssh/id_rsa\x00")
+	if c := Run(w, nil, stdin, &stderr); c != ExitForbidden {
+		fw.Errorf("Run() = %d, expected %d", c, ExitForbidden)
+	}
+	if s := stderr.String(); !strings.Contains(s, "keys/server.pem") || !strings.Contains(s, ".ssh/id_rsa") {
+		fw.Errorf("Run() reported %q", s)
+	}
+
+	if c := hook("does-not-exist", nil, strings.NewReader(""), &stderr); c != ExitError {
+		fw.Errorf("hook() with missing rule file = %d, expected %d", c, ExitError)
+	}
+}
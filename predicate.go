@@ -0,0 +1,467 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// Predicate is a condition on a file besides its name, such as its size
+// or its type. Predicates are written in front of the glob in rule files.
+type Predicate interface {
+	// Match reports whether the file at path with the info fi satisfies
+	// the predicate.
+	Match(path string, fi os.FileInfo) bool
+}
+
+// PredicateFunc is an adapter to allow the use of an ordinary function
+// as a Predicate.
+type PredicateFunc func(path string, fi os.FileInfo) bool
+
+// Match calls f(path, fi).
+func (f PredicateFunc) Match(path string, fi os.FileInfo) bool {
+	return f(path, fi)
+}
+
+// RegisterPredicate makes a predicate available in rule files under name,
+// so that applications can add domain-specific conditions, such as "owner:"
+// or "label:", alongside the built-in ones. When a rule file containing
+// name:arg is loaded, parse is called with arg, and the returned predicate
+// is used for matching. If parse returns an error, loading the file fails
+// with ErrBadPredicate. An argument that is a list of paths should be split
+// with filepath.SplitList, so that rule files can pass a variable holding
+// the list as "${name:list}".
+//
+// RegisterPredicate is meant to be called from an init function. It panics
+// if name is empty, contains a colon or whitespace, or is already registered.
+func RegisterPredicate(name string, parse func(arg string) (Predicate, error)) {
+	if name == "" || strings.ContainsAny(name, ": \t\n\\") {
+		panic("matcher: invalid predicate name " + strconv.Quote(name))
+	}
+	if parse == nil {
+		panic("matcher: RegisterPredicate parse function is nil")
+	}
+
+	predicatesMu.Lock()
+	defer predicatesMu.Unlock()
+	if _, dup := predicates[name]; dup {
+		panic("matcher: RegisterPredicate called twice for " + name)
+	}
+	predicates[name] = parse
+}
+
+func lookupPredicate(name string) (func(string) (Predicate, error), bool) {
+	predicatesMu.RLock()
+	defer predicatesMu.RUnlock()
+	parse, ok := predicates[name]
+	return parse, ok
+}
+
+var (
+	predicatesMu sync.RWMutex
+
+	// predicates maps the name of a predicate, i.e. what precedes the colon,
+	// to the function that parses its argument.
+	predicates = map[string]func(arg string) (Predicate, error){
+		"size":    parseSize,
+		"type":    parseType,
+		"mtime":   parseMtime,
+		"content": parseContent,
+		"mime":    parseMime,
+		"expires": parseExpires,
+	}
+)
+
+type condition struct {
+	preds []Predicate
+
+	// expires is when the rule stops applying, or zero.
+	expires time.Time
+}
+
+// Expires returns the time given by the expires predicate of the rule, from
+// which on the rule is ignored, or the zero time if it has none.
+func (r Rule) Expires() time.Time {
+	if r.cond == nil {
+		return time.Time{}
+	}
+	return r.cond.expires
+}
+
+// expired reports whether the rule has an expires predicate that has passed.
+func (r Rule) expired() bool {
+	t := r.Expires()
+	return !t.IsZero() && !now().Before(t)
+}
+
+// test reports whether f satisfies all predicates of r, and is a directory
+// if r only matches directories.
+func (r Rule) test(f *file) bool {
+	if !r.Expires().IsZero() {
+		// The decision changes with time, so it must not be cached.
+		f.used = true
+		if r.expired() {
+			return false
+		}
+	}
+	if (r.cond == nil || r.cond.preds == nil) && (!r.DirOnly || f.hinted()) {
+		return true
+	}
+	fi, err := f.info()
+	if err != nil {
+		return false
+	}
+	if r.DirOnly && !fi.IsDir() {
+		return false
+	}
+	if r.cond == nil {
+		return true
+	}
+	for _, p := range r.cond.preds {
+		if !p.Match(f.path, fi) {
+			return false
+		}
+	}
+	return true
+}
+
+// file is the subject of a match. Its info is only retrieved when needed.
+type file struct {
+	path string
+	fi   os.FileInfo
+	err  error
+
+	// dir is whether the path was given with a trailing separator,
+	// which says that it is a directory.
+	dir bool
+
+	// used is whether the info has been asked for.
+	used bool
+}
+
+// hinted reports whether f is a directory by its trailing separator, which
+// is only trusted if no info was given for it.
+func (f *file) hinted() bool {
+	if !f.dir || f.fi != nil {
+		return false
+	}
+	f.used = true
+	return true
+}
+
+func (f *file) info() (os.FileInfo, error) {
+	f.used = true
+	if f.fi == nil && f.err == nil {
+		f.fi, f.err = os.Lstat(f.path)
+	}
+	return f.fi, f.err
+}
+
+// parseRule parses a cleaned line of a rule file into a rule, which consists
+// of an optional "!" and optional predicates followed by a glob, which may
+// end in a slash. If there are predicates but no glob, the glob is "*".
+// The returned error is always a BadPatternError.
+func parseRule(s string) (Rule, error) {
+	var (
+		r       Rule
+		preds   []Predicate
+		conds   []string
+		expires time.Time
+	)
+
+	var column int
+	if strings.HasPrefix(s, "!") {
+		r.Negate = true
+		s = s[1:]
+		column++
+	}
+	for s != "" {
+		end := strings.IndexAny(s, " \t")
+		tok := s
+		if end >= 0 {
+			tok = s[:end]
+		}
+		i := strings.IndexByte(tok, ':')
+		if i < 0 {
+			break
+		}
+		parse, ok := lookupPredicate(tok[:i])
+		if !ok {
+			break
+		}
+		p, err := parse(tok[i+1:])
+		if err != nil {
+			return r, &BadPatternError{Err: ErrBadPredicate, Column: column, Line: -1}
+		}
+		if e, ok := p.(expiry); ok {
+			expires = time.Time(e)
+		} else {
+			preds = append(preds, p)
+		}
+		conds = append(conds, tok)
+
+		n := len(tok)
+		for n < len(s) && (s[n] == ' ' || s[n] == '\t') {
+			n++
+		}
+		column += utf8.RuneCountInString(s[:n])
+		s = s[n:]
+	}
+
+	if s == "" && len(conds) > 0 {
+		s = "*"
+	}
+	if err := r.setGlob(s, column); err != nil {
+		return r, err
+	}
+	if len(conds) > 0 {
+		r.Cond = strings.Join(conds, " ")
+		r.cond = &condition{preds: preds, expires: expires}
+	}
+	return r, nil
+}
+
+// setGlob checks the glob s, which starts at column of its line, and sets
+// it as the glob of r. A trailing slash makes r only match directories.
+// The returned error is always a BadPatternError.
+func (r *Rule) setGlob(s string, column int) error {
+	s, r.DirOnly = trimSlash(s)
+	if err := checkPattern(s); err != nil {
+		err.(*BadPatternError).Column += column
+		return err
+	}
+	r.Glob = s
+	return nil
+}
+
+// trimSlash removes trailing slashes from glob, and reports whether
+// there were any. An escaped slash is kept.
+func trimSlash(glob string) (string, bool) {
+	s := strings.TrimRight(glob, "/")
+	if s == glob || s == "" || strings.HasSuffix(s, "\\") {
+		return glob, false
+	}
+	return s, true
+}
+
+var errBadArg = errors.New("bad predicate argument")
+
+// splitOp splits a comparison operator from the front of arg.
+// If there is none, the operator is "=".
+func splitOp(arg string) (string, string) {
+	for _, op := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(arg, op) {
+			return op, arg[len(op):]
+		}
+	}
+	return "=", arg
+}
+
+func compare(op string, a, b int64) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	default:
+		return a == b
+	}
+}
+
+// parseSize parses arguments such as ">10M" and "<=512".
+func parseSize(arg string) (Predicate, error) {
+	op, num := splitOp(arg)
+	var unit int64 = 1
+	if n := len(num); n > 0 {
+		switch num[n-1] {
+		case 'k', 'K':
+			unit = 1 << 10
+		case 'm', 'M':
+			unit = 1 << 20
+		case 'g', 'G':
+			unit = 1 << 30
+		case 't', 'T':
+			unit = 1 << 40
+		}
+		if unit != 1 {
+			num = num[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return nil, errBadArg
+	}
+	n *= unit
+	return PredicateFunc(func(_ string, fi os.FileInfo) bool {
+		return compare(op, fi.Size(), n)
+	}), nil
+}
+
+var fileTypes = map[string]func(os.FileMode) bool{
+	"file":    os.FileMode.IsRegular,
+	"dir":     os.FileMode.IsDir,
+	"symlink": func(m os.FileMode) bool { return m&os.ModeSymlink != 0 },
+	"pipe":    func(m os.FileMode) bool { return m&os.ModeNamedPipe != 0 },
+	"socket":  func(m os.FileMode) bool { return m&os.ModeSocket != 0 },
+	"device":  func(m os.FileMode) bool { return m&os.ModeDevice != 0 },
+}
+
+// parseType parses arguments such as "dir" and "symlink".
+func parseType(arg string) (Predicate, error) {
+	is, ok := fileTypes[arg]
+	if !ok {
+		return nil, errBadArg
+	}
+	return PredicateFunc(func(_ string, fi os.FileInfo) bool {
+		return is(fi.Mode())
+	}), nil
+}
+
+var ageUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// parseMtime parses arguments such as ">30d" and "<2h", which are compared
+// with the time that has passed since the file was last modified.
+func parseMtime(arg string) (Predicate, error) {
+	op, num := splitOp(arg)
+	n := len(num)
+	if n == 0 {
+		return nil, errBadArg
+	}
+	unit, ok := ageUnits[num[n-1]]
+	if !ok {
+		return nil, errBadArg
+	}
+	v, err := strconv.ParseInt(num[:n-1], 10, 64)
+	if err != nil || v < 0 {
+		return nil, errBadArg
+	}
+	age := time.Duration(v) * unit
+	return PredicateFunc(func(_ string, fi os.FileInfo) bool {
+		return compare(op, int64(now().Sub(fi.ModTime())), int64(age))
+	}), nil
+}
+
+// expiry is the predicate of "expires:", which parseRule takes out of
+// the predicates of a rule, so that files are not stat'ed for it.
+type expiry time.Time
+
+func (e expiry) Match(string, os.FileInfo) bool {
+	return now().Before(time.Time(e))
+}
+
+// parseExpires parses a date such as "2025-01-01", which is midnight in the
+// local time zone, or a time in RFC 3339 format.
+func parseExpires(arg string) (Predicate, error) {
+	t, err := time.ParseInLocation("2006-01-02", arg, time.Local)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, arg)
+	}
+	if err != nil {
+		return nil, errBadArg
+	}
+	return expiry(t), nil
+}
+
+// sniffLen is how much of a file is examined to tell whether it is binary.
+// This is the same amount that git examines.
+const sniffLen = 8000
+
+// parseContent parses the arguments "binary" and "text". Only regular files
+// are either; a file is binary if it contains a NUL byte near the start.
+func parseContent(arg string) (Predicate, error) {
+	var binary bool
+	switch arg {
+	case "binary":
+		binary = true
+	case "text":
+	default:
+		return nil, errBadArg
+	}
+	return PredicateFunc(func(path string, fi os.FileInfo) bool {
+		if !fi.Mode().IsRegular() {
+			return false
+		}
+		b, err := isBinary(path)
+		return err == nil && b == binary
+	}), nil
+}
+
+func isBinary(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return bytes.IndexByte(buf[:n], 0) >= 0, nil
+}
+
+// parseMime parses a pattern for MIME types, such as "video/*" or
+// "text/html", as defined by path.Match.
+func parseMime(arg string) (Predicate, error) {
+	if _, err := path.Match(arg, ""); err != nil || arg == "" {
+		return nil, errBadArg
+	}
+	return PredicateFunc(func(name string, fi os.FileInfo) bool {
+		t := mimeType(name, fi)
+		if t == "" {
+			return false
+		}
+		m, _ := path.Match(arg, t)
+		return m
+	}), nil
+}
+
+// mimeType returns the MIME type of a file without any parameters.
+// It is determined by the extension, and if that is unknown, by the content
+// of the file if it is regular.
+func mimeType(name string, fi os.FileInfo) string {
+	t := mime.TypeByExtension(filepath.Ext(name))
+	if t == "" && fi.Mode().IsRegular() {
+		f, err := os.Open(name)
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+
+		buf := make([]byte, 512)
+		n, _ := io.ReadFull(f, buf)
+		t = http.DetectContentType(buf[:n])
+	}
+	if i := strings.IndexByte(t, ';'); i >= 0 {
+		t = t[:i]
+	}
+	return strings.TrimSpace(t)
+}
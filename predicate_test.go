@@ -0,0 +1,338 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRule(fw *testing.T) {
+	type result struct {
+		Glob string
+		Cond string
+		Err  error
+		Col  int
+	}
+	tests := map[string]result{
+		"foo":                 {"foo", "", nil, 0},
+		"foo:bar":             {"foo:bar", "", nil, 0},
+		"type\\:dir":          {"type\\:dir", "", nil, 0},
+		"type:dir":            {"*", "type:dir", nil, 0},
+		"type:dir build":      {"build", "type:dir", nil, 0},
+		"size:>10M  *.iso":    {"*.iso", "size:>10M", nil, 0},
+		"size:<1k\ttype:file": {"*", "size:<1k type:file", nil, 0},
+		"type:dir a b":        {"a b", "type:dir", nil, 0},
+		"type:dirt":           {"", "", ErrBadPredicate, 0},
+		"size:>=x":            {"", "", ErrBadPredicate, 0},
+		"type:dir size:-1":    {"", "", ErrBadPredicate, 9},
+		"type:dir [":          {"", "", ErrIncompleteClass, 9},
+		"mtime:>30d *.log":    {"*.log", "mtime:>30d", nil, 0},
+		"mtime:30":            {"", "", ErrBadPredicate, 0},
+		"mtime:>3y":           {"", "", ErrBadPredicate, 0},
+		"content:binary a/*":  {"a/*", "content:binary", nil, 0},
+		"content:data":        {"", "", ErrBadPredicate, 0},
+		"mime:video/*":        {"*", "mime:video/*", nil, 0},
+		"mime:[":              {"", "", ErrBadPredicate, 0},
+	}
+
+	for k, v := range tests {
+		r, err := parseRule(k)
+		if err != nil {
+			pe := err.(*BadPatternError)
+			if pe.Err != v.Err || pe.Column != v.Col {
+				fw.Errorf("parseRule(%q) failed with %s at column %d, expected %v at %d", k, pe.Err, pe.Column, v.Err, v.Col)
+			}
+			continue
+		}
+		if v.Err != nil {
+			fw.Errorf("parseRule(%q) succeeded, expected %s", k, v.Err)
+			continue
+		}
+		if r.Glob != v.Glob || r.Cond != v.Cond {
+			fw.Errorf("parseRule(%q) = (%q, %q), expected (%q, %q)", k, r.Cond, r.Glob, v.Cond, v.Glob)
+		}
+	}
+}
+
+func TestParseNegationAndDirOnly(fw *testing.T) {
+	type result struct {
+		Glob    string
+		Negate  bool
+		DirOnly bool
+		Err     error
+	}
+	tests := map[string]result{
+		"!foo":          {"foo", true, false, nil},
+		"\\!foo":        {"\\!foo", false, false, nil},
+		"foo!":          {"foo!", false, false, nil},
+		"build/":        {"build", false, true, nil},
+		"build//":       {"build", false, true, nil},
+		"!a/b/":         {"a/b", true, true, nil},
+		"a\\/":          {"a\\/", false, false, nil},
+		"!type:dir *.d": {"*.d", true, false, nil},
+		"type:dir !*.d": {"!*.d", false, false, nil},
+		"!":             {"", false, false, ErrEmptyGlob},
+		"/":             {"/", false, false, nil},
+		"!/":            {"/", true, false, nil},
+		"a/**/b/":       {"a/**/b", false, true, nil},
+		"!**/":          {"**", true, true, nil},
+	}
+
+	for k, v := range tests {
+		r, err := parseRule(k)
+		if err != nil {
+			if err.(*BadPatternError).Err != v.Err {
+				fw.Errorf("parseRule(%q) failed with %s, expected %v", k, err, v.Err)
+			}
+			continue
+		}
+		if v.Err != nil {
+			fw.Errorf("parseRule(%q) succeeded, expected %s", k, v.Err)
+			continue
+		}
+		if r.Glob != v.Glob || r.Negate != v.Negate || r.DirOnly != v.DirOnly {
+			fw.Errorf("parseRule(%q) = (%q, %v, %v), expected (%q, %v, %v)",
+				k, r.Glob, r.Negate, r.DirOnly, v.Glob, v.Negate, v.DirOnly)
+		}
+		if l := r.line(); l != k && k != "build//" {
+			fw.Errorf("parseRule(%q).line() = %q, expected the input", k, l)
+		}
+	}
+}
+
+func TestPredicates(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]int{
+		"small.iso": 10,
+		"large.iso": 4 << 10,
+		"large.txt": 4 << 10,
+	}
+	for k, v := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, k), make([]byte, v), 0644); err != nil {
+			fw.Fatal(err)
+		}
+	}
+	os.Mkdir(filepath.Join(dir, "assets"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "assets", "logo"), []byte("PNG\x00\x01"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "assets", "README"), []byte("hello\n"), 0644)
+	os.Mkdir(filepath.Join(dir, "build"), 0755)
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	os.Symlink("large.txt", filepath.Join(dir, "link"))
+
+	conf := filepath.Join(dir, "match.conf")
+	ioutil.WriteFile(conf, []byte("size:>2K *.iso\ntype:dir build\ntype:symlink\ncontent:binary assets/*\n"), 0644)
+	w, err := New("match.conf").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"small.iso":  false,
+		"large.iso":  true,
+		"large.txt":  false,
+		"build":      true,
+		"src":        false,
+		"link":       true,
+		"match.conf": false,
+		"missing":    false,
+		"assets":     false,
+
+		"assets/logo":   true,
+		"assets/README": false,
+	}
+	for k, v := range tests {
+		if m := w.Matches(k); m != v {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, m, v)
+		}
+	}
+
+	// MatchesInfo uses the given info instead of the file system.
+	fi, _ := os.Lstat(filepath.Join(dir, "large.iso"))
+	if !w.MatchesInfo("missing.iso", fi) {
+		fw.Errorf("w.MatchesInfo(%q, large.iso) = false, expected true", "missing.iso")
+	}
+}
+
+type fakeInfo struct {
+	os.FileInfo
+	mtime time.Time
+}
+
+func (fi fakeInfo) ModTime() time.Time { return fi.mtime }
+
+func TestMtime(fw *testing.T) {
+	t0 := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return t0 }
+	defer func() { now = time.Now }()
+
+	tests := []struct {
+		Arg   string
+		Age   time.Duration
+		Match bool
+	}{
+		{">30d", 31 * 24 * time.Hour, true},
+		{">30d", 29 * 24 * time.Hour, false},
+		{"<2h", time.Hour, true},
+		{"<2h", 3 * time.Hour, false},
+		{"<=1w", 7 * 24 * time.Hour, true},
+		{">=90s", time.Minute, false},
+	}
+	for _, t := range tests {
+		p, err := parseMtime(t.Arg)
+		if err != nil {
+			fw.Errorf("parseMtime(%q) failed: %s", t.Arg, err)
+			continue
+		}
+		if m := p.Match("", fakeInfo{mtime: t0.Add(-t.Age)}); m != t.Match {
+			fw.Errorf("mtime:%s on file of age %s = %v, expected %v", t.Arg, t.Age, m, t.Match)
+		}
+	}
+}
+
+func TestExpires(fw *testing.T) {
+	now = func() time.Time { return time.Date(2020, 6, 15, 0, 0, 0, 0, time.Local) }
+	defer func() { now = time.Now }()
+
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := filepath.Join(dir, "match.conf")
+	ioutil.WriteFile(conf, []byte("expires:2020-06-01 *.tmp\nexpires:2020-07-01 *.log\n"), 0644)
+	w, err := New("match.conf").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+
+	// The files do not exist, since expires does not need their info.
+	tests := map[string]bool{
+		"a.tmp": false,
+		"a.log": true,
+	}
+	for path, expected := range tests {
+		if m := w.Matches(path); m != expected {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", path, m, expected)
+		}
+	}
+	for _, d := range w.Explain("a.tmp") {
+		if d.Line == 1 && d.Skipped != "expired" {
+			fw.Errorf("decision for line 1 skipped %q, expected %q", d.Skipped, "expired")
+		}
+	}
+
+	// Decisions are not cached beyond the expiry.
+	w.EnableCache(time.Hour)
+	if !w.Matches("b.log") {
+		fw.Errorf("w.Matches(%q) = false before the expiry", "b.log")
+	}
+	now = func() time.Time { return time.Date(2020, 7, 2, 0, 0, 0, 0, time.Local) }
+	if w.Matches("b.log") {
+		fw.Errorf("w.Matches(%q) = true after the expiry", "b.log")
+	}
+
+	r, err := parseRule("expires:2020-06-01T12:00:00Z *.tmp")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if t := r.Expires(); !t.Equal(time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)) {
+		fw.Errorf("r.Expires() = %v, expected 2020-06-01 12:00 UTC", t)
+	}
+	if r.Cond != "expires:2020-06-01T12:00:00Z" {
+		fw.Errorf("r.Cond = %q, expected the expires predicate", r.Cond)
+	}
+	for _, s := range []string{"expires:soon *.tmp", "expires:2020-13-01 *.tmp"} {
+		if _, err := parseRule(s); err == nil {
+			fw.Errorf("parseRule(%q) succeeded, expected error", s)
+		}
+	}
+}
+
+func TestMime(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"photo.jpg":  "",
+		"page.html":  "<html></html>",
+		"notes":      "just some text\n",
+		"image.data": "\x89PNG\r\n\x1a\n",
+	}
+	for k, v := range files {
+		ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0644)
+	}
+
+	tests := []struct {
+		Arg   string
+		File  string
+		Match bool
+	}{
+		{"image/*", "photo.jpg", true},
+		{"image/*", "page.html", false},
+		{"text/html", "page.html", true},
+		{"text/plain", "notes", true},
+		{"image/png", "image.data", true},
+		{"*/*", "missing", false},
+	}
+	for _, t := range tests {
+		p, err := parseMime(t.Arg)
+		if err != nil {
+			fw.Fatalf("parseMime(%q) failed: %s", t.Arg, err)
+		}
+		name := filepath.Join(dir, t.File)
+		fi, err := os.Lstat(name)
+		if err != nil {
+			fi, _ = os.Lstat(dir)
+		}
+		if m := p.Match(name, fi); m != t.Match {
+			fw.Errorf("mime:%s on %s = %v, expected %v", t.Arg, t.File, m, t.Match)
+		}
+	}
+}
+
+func TestRegisterPredicate(fw *testing.T) {
+	RegisterPredicate("label", func(arg string) (Predicate, error) {
+		return PredicateFunc(func(path string, _ os.FileInfo) bool {
+			return filepath.Ext(path) == "."+arg
+		}), nil
+	})
+	defer func() {
+		predicatesMu.Lock()
+		delete(predicates, "label")
+		predicatesMu.Unlock()
+	}()
+
+	r, err := parseRule("label:secret *")
+	if err != nil {
+		fw.Fatalf("parseRule with registered predicate failed: %s", err)
+	}
+	if r.Cond != "label:secret" || !r.test(&file{path: "x.secret", fi: fakeInfo{}}) {
+		fw.Errorf("registered predicate not applied: %+v", r)
+	}
+
+	for _, name := range []string{"", "a:b", "a b", "label", "size"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					fw.Errorf("RegisterPredicate(%q) did not panic", name)
+				}
+			}()
+			RegisterPredicate(name, parseType)
+		}()
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"sort"
+	"time"
+)
+
+// PatternProfile records how much work a rule caused, as returned by
+// Worker.Profile.
+type PatternProfile struct {
+	Rule Rule
+
+	// Evaluations is how often the rule was evaluated against a path.
+	Evaluations int
+
+	// Matches is how many of these evaluations matched.
+	Matches int
+
+	// Time is the total time spent evaluating the rule, including
+	// its predicates.
+	Time time.Duration
+}
+
+// SetProfiling turns the profiler of the Worker on or off. While it is on,
+// the time spent evaluating each rule in Matches is recorded, which can be
+// retrieved with Profile. Turning it off discards what was recorded.
+//
+// Profiling is off by default, since timing every evaluation makes
+// matching noticeably slower.
+func (w *Worker) SetProfiling(on bool) {
+	switch {
+	case !on:
+		w.profile = nil
+	case w.profile == nil:
+		w.profile = make(map[Rule]*PatternProfile)
+	}
+}
+
+// Profile returns what the profiler has recorded for each rule that has been
+// evaluated since profiling was turned on, with the most expensive rules
+// first. Rules of layers added with AddMatcher are not included.
+func (w *Worker) Profile() []PatternProfile {
+	ps := make([]PatternProfile, 0, len(w.profile))
+	for _, p := range w.profile {
+		ps = append(ps, *p)
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].Time != ps[j].Time {
+			return ps[i].Time > ps[j].Time
+		}
+		return ps[i].Evaluations > ps[j].Evaluations
+	})
+	return ps
+}
+
+// matchFirst is like the function matchFirst, but skips rules that are
+// turned off, records the evaluations if profiling is on, and emits an
+// event for each if there is a sink.
+func (w *Worker) matchFirst(rules []Rule, f *file) (Rule, bool) {
+	toggled := w.toggled()
+	if w.profile == nil && w.events == nil && !toggled {
+		return matchFirst(rules, f, w.m)
+	}
+	return precedence(rules, func(r Rule) bool {
+		if toggled && !w.enabled(r) {
+			return false
+		}
+		w.emit(EventTried, f.path, r)
+		if w.profile == nil {
+			return w.m.matchRule(r, f.path) && r.test(f)
+		}
+		start := time.Now()
+		ok := w.m.matchRule(r, f.path) && r.test(f)
+		d := time.Since(start)
+
+		p := w.profile[r]
+		if p == nil {
+			p = &PatternProfile{Rule: r}
+			w.profile[r] = p
+		}
+		p.Evaluations++
+		p.Time += d
+		if ok {
+			p.Matches++
+		}
+		return ok
+	})
+}
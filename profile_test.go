@@ -0,0 +1,51 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import "testing"
+
+func TestProfile(fw *testing.T) {
+	w, err := New("").NewWorker(".")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.o", "*.a")
+	w.Matches("x.o")
+	if p := w.Profile(); len(p) != 0 {
+		fw.Errorf("w.Profile() without profiling = %v, expected none", p)
+	}
+
+	w.SetProfiling(true)
+	for _, p := range []string{"x.o", "x.a", "x.go"} {
+		w.Matches(p)
+	}
+	expected := map[string][2]int{
+		"*.o": {2, 1},
+		"*.a": {3, 1},
+	}
+	profile := w.Profile()
+	if len(profile) != len(expected) {
+		fw.Fatalf("w.Profile() = %+v, expected %d entries", profile, len(expected))
+	}
+	for _, p := range profile {
+		e := expected[p.Rule.Glob]
+		if p.Evaluations != e[0] || p.Matches != e[1] {
+			fw.Errorf("profile of %q = %d evaluations, %d matches; expected %d, %d",
+				p.Rule.Glob, p.Evaluations, p.Matches, e[0], e[1])
+		}
+	}
+
+	c := w.Clone()
+	c.Matches("y.o")
+	if w.Profile()[0].Evaluations+w.Profile()[1].Evaluations != 5 {
+		fw.Errorf("matching with a clone changed the profile of the original")
+	}
+
+	w.SetProfiling(false)
+	w.SetProfiling(true)
+	if p := w.Profile(); len(p) != 0 {
+		fw.Errorf("w.Profile() after restarting = %v, expected none", p)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FetchTimeout is the time limit for requests of a Fetcher without a Client,
+// including reading the response.
+const FetchTimeout = 30 * time.Second
+
+var defaultClient = &http.Client{Timeout: FetchTimeout}
+
+var ErrNotModified = errors.New("remote responded not modified, but nothing is cached")
+
+// Fetcher loads rule files from http and https URLs. Responses are cached
+// in memory and, if CacheDir is set, on disk, so that many tools can share
+// a central rule file without each of them hitting the network every time.
+//
+// A cached copy is used without contacting the server while it is fresh
+// according to the max-age directive of the Cache-Control header.
+// Otherwise the request is revalidated with the ETag of the cached copy.
+// If the server cannot be reached or responds with an error, the cached copy
+// is used regardless of its age, and failing that, the Fallback file.
+//
+// A Fetcher is safe to use concurrently. Requests to the server are made
+// without locking, so a slow URL does not hold up the others.
+type Fetcher struct {
+	// Client is used to make requests. If nil, a client that gives up
+	// after FetchTimeout is used.
+	Client *http.Client
+
+	// CacheDir is the directory where fetched rule files are stored
+	// between runs. If empty, responses are only cached in memory.
+	CacheDir string
+
+	// Fallback is a local rule file that is read when a URL cannot be fetched
+	// and no cached copy exists. It is limited in size like a response.
+	Fallback string
+
+	mu    sync.Mutex
+	cache map[string]*fetched
+}
+
+type fetched struct {
+	ETag    string    `json:"etag"`
+	Expires time.Time `json:"expires"`
+	Body    []byte    `json:"-"`
+}
+
+// NewFetcher returns a Fetcher that stores its cache in dir.
+func NewFetcher(dir string) *Fetcher {
+	return &Fetcher{CacheDir: dir}
+}
+
+// Fetch returns the content of the rule file at url. A response larger than
+// DefaultMaxFileSize fails with ErrFileTooLarge, wrapped in an *os.PathError.
+func (f *Fetcher) Fetch(url string) ([]byte, error) {
+	body, _, err := f.fetch(url, DefaultMaxFileSize)
+	return body, err
+}
+
+// fetch is like Fetch, but also reports whether the content was
+// served from the cache without a full response from the server.
+// Responses larger than max bytes fail if max is positive.
+func (f *Fetcher) fetch(url string, max int64) ([]byte, bool, error) {
+	f.mu.Lock()
+	c := f.lookup(url)
+	f.mu.Unlock()
+	if c != nil && time.Now().Before(c.Expires) {
+		return c.Body, true, nil
+	}
+
+	n, revalidated, err := f.get(url, c, max)
+	if err != nil {
+		if c != nil {
+			return c.Body, true, nil
+		}
+		if f.Fallback != "" {
+			body, err := f.readFallback(max)
+			return body, false, err
+		}
+		return nil, false, err
+	}
+	f.mu.Lock()
+	f.store(url, n)
+	f.mu.Unlock()
+	return n.Body, revalidated, nil
+}
+
+// readFallback reads the Fallback file, failing if it is larger than max
+// bytes and max is positive.
+func (f *Fetcher) readFallback(max int64) ([]byte, error) {
+	file, err := os.Open(f.Fallback)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if max > 0 {
+		r = &limitReader{r: r, n: max, name: f.Fallback}
+	}
+	return ioutil.ReadAll(r)
+}
+
+// get requests url, revalidating the cached copy c if it is not nil.
+// It reports whether the server responded that c is still valid.
+func (f *Fetcher) get(url string, c *fetched, max int64) (*fetched, bool, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if c != nil && c.ETag != "" {
+		req.Header.Set("If-None-Match", c.ETag)
+	}
+
+	client := f.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	n := &fetched{
+		ETag:    resp.Header.Get("ETag"),
+		Expires: time.Now().Add(maxAge(resp.Header.Get("Cache-Control"))),
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		if c == nil {
+			return nil, false, ErrNotModified
+		}
+		if n.ETag == "" {
+			n.ETag = c.ETag
+		}
+		n.Body = c.Body
+		return n, true, nil
+	case resp.StatusCode/100 == 2:
+		var body io.Reader = resp.Body
+		if max > 0 {
+			body = &limitReader{r: body, n: max, name: url}
+		}
+		n.Body, err = ioutil.ReadAll(body)
+		if err != nil {
+			return nil, false, err
+		}
+		return n, false, nil
+	default:
+		return nil, false, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+}
+
+// maxAge returns the max-age directive of a Cache-Control header value,
+// or zero if there is none or caching is forbidden.
+func maxAge(cc string) time.Duration {
+	var age time.Duration
+	for _, d := range strings.Split(cc, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch {
+		case d == "no-cache" || d == "no-store":
+			return 0
+		case strings.HasPrefix(d, "max-age="):
+			s, err := strconv.Atoi(strings.TrimPrefix(d, "max-age="))
+			if err == nil && s > 0 {
+				age = time.Duration(s) * time.Second
+			}
+		}
+	}
+	return age
+}
+
+func (f *Fetcher) lookup(url string) *fetched {
+	if c, ok := f.cache[url]; ok {
+		return c
+	}
+	if f.CacheDir == "" {
+		return nil
+	}
+
+	name := f.cacheName(url)
+	meta, err := ioutil.ReadFile(name + ".json")
+	if err != nil {
+		return nil
+	}
+	var c fetched
+	if json.Unmarshal(meta, &c) != nil {
+		return nil
+	}
+	c.Body, err = ioutil.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+	return &c
+}
+
+// store caches c in memory and on disk. Failing to write the disk
+// cache is not an error, since the content was fetched successfully.
+func (f *Fetcher) store(url string, c *fetched) {
+	if f.cache == nil {
+		f.cache = make(map[string]*fetched)
+	}
+	f.cache[url] = c
+	if f.CacheDir == "" {
+		return
+	}
+
+	meta, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(f.CacheDir, 0755) != nil {
+		return
+	}
+	name := f.cacheName(url)
+	if ioutil.WriteFile(name, c.Body, 0644) == nil {
+		ioutil.WriteFile(name+".json", meta, 0644)
+	}
+}
+
+func (f *Fetcher) cacheName(url string) string {
+	sum := sha1.Sum([]byte(url))
+	return filepath.Join(f.CacheDir, hex.EncodeToString(sum[:]))
+}
+
+// AddURL loads the rule file at url with f, and adds its globs to the
+// local matcher as AddFile does. Globs containing a path separator are
+// relative to the working directory of the Worker. Responses larger than
+// Matcher.MaxFileSize fail with ErrFileTooLarge.
+//
+// The URL is not one of the configuration files of the Worker: ConfigFiles,
+// Changed, and Reload ignore it, and Reload keeps its rules as they are.
+// To pick up changes to the remote file, add it to a new Worker.
+func (w *Worker) AddURL(f *Fetcher, url string) error {
+	body, cached, err := f.fetch(url, w.m.maxFileSize())
+	if err != nil {
+		return err
+	}
+	if cached {
+		w.count(MetricCacheHits)
+	}
+
+	err = w.addReader(bytes.NewReader(body), url, w.cwd, ScopeSession)
+	if err != nil {
+		return err
+	}
+	w.count(MetricConfigsLoaded)
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFetcher(fw *testing.T) {
+	var requests, modified int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests++
+		rw.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+		modified++
+		rw.Write([]byte("# central policy\n*.bak\nbuild/*\n"))
+	}))
+
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := NewFetcher(dir)
+	for i := 0; i < 2; i++ {
+		w, err := New("").NewWorker(dir)
+		if err != nil {
+			fw.Fatal(err)
+		}
+		if err = w.AddURL(f, srv.URL); err != nil {
+			fw.Fatalf("w.AddURL(%q) failed: %s", srv.URL, err)
+		}
+		if !w.Matches("foo.bak") || !w.Matches("build/foo") || w.Matches("foo") {
+			fw.Errorf("unexpected globs loaded from %s: %q", srv.URL, w.local)
+		}
+	}
+	if requests != 2 || modified != 1 {
+		fw.Errorf("got %d requests with %d full responses, expected 2 with 1", requests, modified)
+	}
+
+	// A new Fetcher on the same directory falls back to the disk cache
+	// when the server is gone.
+	srv.Close()
+	body, err := NewFetcher(dir).Fetch(srv.URL)
+	if err != nil || len(body) == 0 {
+		fw.Errorf("Fetch from disk cache = (%q, %v)", body, err)
+	}
+
+	// Without a cache, the fallback file is used.
+	fallback := filepath.Join(dir, "fallback")
+	ioutil.WriteFile(fallback, []byte("*.tmp\n"), 0644)
+	f = &Fetcher{Fallback: fallback}
+	body, err = f.Fetch(srv.URL)
+	if err != nil || string(body) != "*.tmp\n" {
+		fw.Errorf("Fetch with fallback = (%q, %v)", body, err)
+	}
+}
+
+func TestFetcherConcurrent(fw *testing.T) {
+	entered, release := make(chan bool), make(chan bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			entered <- true
+			<-release
+		}
+		rw.Header().Set("Cache-Control", "max-age=3600")
+		rw.Write([]byte("*.bak\n"))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	f := new(Fetcher)
+	if _, err := f.Fetch(srv.URL + "/fast"); err != nil {
+		fw.Fatal(err)
+	}
+	go f.Fetch(srv.URL + "/slow")
+	<-entered
+
+	done := make(chan error)
+	go func() {
+		_, err := f.Fetch(srv.URL + "/fast")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			fw.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		fw.Error("a cached URL waited for the request of another one")
+	}
+}
+
+func TestFetcherMaxFileSize(fw *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("*.bak\nbuild/*\n"))
+	}))
+	defer srv.Close()
+
+	m := New("")
+	m.MaxFileSize = 8
+	w := m.newWorker(os.TempDir())
+	err := w.AddURL(new(Fetcher), srv.URL)
+	if pe, ok := err.(*os.PathError); !ok || pe.Err != ErrFileTooLarge {
+		fw.Errorf("w.AddURL() of a large response = %v, expected %v", err, ErrFileTooLarge)
+	}
+	m.MaxFileSize = 0
+	if err := w.AddURL(new(Fetcher), srv.URL); err != nil {
+		fw.Errorf("w.AddURL() = %v", err)
+	}
+
+	// The fallback file is limited as well.
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fallback := filepath.Join(dir, "fallback")
+	ioutil.WriteFile(fallback, []byte("*.bak\nbuild/*\n"), 0644)
+	srv.Close()
+	f := &Fetcher{Fallback: fallback}
+	_, _, err = f.fetch(srv.URL, 8)
+	if pe, ok := err.(*os.PathError); !ok || pe.Err != ErrFileTooLarge {
+		fw.Errorf("f.fetch() of a large fallback file = %v, expected %v", err, ErrFileTooLarge)
+	}
+	if _, _, err := f.fetch(srv.URL, 0); err != nil {
+		fw.Errorf("f.fetch() of a fallback file = %v", err)
+	}
+}
+
+func TestMaxAge(fw *testing.T) {
+	tests := map[string]int{
+		"":                          0,
+		"max-age=60":                60,
+		"public, max-age=3600":      3600,
+		"no-cache, max-age=60":      0,
+		"max-age=60, no-store":      0,
+		"max-age=-1":                0,
+		"private, MAX-AGE=10, xyz=": 10,
+	}
+	for k, v := range tests {
+		if d := maxAge(k); int(d.Seconds()) != v {
+			fw.Errorf("maxAge(%q) = %v, expected %ds", k, d, v)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/goulash/matcher/glob"
+)
+
+// Rule is a glob together with its provenance, i.e. where it came from.
+type Rule struct {
+	// Glob is the glob as it is matched, without a leading "!" or trailing
+	// slash. Globs from files that contain a path separator are joined to
+	// the directory of the file.
+	Glob string
+
+	// Source is the file or URL the glob was read from.
+	// It is empty for globs added with Add.
+	Source string
+
+	// Line is the line in Source the glob was read from, or zero.
+	Line int
+
+	// Scope is the configuration scope the rule belongs to.
+	Scope Scope
+
+	// Cond contains the predicates that precede the glob in the rule file,
+	// as they were written, such as "size:>10M type:file". It is empty if
+	// the rule has no predicates.
+	Cond string
+
+	// Negate is whether the glob was written with a leading "!", so that
+	// the rule excludes the files it matches from being matched.
+	Negate bool
+
+	// DirOnly is whether the glob was written with a trailing slash,
+	// so that the rule only matches directories.
+	DirOnly bool
+
+	// Regexp is whether Glob is a regular expression, as in .hgignore files,
+	// rather than a glob. The directory of its file is built into it, and
+	// it is matched against the whole path.
+	Regexp bool
+
+	cond *condition
+}
+
+// String returns the glob of the rule as it would be written in a rule file,
+// with a leading "!" if it is negated and a trailing slash if it only matches
+// directories.
+func (r Rule) String() string {
+	s := r.Glob
+	if r.Negate {
+		s = "!" + s
+	}
+	if r.DirOnly {
+		s += "/"
+	}
+	return s
+}
+
+// line returns the rule as a line of a rule file, with its predicates.
+func (r Rule) line() string {
+	if r.Cond == "" {
+		return r.String()
+	}
+	n := r
+	n.Negate = false
+	s := r.Cond + " " + n.String()
+	if r.Negate {
+		s = "!" + s
+	}
+	return s
+}
+
+// describe returns the glob of the rule quoted, followed by its
+// provenance if it has any.
+func (r Rule) describe() string {
+	s := strconv.Quote(r.String())
+	if r.Cond != "" {
+		s = r.Cond + " " + s
+	}
+	if r.Source == "" {
+		return s
+	}
+	return fmt.Sprintf("%s (%s:%d)", s, r.Source, r.Line)
+}
+
+// Rules returns the global and local rules of the Worker, in order of the
+// precedence of the files they come from. The rules of one file are in the
+// order they were written, but later ones take precedence, as in gitignore.
+// Rules of layers added with AddMatcher are not included.
+func (w *Worker) Rules() []Rule {
+	rules := make([]Rule, 0, len(w.global)+len(w.local))
+	rules = append(rules, w.global...)
+	return append(rules, w.local...)
+}
+
+// EffectiveRules returns the rules that apply in dir, in the order of
+// Worker.Rules, as a Worker created for dir would see them. Globs
+// containing a path separator are anchored, i.e. joined to the directory
+// of their file, and every rule records its provenance, so the result can
+// be stored in the manifest of a backup as a record of the rules in force.
+//
+// Errors reading configuration files are handled as by NewWorker. If they
+// are collected, the rules that could be read are returned with ConfigErrors.
+func (m *Matcher) EffectiveRules(dir string) ([]Rule, error) {
+	w, err := m.NewWorker(dir)
+	if w == nil {
+		return nil, err
+	}
+	return w.Rules(), err
+}
+
+// Clone returns a copy of the Worker. Globs later added to the copy
+// do not affect the original, and vice versa. Layers added with AddMatcher
+// are shared. The provenance, hit counts, and profile of all rules are
+// preserved, and so are the scopes and files that are turned off.
+//
+// Cloning is cheap, since neither files are read nor patterns compiled
+// again: the rules are shared until either Worker changes them, so a pool
+// of Workers for concurrent use can be made from a single one. Clone may
+// be called from many goroutines at once on a Worker that is not modified
+// meanwhile.
+func (w *Worker) Clone() *Worker {
+	c := *w
+	if w.shared != nil {
+		atomic.StoreUint32(w.shared, 1)
+	} else {
+		c.local = append([]Rule(nil), w.local...)
+	}
+	c.configs = append([]config(nil), w.configs...)
+	c.above = append([]Layer(nil), w.above...)
+	c.below = append([]Layer(nil), w.below...)
+	if w.disabledFiles != nil {
+		c.disabledFiles = make(map[string]bool, len(w.disabledFiles))
+		for f := range w.disabledFiles {
+			c.disabledFiles[f] = true
+		}
+	}
+	c.hits = w.hits.clone()
+	if w.cache != nil {
+		c.EnableCache(w.cache.staleness)
+	}
+	if w.profile != nil {
+		c.profile = make(map[Rule]*PatternProfile, len(w.profile))
+		for r, p := range w.profile {
+			q := *p
+			c.profile[r] = &q
+		}
+	}
+	return &c
+}
+
+// own makes sure that the Worker has its own copy of its rules before they
+// are changed in place, if they may be shared with clones.
+func (w *Worker) own() {
+	if w.shared != nil && atomic.LoadUint32(w.shared) == 0 {
+		return
+	}
+	w.local = append(make([]Rule, 0, len(w.local)+1), w.local...)
+	w.shared = new(uint32)
+}
+
+// Rebase returns a copy of the Worker whose rules apply to a tree mirroring
+// the one at oldRoot, such as a build output directory that has the same
+// layout as the sources. Globs anchored beneath oldRoot are moved beneath
+// newRoot, and so are the working directory and the root of the Worker if
+// they are beneath oldRoot. Other rules, including regular expressions, are
+// kept as they are. Relative roots
+// are resolved against the working directory of the Worker.
+//
+// The provenance of the rules is preserved, but the copy starts without hit
+// counts or profiling data. Layers added with AddMatcher are shared, as by
+// Clone.
+func (w *Worker) Rebase(oldRoot, newRoot string) *Worker {
+	oldRoot, newRoot = w.abs(oldRoot), w.abs(newRoot)
+	c := w.Clone()
+	c.hits = new(hitCounters)
+	if c.profile != nil {
+		c.profile = make(map[Rule]*PatternProfile)
+	}
+	c.own()
+	c.cwd, _ = rebase(c.cwd, oldRoot, newRoot)
+	if c.root != "" {
+		c.root, _ = rebase(c.root, oldRoot, newRoot)
+	}
+	oldGlob, newGlob := glob.QuoteMeta(oldRoot), glob.QuoteMeta(newRoot)
+	for i, r := range c.local {
+		if !r.Regexp && strings.Contains(r.Glob, "/") {
+			c.local[i].Glob, _ = rebase(r.Glob, oldGlob, newGlob)
+		}
+	}
+	return c
+}
+
+// rebase moves path from beneath oldRoot to beneath newRoot, and reports
+// whether it was beneath oldRoot.
+func rebase(path, oldRoot, newRoot string) (string, bool) {
+	rel, err := filepath.Rel(oldRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path, false
+	}
+	return filepath.Join(newRoot, rel), true
+}
+
+// hit records that r decided whether path is matched. Benchmarks set
+// the counters to nil to measure matching without counting.
+func (w *Worker) hit(r Rule, path string) {
+	if w.hits != nil {
+		w.hits.add(r, path)
+	}
+}
+
+// Hits returns how often r decided a match over the lifetime of the Worker.
+// Only the rule that decides whether a path is matched is counted. Counting
+// is safe for concurrent use and adds little overhead to matching.
+func (w *Worker) Hits(r Rule) int {
+	return w.hits.get(r)
+}
+
+// UnusedPatterns returns the rules of the Worker that have never decided
+// a match, in the order returned by Rules. Calling Matches for every file
+// in a tree before calling UnusedPatterns reveals which rules have no effect
+// on that tree; this includes rules that are shadowed by earlier rules.
+func (w *Worker) UnusedPatterns() []Rule {
+	var unused []Rule
+	for _, r := range w.Rules() {
+		if w.hits.get(r) == 0 {
+			unused = append(unused, r)
+		}
+	}
+	return unused
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRulesProvenance(fw *testing.T) {
+	m := New("match.conf")
+	m.Add("match.conf")
+	w, err := m.NewWorker(filepath.Join("tests", "dead", "good"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+
+	c := w.Clone()
+	c.Add("extra")
+	if n, k := len(w.Rules()), len(c.Rules()); k != n+1 {
+		fw.Errorf("clone has %d rules, expected %d", k, n+1)
+	}
+
+	var found bool
+	for _, r := range c.Rules() {
+		if r.Glob != "[2-5]" {
+			continue
+		}
+		found = true
+		if filepath.Base(r.Source) != "match.conf" || r.Line != 2 {
+			fw.Errorf("rule %q has provenance %s:%d, expected match.conf:2", r, r.Source, r.Line)
+		}
+	}
+	if !found {
+		fw.Errorf("rule %q missing from clone: %v", "[2-5]", c.Rules())
+	}
+}
+
+func TestCloneShared(fw *testing.T) {
+	w := testWorker(fw)
+	w.Add("*.o", "*.a")
+	rules := w.Rules()
+
+	// Changes in place to either Worker must not show in the other.
+	c := w.Clone()
+	p, _ := w.Push("*.tmp")
+	p.Close()
+	w.Push("*.log")
+	if !reflect.DeepEqual(c.Rules(), rules) {
+		fw.Errorf("c.Rules() = %v after changing the original, expected %v", c.Rules(), rules)
+	}
+	c.Add("*.so")
+	if n := len(w.Rules()); n != len(rules)+1 {
+		fw.Errorf("w has %d rules after changing the clone, expected %d", n, len(rules)+1)
+	}
+	if !c.Matches("a.so") || w.Matches("a.so") || c.Matches("a.log") || !w.Matches("a.log") {
+		fw.Error("clone and original share rules added afterwards")
+	}
+
+	// Clones made concurrently are independent as well.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := w.Clone()
+			c.Add("*.x")
+			if !c.Matches("a.x") || !c.Matches("a.o") {
+				fw.Error("concurrent clone does not match")
+			}
+		}()
+	}
+	wg.Wait()
+	if w.Matches("a.x") {
+		fw.Error("original matches a glob added to a clone")
+	}
+}
+
+func BenchmarkClone(b *testing.B) {
+	w, err := New("").NewWorker(".")
+	if err != nil {
+		b.Fatal(err)
+	}
+	w.AddString(strings.Join(benchRules, "\n"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Clone()
+	}
+}
+
+func TestUnusedPatterns(fw *testing.T) {
+	w, err := New("").NewWorker("tests")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.o", "*.a", "test*")
+	for _, p := range []string{"main.o", "lib.o", "main.go", "README"} {
+		w.Matches(p)
+	}
+
+	unused := w.UnusedPatterns()
+	if len(unused) != 2 || unused[0].Glob != "*.a" || unused[1].Glob != "test*" {
+		fw.Errorf("w.UnusedPatterns() = %v, expected [*.a test*]", unused)
+	}
+	if n := w.Hits(Rule{Glob: "*.o"}); n != 2 {
+		fw.Errorf("w.Hits(*.o) = %d, expected 2", n)
+	}
+	if n := len(w.Clone().UnusedPatterns()); n != 2 {
+		fw.Errorf("clone has %d unused patterns, expected 2", n)
+	}
+}
+
+func TestEffectiveRules(fw *testing.T) {
+	m := New("match.conf")
+	m.Add("match.conf")
+	rules, err := m.EffectiveRules(filepath.Join("tests", "dead"))
+	if err != nil {
+		fw.Fatal(err)
+	}
+
+	abs, _ := filepath.Abs("tests")
+	expected := []Rule{
+		{Glob: "match.conf", Scope: ScopeSession},
+		{Glob: "*foo*", Source: filepath.Join(abs, "dead", "match.conf"), Line: 2, Scope: ScopeProject},
+		{Glob: "*bar*", Source: filepath.Join(abs, "dead", "match.conf"), Line: 3, Scope: ScopeProject},
+		{Glob: filepath.Join(abs, "foo"), Source: filepath.Join(abs, "match.conf"), Line: 4, Scope: ScopeProject},
+	}
+	if len(rules) < len(expected) {
+		fw.Fatalf("m.EffectiveRules() = %v, expected at least %v", rules, expected)
+	}
+	for i, r := range expected {
+		if rules[i] != r {
+			fw.Errorf("m.EffectiveRules()[%d] = %s, expected %s", i, rules[i].describe(), r.describe())
+		}
+	}
+}
+
+func TestRebase(fw *testing.T) {
+	w, err := New("").NewWorker("src")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.o")
+	r := strings.NewReader("gen/*.go\n../vendor/x\n")
+	if err := w.addReader(r, "rules", w.cwd, ScopeSession); err != nil {
+		fw.Fatal(err)
+	}
+	w.Matches("a.o")
+
+	c := w.Rebase(".", "../out")
+	if g := w.Rules()[0]; w.Hits(g) != 1 || c.Hits(g) != 0 {
+		fw.Errorf("rebased worker has %d hits for %q, expected 0", c.Hits(g), g)
+	}
+	tests := map[string][2]bool{
+		"a.o":             {true, true},
+		"gen/x.go":        {true, true},
+		"../out/a.o":      {true, true},
+		"../out/gen/x.go": {false, true},
+		"../src/gen/x.go": {true, false},
+		"../vendor/x":     {true, true},
+	}
+	for k, v := range tests {
+		if m := w.Matches(k); m != v[0] {
+			fw.Errorf("w.Matches(%q) = %v, expected %v", k, m, v[0])
+		}
+		if m := c.Matches(k); m != v[1] {
+			fw.Errorf("rebased Matches(%q) = %v, expected %v", k, m, v[1])
+		}
+	}
+}
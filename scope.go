@@ -0,0 +1,170 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import "path/filepath"
+
+// Scope is one of the layers of configuration that make up a Worker.
+// The scopes are listed here in order of precedence, from highest to lowest,
+// which is the order in which a Worker consults their rules.
+type Scope int
+
+const (
+	// ScopeSession contains the globs of the Matcher, as well as everything
+	// added to a Worker at runtime with Add, AddFile, and the like.
+	ScopeSession Scope = iota
+
+	// ScopeProject contains the configuration files found by NewWorker
+	// in the directory of the Worker and all its parents, nearest first.
+	ScopeProject
+
+	// ScopeUser contains the rules of Matcher.UserFile, or the rule file
+	// of Matcher.App, followed by those of the configuration file in the
+	// home directory.
+	ScopeUser
+
+	// ScopeSystem contains the rules of Matcher.SystemFile.
+	ScopeSystem
+
+	numScopes = iota
+)
+
+var scopeNames = [numScopes]string{
+	ScopeSession: "session",
+	ScopeProject: "project",
+	ScopeUser:    "user",
+	ScopeSystem:  "system",
+}
+
+func (s Scope) String() string {
+	if s < 0 || s >= numScopes {
+		return "unknown"
+	}
+	return scopeNames[s]
+}
+
+// SetScope enables or disables the scope s for all Workers created
+// afterwards by the Matcher. All scopes are enabled by default.
+// Disabling a scope that is read from files skips reading them.
+//
+// Disabling ScopeSession only excludes the globs of the Matcher;
+// globs added to the Worker itself are always used.
+func (m *Matcher) SetScope(s Scope, enabled bool) {
+	if s >= 0 && s < numScopes {
+		m.disabled[s] = !enabled
+		m.resetPool()
+	}
+}
+
+// ScopeEnabled returns whether the scope s is enabled.
+func (m *Matcher) ScopeEnabled(s Scope) bool {
+	return s >= 0 && s < numScopes && !m.disabled[s]
+}
+
+// SetScopeEnabled turns the rules of the scope s on or off in the Worker,
+// without reading or removing them, so that tools can offer flags such as
+// --no-ignore-global cheaply. All scopes are enabled by default. Disabled
+// rules are still returned by Rules, but they never decide a match.
+//
+// As for Matcher.SetScope, disabling ScopeSession only turns off the globs
+// of the Matcher; globs added to the Worker itself remain in effect.
+func (w *Worker) SetScopeEnabled(s Scope, enabled bool) {
+	if s >= 0 && s < numScopes && w.disabled[s] == enabled {
+		w.disabled[s] = !enabled
+		w.invalidate()
+	}
+}
+
+// ScopeEnabled returns whether the scope s is enabled in the Worker.
+func (w *Worker) ScopeEnabled(s Scope) bool {
+	return s >= 0 && s < numScopes && !w.disabled[s]
+}
+
+// SetFileEnabled turns the rules of the rule file path on or off in the
+// Worker, like SetScopeEnabled does for a scope. A relative path is relative
+// to the working directory of the Worker. Names passed to AddReader are
+// used as they are.
+func (w *Worker) SetFileEnabled(path string, enabled bool) {
+	path = w.source(path)
+	if enabled == !w.disabledFiles[path] {
+		return
+	}
+	if enabled {
+		delete(w.disabledFiles, path)
+	} else {
+		if w.disabledFiles == nil {
+			w.disabledFiles = make(map[string]bool)
+		}
+		w.disabledFiles[path] = true
+	}
+	w.invalidate()
+}
+
+// FileEnabled returns whether the rules of the rule file path are enabled
+// in the Worker.
+func (w *Worker) FileEnabled(path string) bool {
+	return !w.disabledFiles[w.source(path)]
+}
+
+// source returns the Source of the rules read from path.
+func (w *Worker) source(path string) string {
+	for _, c := range w.configs {
+		if c.name == path || c.path == w.abs(path) {
+			return c.name
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	for _, r := range w.local {
+		if r.Source == path {
+			return path
+		}
+	}
+	return w.abs(path)
+}
+
+// toggled returns whether any scope or file is disabled in the Worker.
+func (w *Worker) toggled() bool {
+	return len(w.disabledFiles) > 0 || w.disabled != [numScopes]bool{}
+}
+
+// enabled returns whether the local rule r is in effect in the Worker.
+// The globs of the Matcher are turned off by skipping them altogether.
+func (w *Worker) enabled(r Rule) bool {
+	return !w.disabledFiles[r.Source] && (r.Scope == ScopeSession || !w.disabled[r.Scope])
+}
+
+// RulesIn returns the rules of the Worker in the scope s,
+// in the order of Rules.
+func (w *Worker) RulesIn(s Scope) []Rule {
+	var rules []Rule
+	for _, r := range w.Rules() {
+		if r.Scope == s {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// insert adds rules to the local rules of the Worker, keeping them
+// ordered by scope. Within a scope, rules keep the order they were added in.
+// Their globs are compiled right away, rather than when they are matched.
+func (w *Worker) insert(rules ...Rule) {
+	w.own()
+	for _, r := range rules {
+		i := len(w.local)
+		for i > 0 && w.local[i-1].Scope > r.Scope {
+			i--
+		}
+		w.local = append(w.local, Rule{})
+		copy(w.local[i+1:], w.local[i:])
+		w.local[i] = r
+		if !r.Regexp {
+			compiled(r.Glob)
+		}
+	}
+	w.invalidate()
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScopes(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"system":     "*.sys\n",
+		"user":       "*.usr\n",
+		"match.conf": "*.prj\n",
+	}
+	for k, v := range files {
+		ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0644)
+	}
+
+	m := New("match.conf")
+	m.SystemFile = filepath.Join(dir, "system")
+	m.UserFile = filepath.Join(dir, "user")
+	m.Add("*.glb")
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.ses")
+
+	var globs []string
+	for _, r := range w.Rules() {
+		globs = append(globs, r.Glob)
+	}
+	expected := []string{"*.glb", "*.ses", "*.prj", "*.usr", "*.sys"}
+	if !reflect.DeepEqual(globs, expected) {
+		fw.Errorf("w.Rules() = %q, expected %q", globs, expected)
+	}
+	if r := w.RulesIn(ScopeUser); len(r) != 1 || r[0].Glob != "*.usr" {
+		fw.Errorf("w.RulesIn(ScopeUser) = %v", r)
+	}
+
+	m.SetScope(ScopeUser, false)
+	m.SetScope(ScopeSession, false)
+	if m.ScopeEnabled(ScopeUser) || !m.ScopeEnabled(ScopeSystem) {
+		fw.Errorf("ScopeEnabled does not reflect SetScope")
+	}
+	w, err = m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	tests := map[string]bool{
+		"a.glb": false,
+		"a.prj": true,
+		"a.usr": false,
+		"a.sys": true,
+	}
+	for k, v := range tests {
+		if u := w.Matches(k); u != v {
+			fw.Errorf("w.Matches(%q) = %v with user and session scopes disabled, expected %v", k, u, v)
+		}
+	}
+}
+
+func TestWorkerToggles(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "user"), []byte("*.usr\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "match.conf"), []byte("*.prj\n!keep.usr\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "extra"), []byte("*.ext\n"), 0644)
+
+	m := New("match.conf")
+	m.UserFile = filepath.Join(dir, "user")
+	m.Add("*.glb")
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w.Add("*.ses")
+	w.AddFile(filepath.Join(dir, "extra"))
+	w.AddString("*.str\n")
+	w.EnableCache(time.Hour)
+
+	check := func(name string, expected map[string]bool) {
+		for k, v := range expected {
+			if r := w.Matches(k); r != v {
+				fw.Errorf("%s: w.Matches(%q) = %v, expected %v", name, k, r, v)
+			}
+		}
+	}
+	all := map[string]bool{"a.glb": true, "a.ses": true, "a.prj": true, "a.usr": true, "keep.usr": false, "a.ext": true, "a.str": true}
+	check("initially", all)
+
+	w.SetScopeEnabled(ScopeSession, false)
+	w.SetScopeEnabled(ScopeUser, false)
+	if w.ScopeEnabled(ScopeUser) || !w.ScopeEnabled(ScopeProject) {
+		fw.Errorf("ScopeEnabled does not reflect SetScopeEnabled")
+	}
+	check("without session and user", map[string]bool{"a.glb": false, "a.ses": true, "a.usr": false, "a.prj": true})
+
+	c := w.Clone()
+	w.SetScopeEnabled(ScopeSession, true)
+	w.SetScopeEnabled(ScopeUser, true)
+	if c.Matches("a.usr") || !w.Matches("a.usr") {
+		fw.Errorf("toggles are shared between clones")
+	}
+
+	w.SetFileEnabled("match.conf", false)
+	w.SetFileEnabled(filepath.Join(dir, "extra"), false)
+	w.SetFileEnabled("(string)", false)
+	if w.FileEnabled(filepath.Join(dir, "match.conf")) || !w.FileEnabled(m.UserFile) {
+		fw.Errorf("FileEnabled does not reflect SetFileEnabled")
+	}
+	check("without files", map[string]bool{"a.prj": false, "keep.usr": true, "a.ext": false, "a.str": false, "a.usr": true})
+
+	w.SetFileEnabled("match.conf", true)
+	w.SetFileEnabled("extra", true)
+	w.SetFileEnabled("(string)", true)
+	check("finally", all)
+	if n := len(w.Rules()); n != 7 {
+		fw.Errorf("len(w.Rules()) = %d, expected 7", n)
+	}
+}
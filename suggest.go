@@ -0,0 +1,213 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/goulash/matcher/glob"
+)
+
+// Suggestion is the result of Suggest.
+type Suggestion struct {
+	// Rules are the suggested rules, in the order they were chosen.
+	Rules []SuggestedRule
+
+	// Uncovered are the paths to exclude for which there is no rule that
+	// does not also exclude a path to keep, because the path to keep is the
+	// same or beneath it, or the path is not beneath the directory.
+	Uncovered []string
+}
+
+// SuggestedRule is a rule of a Suggestion.
+type SuggestedRule struct {
+	// Pattern is the line to add to the rule file.
+	Pattern string
+
+	// Covers are the paths to exclude that the rule was chosen for.
+	Covers []string
+
+	// Alternatives are other patterns that exclude the same paths without
+	// excluding any path to keep. If there are any, the examples do not
+	// determine the rule, and the user may have to choose.
+	Alternatives []string
+}
+
+// Suggest returns a small set of rules for a rule file that exclude all
+// paths in exclude, but none in keep, so that editors can offer to "add to
+// ignore file" for a selection of files. Paths are relative to the directory
+// of the rule file. Excluding a directory excludes everything beneath it,
+// as when walking directories, and a path is taken to be a directory if
+// another of the paths is beneath it.
+//
+// The rules are chosen greedily, each covering as many of the remaining
+// paths as possible. Among rules that cover the same paths, the most
+// specific one is chosen, so that a single path results in a rule for
+// exactly that path, and more general rules, such as one for an extension,
+// are only chosen if the examples call for them. The others are reported
+// as alternatives.
+func Suggest(exclude, keep []string) Suggestion {
+	var s Suggestion
+	dirs := make(map[string]bool)
+	clean := func(paths []string) []string {
+		var cleaned []string
+		for _, p := range paths {
+			p = path.Clean(filepath.ToSlash(p))
+			cleaned = append(cleaned, p)
+			for d := path.Dir(p); d != "." && d != "/"; d = path.Dir(d) {
+				dirs[d] = true
+			}
+		}
+		return cleaned
+	}
+	exclude, keep = clean(exclude), clean(keep)
+
+	var remaining []string
+	for _, p := range exclude {
+		if p == "." || p == ".." || strings.HasPrefix(p, "../") || path.IsAbs(p) || covers(candidates(p, dirs[p])[0], keep, dirs) {
+			s.Uncovered = append(s.Uncovered, p)
+			continue
+		}
+		remaining = append(remaining, p)
+	}
+
+	var cands []*candidate
+	seen := make(map[string]bool)
+	for _, p := range remaining {
+		for _, c := range candidates(p, dirs[p]) {
+			if !seen[c.pattern()] && !covers(c, keep, dirs) {
+				seen[c.pattern()] = true
+				cands = append(cands, c)
+			}
+		}
+	}
+
+	for len(remaining) > 0 {
+		var best *candidate
+		var bestCovered []string
+		for _, c := range cands {
+			covered := coveredBy(c, remaining, dirs)
+			if len(covered) > len(bestCovered) || len(covered) == len(bestCovered) && best != nil && c.rank < best.rank {
+				best, bestCovered = c, covered
+			}
+		}
+		r := SuggestedRule{Pattern: best.pattern(), Covers: bestCovered}
+		for _, c := range cands {
+			if c != best && len(coveredBy(c, bestCovered, dirs)) == len(bestCovered) {
+				r.Alternatives = append(r.Alternatives, c.pattern())
+			}
+		}
+		s.Rules = append(s.Rules, r)
+
+		var rest []string
+		for _, p := range remaining {
+			if !covers(best, []string{p}, dirs) {
+				rest = append(rest, p)
+			}
+		}
+		remaining = rest
+	}
+	return s
+}
+
+// candidate is a possible rule of a suggestion.
+type candidate struct {
+	// elems are the elements of the pattern, which is matched against
+	// a prefix of the elements of a path if the candidate is anchored,
+	// and otherwise against any element.
+	elems    []string
+	anchored bool
+	dirOnly  bool
+
+	// rank orders candidates that cover the same paths, from the most
+	// specific to the most general.
+	rank int
+}
+
+// The ranks of candidates.
+const (
+	rankPath = iota
+	rankName
+	rankAnchoredDir
+	rankExt
+	rankDir
+)
+
+func (c *candidate) pattern() string {
+	s := strings.Join(c.elems, "/")
+	if c.anchored {
+		s = "/" + s
+	}
+	if c.dirOnly {
+		s += "/"
+	}
+	return s
+}
+
+// candidates returns the candidates for excluding the path p.
+func candidates(p string, isDir bool) []*candidate {
+	elems := strings.Split(p, "/")
+	quoted := make([]string, len(elems))
+	for i, e := range elems {
+		quoted[i] = glob.QuoteMeta(e)
+	}
+	last := len(elems) - 1
+	cands := []*candidate{
+		{elems: quoted, anchored: true, dirOnly: isDir, rank: rankPath},
+		{elems: quoted[last:], dirOnly: isDir, rank: rankName},
+	}
+	if ext := path.Ext(elems[last]); !isDir && ext != "" && ext != elems[last] {
+		cands = append(cands, &candidate{elems: []string{"*" + glob.QuoteMeta(ext)}, rank: rankExt})
+	}
+	for i := 0; i < last; i++ {
+		cands = append(cands,
+			&candidate{elems: quoted[:i+1], anchored: true, dirOnly: true, rank: rankAnchoredDir},
+			&candidate{elems: quoted[i : i+1], dirOnly: true, rank: rankDir})
+	}
+	return cands
+}
+
+// covers reports whether c excludes any of the paths, either itself or
+// by excluding a directory above it.
+func covers(c *candidate, paths []string, dirs map[string]bool) bool {
+	return len(coveredBy(c, paths, dirs)) > 0
+}
+
+// coveredBy returns the paths that c excludes.
+func coveredBy(c *candidate, paths []string, dirs map[string]bool) []string {
+	var covered []string
+	for _, p := range paths {
+		elems := strings.Split(p, "/")
+		for i := range elems {
+			isDir := i < len(elems)-1 || dirs[p]
+			if c.dirOnly && !isDir {
+				continue
+			}
+			if c.matches(elems, i) {
+				covered = append(covered, p)
+				break
+			}
+		}
+	}
+	return covered
+}
+
+// matches reports whether c matches the path formed by elems[:i+1].
+func (c *candidate) matches(elems []string, i int) bool {
+	if !c.anchored {
+		return match(c.elems[0], elems[i])
+	}
+	if len(c.elems) != i+1 {
+		return false
+	}
+	for j, e := range c.elems {
+		if !match(e, elems[j]) {
+			return false
+		}
+	}
+	return true
+}
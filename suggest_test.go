@@ -0,0 +1,93 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSuggest(fw *testing.T) {
+	type result struct {
+		Patterns  []string
+		Uncovered []string
+	}
+	tests := []struct {
+		Exclude, Keep []string
+		Result        result
+	}{
+		{[]string{"build/a.o"}, nil, result{[]string{"/build/a.o"}, nil}},
+		{[]string{"a.o", "src/b.o", "lib/c.o"}, nil, result{[]string{"*.o"}, nil}},
+		{[]string{"a.o", "src/b.o"}, []string{"vendor/x.o"}, result{[]string{"/a.o", "/src/b.o"}, nil}},
+		{[]string{"out/a", "out/b", "out/sub/c"}, []string{"src/a"}, result{[]string{"/out/"}, nil}},
+		{[]string{"x/node_modules/a", "y/node_modules/b"}, []string{"x/main.js"}, result{[]string{"node_modules/"}, nil}},
+		{[]string{"dir"}, []string{"dir/keep"}, result{nil, []string{"dir"}}},
+		{[]string{"../up", "ok.tmp"}, nil, result{[]string{"/ok.tmp"}, []string{"../up"}}},
+		{[]string{"a[1].txt"}, nil, result{[]string{"/a\\[1].txt"}, nil}},
+	}
+
+	for _, t := range tests {
+		s := Suggest(t.Exclude, t.Keep)
+		var patterns []string
+		for _, r := range s.Rules {
+			patterns = append(patterns, r.Pattern)
+		}
+		if !reflect.DeepEqual(patterns, t.Result.Patterns) || !reflect.DeepEqual(s.Uncovered, t.Result.Uncovered) {
+			fw.Errorf("Suggest(%q, %q) = (%q, %q), expected (%q, %q)",
+				t.Exclude, t.Keep, patterns, s.Uncovered, t.Result.Patterns, t.Result.Uncovered)
+		}
+	}
+}
+
+func TestSuggestAlternatives(fw *testing.T) {
+	s := Suggest([]string{"build/a.o"}, []string{"b.o"})
+	if len(s.Rules) != 1 {
+		fw.Fatalf("Suggest() = %+v, expected one rule", s)
+	}
+	alts := strings.Join(s.Rules[0].Alternatives, " ")
+	if alts != "a.o /build/ build/" {
+		fw.Errorf("alternatives = %q, expected all but *.o", alts)
+	}
+}
+
+// TestSuggestApplies checks that the suggested rules have the intended
+// effect when they are written to a rule file.
+func TestSuggestApplies(fw *testing.T) {
+	exclude := []string{"out/a", "out/sub/b", "x.tmp", "src/y.tmp", "src/gen/"}
+	keep := []string{"src/main.go", "out.txt", "src/gen.go"}
+	s := Suggest(exclude, keep)
+
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"out/sub", "src"} {
+		os.MkdirAll(filepath.Join(dir, d), 0755)
+	}
+	var lines []string
+	for _, r := range s.Rules {
+		lines = append(lines, r.Pattern)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "rules"), []byte(strings.Join(lines, "\n")), 0644)
+	w, err := New("rules").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	for _, p := range keep {
+		if w.Matches(p) {
+			fw.Errorf("rules %q match %q, which is to be kept", lines, p)
+		}
+	}
+	for _, p := range []string{"out", "x.tmp", "src/y.tmp"} {
+		if !w.Matches(p) {
+			fw.Errorf("rules %q do not match %q, which is to be excluded", lines, p)
+		}
+	}
+}
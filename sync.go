@@ -0,0 +1,138 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// SyncWorker is a Worker that is safe for concurrent use, as returned by
+// Synchronized. Changes to the rules wait for the matches in progress, and
+// matches wait for changes in progress. Many matches can be decided at
+// once, unless the Worker has a cache or profiling enabled, in which case
+// they are decided one after the other as well.
+//
+// It is meant for applications that cannot give every goroutine its own
+// Worker, such as with Clone, and is slower than that.
+type SyncWorker struct {
+	mu sync.RWMutex
+	w  *Worker
+}
+
+// Synchronized returns a SyncWorker for w, which must not be used directly
+// afterwards.
+func Synchronized(w *Worker) *SyncWorker {
+	return &SyncWorker{w: w}
+}
+
+// rlock locks s for deciding a match, and returns the function to unlock it.
+func (s *SyncWorker) rlock() func() {
+	s.mu.RLock()
+	if s.w.cache == nil && s.w.profile == nil {
+		return s.mu.RUnlock
+	}
+	s.mu.RUnlock()
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// Matches is Worker.Matches.
+func (s *SyncWorker) Matches(path string) bool {
+	defer s.rlock()()
+	return s.w.Matches(path)
+}
+
+// MatchesInfo is Worker.MatchesInfo.
+func (s *SyncWorker) MatchesInfo(path string, fi os.FileInfo) bool {
+	defer s.rlock()()
+	return s.w.MatchesInfo(path, fi)
+}
+
+// Match is Worker.Match.
+func (s *SyncWorker) Match(path string) (MatchResult, bool) {
+	defer s.rlock()()
+	return s.w.Match(path)
+}
+
+// Add is Worker.Add.
+func (s *SyncWorker) Add(glob ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Add(glob...)
+}
+
+// AddFile is Worker.AddFile.
+func (s *SyncWorker) AddFile(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.AddFile(path)
+}
+
+// AddReader is Worker.AddReader. The Worker is locked while r is read.
+func (s *SyncWorker) AddReader(r io.Reader, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.AddReader(r, name)
+}
+
+// Reset is Worker.Reset.
+func (s *SyncWorker) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Reset()
+}
+
+// Do calls fn with the Worker while no other method of s runs, so that
+// any method of the Worker can be used. The Worker must not be retained
+// after fn returns.
+func (s *SyncWorker) Do(fn func(w *Worker)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.w)
+}
+
+// Snapshot returns a clone of the Worker, which is not affected by later
+// changes to s and can be used without locking.
+func (s *SyncWorker) Snapshot() *Worker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.w.Clone()
+}
+
+// Watch checks the configuration files of the Worker for changes every
+// interval, and reloads them with Worker.Reload when any has changed, until
+// ctx is done, when it returns the error of ctx. Errors reloading the files
+// are logged, and the rules read before are kept until the files can be read
+// again; in ModeCollect, files with invalid lines are reloaded without them.
+// Watch is meant to run in its own goroutine:
+//
+//	go s.Watch(ctx, 2*time.Second)
+func (s *SyncWorker) Watch(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		s.mu.RLock()
+		changed := s.w.Changed()
+		s.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		s.mu.Lock()
+		if err := s.w.Reload(); err != nil {
+			s.w.logf("error reloading configuration: %s", err)
+		}
+		s.mu.Unlock()
+	}
+}
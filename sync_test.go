@@ -0,0 +1,94 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSynchronized(fw *testing.T) {
+	for _, cached := range []bool{false, true} {
+		w := testWorker(fw)
+		if cached {
+			w.EnableCache(time.Minute)
+		}
+		s := Synchronized(w)
+		snap := s.Snapshot()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					s.Add(fmt.Sprintf("*.%d-%d", i, j))
+				}
+				s.AddReader(strings.NewReader("*.tmp\n"), "extra")
+			}(i)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					s.Matches(fmt.Sprintf("a.%d-%d", i, j))
+					s.Match("a.tmp")
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if !s.Matches("a.3-49") || !s.Matches("a.tmp") {
+			fw.Errorf("cached = %v: globs added concurrently are missing", cached)
+		}
+		if snap.Matches("a.tmp") {
+			fw.Errorf("cached = %v: snapshot changed with the SyncWorker", cached)
+		}
+		s.Reset()
+		s.Do(func(w *Worker) {
+			if n := len(w.Rules()); n != 1 {
+				fw.Errorf("cached = %v: %d rules after Reset, expected 1", cached, n)
+			}
+		})
+	}
+}
+
+func TestWatch(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rules := filepath.Join(dir, "rules")
+	ioutil.WriteFile(rules, []byte("*.a\n"), 0644)
+
+	w, err := New("rules").NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	s := Synchronized(w)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Watch(ctx, time.Millisecond) }()
+
+	ioutil.WriteFile(rules, []byte("*.bb\n"), 0644)
+	deadline := time.Now().Add(5 * time.Second)
+	for !s.Matches("x.bb") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !s.Matches("x.bb") || s.Matches("x.a") {
+		fw.Error("s.Watch() did not reload the changed file")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		fw.Errorf("s.Watch() = %v, expected %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DebugEnv is the environment variable that turns on tracing in all
+// Matchers created by New. If it is set to 1, true, or stderr, the trace is
+// written to standard error; any other non-empty value except 0 and false
+// is taken as the name of a file that the trace is appended to.
+const DebugEnv = "MATCHER_DEBUG"
+
+var (
+	debugOnce sync.Once
+	debugOut  io.Writer
+)
+
+// debugWriter returns the writer selected by DebugEnv, or nil.
+// The environment is only consulted once.
+func debugWriter() io.Writer {
+	debugOnce.Do(func() {
+		debugOut = openDebug(os.Getenv(DebugEnv))
+	})
+	return debugOut
+}
+
+func openDebug(v string) io.Writer {
+	switch strings.ToLower(v) {
+	case "", "0", "false":
+		return nil
+	case "1", "true", "stderr":
+		return os.Stderr
+	}
+	f, err := os.OpenFile(v, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "matcher: cannot open debug file: %s\n", err)
+		return os.Stderr
+	}
+	return f
+}
+
+// Logger is the minimal logging interface used by the matcher.
+// It is satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// SetTrace makes the Worker write a line to out for every decision made by
+// Matches, naming the rule responsible for a match. Passing nil turns
+// tracing off again.
+//
+// This is meant for debugging surprising matches in production, and can be
+// turned on and off at any time.
+func (w *Worker) SetTrace(out io.Writer) {
+	w.tracer = out
+}
+
+func (w *Worker) tracef(format string, v ...interface{}) {
+	if w.tracer != nil {
+		fmt.Fprintf(w.tracer, format+"\n", v...)
+	}
+}
+
+func (w *Worker) logf(format string, v ...interface{}) {
+	if w.logger != nil {
+		w.logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrace(fw *testing.T) {
+	var logs, trace bytes.Buffer
+	m := New("match.conf")
+	m.Logger = log.New(&logs, "", 0)
+	w, err := m.NewWorker("tests/dead/good")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if n := strings.Count(logs.String(), "loaded "); n != 3 {
+		fw.Errorf("logged %d loaded configs, expected 3:\n%s", n, logs.String())
+	}
+
+	w.Matches("1")
+	if trace.Len() != 0 {
+		fw.Errorf("trace written before SetTrace")
+	}
+
+	w.SetTrace(&trace)
+	w.Matches("1")
+	w.Matches("2")
+	w.SetTrace(nil)
+	w.Matches("3")
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	if len(lines) != 2 {
+		fw.Fatalf("trace has %d lines, expected 2:\n%s", len(lines), trace.String())
+	}
+	if !strings.HasSuffix(lines[0], ": no match") {
+		fw.Errorf("unexpected trace for 1: %s", lines[0])
+	}
+	conf := filepath.Join("tests", "dead", "good", "match.conf")
+	if !strings.Contains(lines[1], `matched by "[2-5]"`) || !strings.Contains(lines[1], conf+":2") {
+		fw.Errorf("unexpected trace for 2: %s", lines[1])
+	}
+}
+
+func TestOpenDebug(fw *testing.T) {
+	for _, v := range []string{"", "0", "false", "FALSE"} {
+		if out := openDebug(v); out != nil {
+			fw.Errorf("openDebug(%q) = %v, expected nil", v, out)
+		}
+	}
+	for _, v := range []string{"1", "true", "stderr"} {
+		if out := openDebug(v); out != os.Stderr {
+			fw.Errorf("openDebug(%q) = %v, expected os.Stderr", v, out)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "trace.log")
+	out := openDebug(name)
+	f, ok := out.(*os.File)
+	if !ok || f.Name() != name {
+		fw.Fatalf("openDebug(%q) = %v, expected file", name, out)
+	}
+	f.Close()
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/goulash/matcher/glob"
+)
+
+// UserRuleFile returns the path of the per-user rule file of app,
+// which is "ignore" in the configuration directory of app. Following the
+// XDG base directory specification, this is $XDG_CONFIG_HOME/app/ignore,
+// falling back to ~/.config/app/ignore if XDG_CONFIG_HOME is not set.
+// On other systems, the conventions of os.UserConfigDir apply.
+func UserRuleFile(app string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, app, "ignore"), nil
+}
+
+// AddGlobalFile reads the rule file path once, like the excludes file that
+// git reads from core.excludesFile, and adds its rules to the user scope of
+// all Workers created by the Matcher afterwards, after those of UserFile and
+// the home directory. Unlike in other rule files, globs containing a path
+// separator are relative to the root of each Worker, or to its working
+// directory if it has none, rather than to the directory of the file, so
+// that a single file serves every project.
+//
+// The dialect of the file is chosen by its name as for other rule files,
+// and errors are returned as by Worker.AddFile. The file is not read again
+// by Reload.
+func (m *Matcher) AddGlobalFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	t := m.newWorker("/")
+	err = t.read(context.Background(), f, path, "/", ScopeUser, nil)
+	if _, ok := err.(PatternErrors); err != nil && !ok {
+		return err
+	}
+	defer m.resetPool()
+	m.files = append(m.files, t.local...)
+	return err
+}
+
+// globalRule returns the rule r of a file added with AddGlobalFile, which
+// was anchored at the root directory when it was read, anchored at the root
+// of the Worker instead.
+func (w *Worker) globalRule(r Rule) Rule {
+	base := strings.TrimSuffix(w.workspace(), "/")
+	switch {
+	case r.Regexp:
+		r.Glob = "^" + regexp.QuoteMeta(base+"/") + strings.TrimPrefix(r.Glob, "^/")
+	case strings.HasPrefix(r.Glob, "/"):
+		r.Glob = glob.QuoteMeta(base) + r.Glob
+	}
+	return r
+}
+
+// userFile returns the rule file of the user scope, or "" if there is none.
+func (m *Matcher) userFile() string {
+	if m.UserFile != "" {
+		return m.UserFile
+	}
+	if m.App == "" {
+		return ""
+	}
+	path, err := UserRuleFile(m.App)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// homeFile returns the configuration file in the home directory,
+// e.g. ~/.dunignore, or "" if there is none. A dot is prepended to the
+// name of the first configuration file if it does not start with one.
+// Like the file of UserRuleFile, it is only read if App is set, so that
+// Matchers of libraries and tests do not pick up the rules of the user.
+func (m *Matcher) homeFile() string {
+	if m.App == "" || len(m.names) == 0 {
+		return ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	name := m.names[0]
+	if !strings.HasPrefix(name, ".") {
+		name = "." + name
+	}
+	return filepath.Join(home, name)
+}
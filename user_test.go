@@ -0,0 +1,184 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setenv sets the environment variable k to v, and returns a function
+// that restores its previous state.
+func setenv(k, v string) func() {
+	old, ok := os.LookupEnv(k)
+	os.Setenv(k, v)
+	return func() {
+		if ok {
+			os.Setenv(k, old)
+		} else {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+// noUserFiles points HOME and XDG_CONFIG_HOME to an empty directory, so
+// that the rule files of the user do not affect a test, and returns a
+// function that restores them and removes the directory.
+func noUserFiles(fw *testing.T) func() {
+	dir, err := ioutil.TempDir("", "home")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	home := setenv("HOME", dir)
+	xdg := setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	return func() {
+		xdg()
+		home()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestUserRuleFile(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))()
+	defer setenv("HOME", filepath.Join(dir, "home"))()
+
+	path, err := UserRuleFile("dun")
+	if expected := filepath.Join(dir, "xdg", "dun", "ignore"); err != nil || path != expected {
+		fw.Errorf("UserRuleFile(%q) = (%q, %v), expected %q", "dun", path, err, expected)
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	ioutil.WriteFile(path, []byte("*.bak\n"), 0644)
+
+	m := New("")
+	m.App = "dun"
+	w, err := m.NewWorker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	if !w.Matches("x.bak") {
+		fw.Errorf("user rule file of app not loaded: %v", w.Rules())
+	}
+
+	os.Unsetenv("XDG_CONFIG_HOME")
+	path, err = UserRuleFile("dun")
+	if expected := filepath.Join(dir, "home", ".config", "dun", "ignore"); err != nil || path != expected {
+		fw.Errorf("UserRuleFile(%q) without XDG_CONFIG_HOME = (%q, %v), expected %q", "dun", path, err, expected)
+	}
+}
+
+func TestHomeFile(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	home := filepath.Join(dir, "home")
+	defer setenv("HOME", home)()
+	defer setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))()
+	os.MkdirAll(filepath.Join(home, ".config", "dun"), 0755)
+	os.MkdirAll(filepath.Join(home, "project"), 0755)
+	os.MkdirAll(filepath.Join(dir, "elsewhere"), 0755)
+	ioutil.WriteFile(filepath.Join(home, ".config", "dun", "ignore"), []byte("*.xdg\n"), 0644)
+	ioutil.WriteFile(filepath.Join(home, ".dunignore"), []byte("*.home\n"), 0644)
+
+	m := New(".dunignore")
+	m.App = "dun"
+	for _, d := range []string{"project", "../elsewhere"} {
+		w, err := m.NewWorker(filepath.Join(home, d))
+		if err != nil {
+			fw.Fatal(err)
+		}
+		if !w.Matches("a.xdg") || !w.Matches("a.home") {
+			fw.Errorf("user files not loaded for %s: %v", d, w.Rules())
+		}
+		if n := len(w.Rules()); n != 2 {
+			fw.Errorf("worker in %s has %d rules, expected 2: %v", d, n, w.Rules())
+		}
+	}
+
+	w, _ := m.NewWorker(filepath.Join(dir, "elsewhere"))
+	rules := w.RulesIn(ScopeUser)
+	if len(rules) != 2 || rules[0].Glob != "*.xdg" || rules[1].Glob != "*.home" {
+		fw.Errorf("user scope = %v, expected [*.xdg *.home]", rules)
+	}
+
+	w, _ = New(".dunignore").NewWorker(filepath.Join(dir, "elsewhere"))
+	if w.Matches("a.home") {
+		fw.Errorf("home file read without App: %v", w.Rules())
+	}
+}
+
+func TestAddGlobalFile(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"home/excludes":       "*.swp\n/build/out\ndist/\n",
+		"home/.hgignore":      "^tmp/\n",
+		"one/.root":           "",
+		"one/match.conf":      "!keep.swp\n",
+		"two/.root":           "",
+		"two/sub/placeholder": "",
+	}
+	for k, v := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(k)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0644)
+	}
+
+	m := New("match.conf")
+	m.Sentinels = []string{".root"}
+	excludes := filepath.Join(dir, "home", "excludes")
+	if err := m.AddGlobalFile(excludes); err != nil {
+		fw.Fatal(err)
+	}
+	if err := m.AddGlobalFile(filepath.Join(dir, "home", ".hgignore")); err != nil {
+		fw.Fatal(err)
+	}
+	if err := m.AddGlobalFile(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		fw.Errorf("m.AddGlobalFile of a missing file = %v, expected not exist", err)
+	}
+
+	tests := map[string]map[string]bool{
+		"one": {
+			"a.swp": true, "keep.swp": false, "build/out": true, "x/build/out": false,
+			"dist/": true, "tmp/a": true, "x/tmp/a": false,
+		},
+		"two/sub": {
+			"a.swp": true, "keep.swp": true, "../build/out": true, "build/out": false,
+			"../tmp/a": true, "tmp/a": false,
+		},
+	}
+	for d, paths := range tests {
+		w, err := m.NewWorker(filepath.Join(dir, d))
+		if err != nil {
+			fw.Fatal(err)
+		}
+		for p, v := range paths {
+			if r := w.Matches(p); r != v {
+				fw.Errorf("in %s: w.Matches(%q) = %v, expected %v", d, p, r, v)
+			}
+		}
+		if rs := w.Decisions("a.swp"); len(rs) != 1 || rs[0].Source != excludes || rs[0].Layer != "user" {
+			fw.Errorf("in %s: w.Decisions(%q) = %+v, expected a rule of %s", d, "a.swp", rs, excludes)
+		}
+	}
+
+	m.SetScope(ScopeUser, false)
+	w, err := m.NewWorker(filepath.Join(dir, "one"))
+	if err != nil || w.Matches("a.swp") {
+		fw.Errorf("the global file applies with ScopeUser disabled")
+	}
+}
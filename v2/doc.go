@@ -0,0 +1,49 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package matcher is version 2 of github.com/goulash/matcher. It matches
+// paths against gitignore-like rules with the same syntax and semantics as
+// version 1, which it is built on, but with a smaller, more regular API:
+//
+//   - Patterns are values: Compile returns a Pattern that can be matched
+//     on its own, and results name the pattern that decided as written.
+//   - Every failure is returned as an error, usually an *Error that records
+//     where it happened; no function panics because of its input.
+//   - A Matcher is constructed once with options, and is immutable after
+//     that, so it is safe for concurrent use.
+//   - Rules come from layered stores, such as rule files and lists of globs,
+//     that are consulted in the order in which they are given.
+//
+// A Matcher is created with New, and a Worker for a directory with
+// Matcher.Worker:
+//
+//	m, err := matcher.New(
+//		matcher.WithConfig(".dunignore"),
+//		matcher.WithStore(matcher.Globs("defaults", "*.o", "*.tmp")),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	w, err := m.Worker(".")
+//	if err != nil {
+//		return err
+//	}
+//	res, err := w.Match("build/main.o")
+//
+// Migrating from version 1
+//
+// Version 1 remains maintained, and both versions can be used in the same
+// program, since they read the same rule files. The main differences are:
+//
+//	v1                              v2
+//	New(config) and Matcher fields  New(WithConfig(config), ...)
+//	Matcher.Add(globs...)           WithStore(Globs(name, globs...))
+//	Worker.AddFile(path)            WithStore(File(path))
+//	Worker.Matches(path) bool       Worker.Match(path) (Result, error)
+//	Worker.Match(path)              Result.Pattern, Result.Source, ...
+//	*BadPatternError                *Error, with errors.Is(err, ErrSyntax)
+//
+// Features of version 1 that are not yet part of version 2, such as
+// profiling and overlays, remain available through Worker.V1.
+package matcher
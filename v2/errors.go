@@ -0,0 +1,107 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"errors"
+	"fmt"
+
+	v1 "github.com/goulash/matcher"
+)
+
+var (
+	// ErrSyntax is matched by errors.Is for every *Error about a malformed
+	// pattern or rule file line.
+	ErrSyntax = errors.New("syntax error")
+
+	// ErrEmptyPath is returned for matching an empty path.
+	ErrEmptyPath = errors.New("empty path")
+
+	// ErrInternal is wrapped by errors for conditions that indicate a bug
+	// in this package, which version 1 would have panicked with.
+	ErrInternal = errors.New("internal error")
+)
+
+// Error describes a failure of an operation on patterns or rules.
+type Error struct {
+	// Op is the operation that failed, such as "compile", "load",
+	// or "match".
+	Op string
+
+	// Source, Line, and Column locate the problem, as far as they are
+	// known. Source is a file name or the name of a store. Lines are
+	// counted from 1 and columns from 0 in runes; they are 0 if unknown.
+	Source string
+	Line   int
+	Column int
+
+	// Err is the underlying error, such as ErrTrailingEscape.
+	Err error
+
+	syntax bool
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Source != "" && e.Line > 0:
+		return fmt.Sprintf("%s %s:%d:%d: %v", e.Op, e.Source, e.Line, e.Column, e.Err)
+	case e.Source != "":
+		return fmt.Sprintf("%s %s: %v", e.Op, e.Source, e.Err)
+	default:
+		return fmt.Sprintf("%s: %v", e.Op, e.Err)
+	}
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrSyntax and e is about a syntax error.
+func (e *Error) Is(target error) bool {
+	return target == ErrSyntax && e.syntax
+}
+
+// Errors about malformed patterns, which are wrapped by an *Error.
+var (
+	ErrUnexpectedRune     = v1.ErrUnexpectedRune
+	ErrNegativeRange      = v1.ErrNegativeRange
+	ErrDualStar           = v1.ErrDualStar
+	ErrEmptyClass         = v1.ErrEmptyClass
+	ErrEmptyGlob          = v1.ErrEmptyGlob
+	ErrIncompleteClass    = v1.ErrIncompleteClass
+	ErrTrailingEscape     = v1.ErrTrailingEscape
+	ErrTrailingWhitespace = v1.ErrTrailingWhitespace
+	ErrBadPredicate       = v1.ErrBadPredicate
+	ErrBadMacro           = v1.ErrBadMacro
+	ErrUndefinedMacro     = v1.ErrUndefinedMacro
+	ErrMacroCycle         = v1.ErrMacroCycle
+	ErrBadVariable        = v1.ErrBadVariable
+	ErrUndefinedVariable  = v1.ErrUndefinedVariable
+)
+
+// wrap converts an error of version 1 into an *Error for op.
+func wrap(op, source string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pe *v1.BadPatternError
+	if errors.As(err, &pe) {
+		e := &Error{Op: op, Source: source, Column: pe.Column, Err: pe.Err, syntax: true}
+		if pe.Line > 0 {
+			e.Source, e.Line = pe.File, pe.Line
+		}
+		return e
+	}
+	return &Error{Op: op, Source: source, Err: err}
+}
+
+// recoverError turns a panic into an *Error for op wrapping ErrInternal,
+// and stores it in *err.
+func recoverError(op string, err *error) {
+	if r := recover(); r != nil {
+		*err = &Error{Op: op, Err: fmt.Errorf("%w: %v", ErrInternal, r)}
+	}
+}
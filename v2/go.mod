@@ -0,0 +1,9 @@
+module github.com/goulash/matcher/v2
+
+go 1.16
+
+require github.com/goulash/matcher v1.1.0
+
+// Version 2 is built on the API that version 1 has from v1.1.0 on. While
+// the two are developed side by side, the v1 module next to it is used.
+replace github.com/goulash/matcher => ../
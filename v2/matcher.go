@@ -0,0 +1,143 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	v1 "github.com/goulash/matcher"
+)
+
+// Pattern is a compiled glob pattern. It matches a path as a rule
+// consisting of the pattern alone would.
+type Pattern struct {
+	p *v1.Pattern
+}
+
+// Compile checks pattern and compiles it. A malformed pattern is reported
+// as an *Error that matches ErrSyntax.
+func Compile(pattern string) (Pattern, error) {
+	p, err := v1.CompilePattern(pattern)
+	if err != nil {
+		return Pattern{}, wrap("compile", pattern, err)
+	}
+	return Pattern{p}, nil
+}
+
+// String returns the source of the pattern, or "" for the zero Pattern.
+func (p Pattern) String() string {
+	if p.p == nil {
+		return ""
+	}
+	return p.p.String()
+}
+
+// Match reports whether path is matched by p. The zero Pattern matches
+// nothing.
+func (p Pattern) Match(path string) (ok bool, err error) {
+	if p.p == nil {
+		return false, nil
+	}
+	if path == "" {
+		return false, &Error{Op: "match", Source: p.String(), Err: ErrEmptyPath}
+	}
+	defer recoverError("match", &err)
+	return p.p.Match(path), nil
+}
+
+// Matcher holds the configuration shared by Workers. It is immutable,
+// and safe for concurrent use.
+type Matcher struct {
+	c config
+}
+
+// New returns a Matcher configured by opts. The first option that fails
+// is returned as the error.
+func New(opts ...Option) (*Matcher, error) {
+	m := &Matcher{}
+	for _, o := range opts {
+		if err := o(&m.c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Worker returns a Worker for the directory dir, which reads the stores
+// and configuration files of the Matcher. Any error reading them is
+// returned, usually as an *Error.
+func (m *Matcher) Worker(dir string) (w *Worker, err error) {
+	defer recoverError("load", &err)
+
+	vm := v1.New(m.c.name)
+	vm.Policy = v1.Abort
+	vm.Sentinels = m.c.sentinels
+	vm.Root = m.c.root
+	vm.CaseFold = m.c.fold
+	vm.Mode = m.c.mode
+	vm.Dialect = m.c.dialect
+	vw, err := vm.NewWorker(dir)
+	if err != nil {
+		return nil, wrap("load", dir, err)
+	}
+
+	for _, s := range m.c.stores {
+		r, err := s.Open()
+		if err != nil {
+			return nil, wrap("load", s.Name(), err)
+		}
+		err = vw.AddReader(r, s.Name())
+		r.Close()
+		if err != nil {
+			return nil, wrap("load", s.Name(), err)
+		}
+	}
+	return &Worker{w: vw, stores: len(m.c.stores)}, nil
+}
+
+// Worker matches paths relative to a directory. Its methods may be called
+// concurrently.
+type Worker struct {
+	w      *v1.Worker
+	stores int
+}
+
+// Result describes what decided whether a path is matched.
+type Result struct {
+	// Matched is whether the path is matched.
+	Matched bool
+
+	// Pattern is the pattern of the rule that decided, as written in the
+	// rule file, with "!" and predicates. It is empty if no rule decided.
+	Pattern string
+
+	// Negated is whether the rule that decided is negated.
+	Negated bool
+
+	// Source and Line are the store or file and the line of the rule.
+	Source string
+	Line   int
+}
+
+// Match decides whether path is matched. A relative path is relative to
+// the directory of the Worker.
+func (w *Worker) Match(path string) (res Result, err error) {
+	if path == "" {
+		return Result{}, &Error{Op: "match", Err: ErrEmptyPath}
+	}
+	defer recoverError("match", &err)
+	r, ok := w.w.Match(path)
+	return Result{
+		Matched: ok,
+		Pattern: r.Pattern,
+		Negated: r.Rule.Negate,
+		Source:  r.Source,
+		Line:    r.Line,
+	}, nil
+}
+
+// V1 returns a copy of the version 1 Worker that w is built on, for features
+// that version 2 does not provide yet.
+func (w *Worker) V1() *v1.Worker {
+	return w.w.Clone()
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompile(fw *testing.T) {
+	p, err := Compile("*.o")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"a.o":   true,
+		"b/a.o": true,
+		"a.c":   false,
+	} {
+		ok, err := p.Match(path)
+		if err != nil || ok != want {
+			fw.Errorf("Match(%q) = %v, %v; want %v", path, ok, err, want)
+		}
+	}
+	if _, err := p.Match(""); !errors.Is(err, ErrEmptyPath) {
+		fw.Errorf("Match(\"\") = %v; want ErrEmptyPath", err)
+	}
+
+	_, err = Compile("a\\")
+	if !errors.Is(err, ErrSyntax) || !errors.Is(err, ErrTrailingEscape) {
+		fw.Errorf("Compile(\"a\\\\\") = %v; want ErrSyntax and ErrTrailingEscape", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "compile" {
+		fw.Errorf("Compile(\"a\\\\\") = %#v; want *Error with Op compile", err)
+	}
+}
+
+func TestWorker(fw *testing.T) {
+	dir, err := ioutil.TempDir("", "matcher")
+	if err != nil {
+		fw.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "match.conf"), []byte("*.c\n!main.o\n"), 0644)
+	if err != nil {
+		fw.Fatal(err)
+	}
+
+	m, err := New(
+		WithConfig("match.conf"),
+		WithSentinels(),
+		WithRoot(dir),
+		WithStore(Globs("first", "!keep.o")),
+		WithStore(Globs("second", "*.o", "keep.c")),
+	)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	w, err := m.Worker(dir)
+	if err != nil {
+		fw.Fatal(err)
+	}
+	for path, want := range map[string]Result{
+		"a.o":    {Matched: true, Pattern: "*.o", Source: "second", Line: 1},
+		"keep.o": {Pattern: "!keep.o", Negated: true, Source: "first", Line: 1},
+		"main.o": {Matched: true, Pattern: "*.o", Source: "second", Line: 1},
+		"a.c":    {Matched: true, Pattern: "*.c", Source: filepath.Join(dir, "match.conf"), Line: 1},
+		"a.h":    {},
+	} {
+		res, err := w.Match(path)
+		if err != nil || res != want {
+			fw.Errorf("Match(%q) = %+v, %v; want %+v", path, res, err, want)
+		}
+	}
+	if _, err := w.Match(""); !errors.Is(err, ErrEmptyPath) {
+		fw.Errorf("Match(\"\") = %v; want ErrEmptyPath", err)
+	}
+}
+
+func TestWorkerErrors(fw *testing.T) {
+	if _, err := New(WithConfig("")); err == nil {
+		fw.Error("New(WithConfig(\"\")) succeeded")
+	}
+
+	m, err := New(WithStore(Globs("bad", "*.o", "[a")))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	_, err = m.Worker(".")
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, ErrSyntax) || e.Source != "bad" || e.Line != 2 {
+		fw.Errorf("Worker = %v; want syntax error at bad:2", err)
+	}
+
+	m, err = New(WithStore(File("does/not/exist")))
+	if err != nil {
+		fw.Fatal(err)
+	}
+	_, err = m.Worker(".")
+	if !errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrSyntax) {
+		fw.Errorf("Worker = %v; want os.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"errors"
+
+	v1 "github.com/goulash/matcher"
+)
+
+// Option configures a Matcher in New.
+type Option func(*config) error
+
+// config is what the options of New set.
+type config struct {
+	name      string
+	stores    []Store
+	sentinels []string
+	root      string
+	fold      v1.Fold
+	mode      v1.Mode
+	dialect   v1.Dialect
+}
+
+// WithConfig makes Workers read the configuration files called name in
+// their directory and its parents, as in version 1.
+func WithConfig(name string) Option {
+	return func(c *config) error {
+		if name == "" {
+			return &Error{Op: "new", Err: errors.New("empty configuration file name")}
+		}
+		c.name = name
+		return nil
+	}
+}
+
+// WithStore adds a layer of rules to every Worker. Stores take precedence
+// over configuration files, and over the stores added after them.
+func WithStore(s Store) Option {
+	return func(c *config) error {
+		c.stores = append(c.stores, s)
+		return nil
+	}
+}
+
+// WithSentinels sets the names of files and directories that mark the
+// root of a project, as Matcher.Sentinels does in version 1.
+func WithSentinels(names ...string) Option {
+	return func(c *config) error {
+		c.sentinels = append([]string{}, names...)
+		return nil
+	}
+}
+
+// WithRoot sets the directory at which Workers stop looking for
+// configuration files, as Matcher.Root does in version 1.
+func WithRoot(dir string) Option {
+	return func(c *config) error {
+		c.root = dir
+		return nil
+	}
+}
+
+// WithCaseFold makes matching case-insensitive in the given mode.
+func WithCaseFold(f v1.Fold) Option {
+	return func(c *config) error {
+		c.fold = f
+		return nil
+	}
+}
+
+// WithMode sets how strictly rule files are parsed.
+func WithMode(m v1.Mode) Option {
+	return func(c *config) error {
+		c.mode = m
+		return nil
+	}
+}
+
+// WithDialect sets the syntax of all rule files, instead of choosing it
+// by their names.
+func WithDialect(d v1.Dialect) Option {
+	return func(c *config) error {
+		c.dialect = d
+		return nil
+	}
+}
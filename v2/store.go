@@ -0,0 +1,53 @@
+// Copyright (c) 2015, Ben Morgan. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Store is a layer of rules, such as a rule file or a list of globs.
+// Stores are read in the rule file format whenever a Worker is created,
+// so a store backed by a file sees its current content.
+type Store interface {
+	// Name identifies the store in errors and results.
+	Name() string
+
+	// Open returns the rules of the store in the rule file format.
+	Open() (io.ReadCloser, error)
+}
+
+// Globs returns a Store of the given globs, one per line, under name.
+func Globs(name string, globs ...string) Store {
+	return globStore{name, globs}
+}
+
+type globStore struct {
+	name  string
+	globs []string
+}
+
+func (s globStore) Name() string { return s.name }
+
+func (s globStore) Open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(strings.Join(s.globs, "\n"))), nil
+}
+
+// File returns a Store of the rule file at path. Unlike configuration files,
+// which are looked for, a missing file is an error when a Worker is created.
+func File(path string) Store {
+	return fileStore(path)
+}
+
+type fileStore string
+
+func (s fileStore) Name() string { return string(s) }
+
+func (s fileStore) Open() (io.ReadCloser, error) {
+	return os.Open(string(s))
+}